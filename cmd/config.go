@@ -72,7 +72,9 @@ Tests handler configs present in ~/.kubewatch.yaml by sending test messages`,
 			Reason:    "Tested",
 			Status:    "Normal",
 		}
-		eventHandler.Handle(e)
+		if err := eventHandler.Handle(e); err != nil {
+			logrus.Fatal(err)
+		}
 	},
 }
 
@@ -0,0 +1,124 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/client"
+	"github.com/bitnami-labs/kubewatch/pkg/deadletter"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// deadLetterCmd represents the deadletter command
+var deadLetterCmd = &cobra.Command{
+	Use:   "deadletter",
+	Short: "inspect and replay the events handlers gave up on",
+	Long: `
+deadletter command lists and replays the events stored in the dead-letter
+file set by deadLetter.file in ~/.kubewatch.yaml`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+var deadLetterListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "list the dead-lettered events",
+	Long: `
+Lists the dead-lettered events, oldest first, with the handler that gave up
+on them, the number of attempts and the last error`,
+	Run: func(cmd *cobra.Command, args []string) {
+		_, path := deadLetterFile(cmd)
+		handler, _ := cmd.Flags().GetString("handler")
+
+		letters, err := deadletter.Read(path)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "TIME\tHANDLER\tEVENT\tATTEMPTS\tERROR")
+		for _, l := range letters {
+			if handler != "" && l.Handler != handler {
+				continue
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n", l.Time.Format(time.RFC3339), l.Handler, l.Subject(), l.Attempts, l.Error)
+		}
+		w.Flush()
+	},
+}
+
+var deadLetterReplayCmd = &cobra.Command{
+	Use:   "replay",
+	Short: "send the dead-lettered events again",
+	Long: `
+Sends the dead-lettered events again, each to the handler that gave up on it
+or to the one named by --to. Letters naming no handler are only replayed
+with --to, since sending them to every handler would skip the routes and
+filters. Replayed events are removed from the file, and the ones failing
+again are kept. Stop kubewatch while replaying, since it could append to the
+file meanwhile.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		conf, path := deadLetterFile(cmd)
+		handler, _ := cmd.Flags().GetString("handler")
+		to, _ := cmd.Flags().GetString("to")
+
+		keep := func(l deadletter.Letter) bool { return handler != "" && l.Handler != handler }
+		replayed, failed, err := client.ReplayDeadLetters(conf, path, to, keep)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		fmt.Printf("Replayed %d events, %d failed again\n", replayed, failed)
+		if failed > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+// deadLetterFile returns the configuration and the path of the dead-letter
+// file, from --file or from the configuration
+func deadLetterFile(cmd *cobra.Command) (*config.Config, string) {
+	conf, err := config.New()
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	path, _ := cmd.Flags().GetString("file")
+	if path == "" {
+		path = conf.DeadLetter.File
+	}
+	if path == "" {
+		logrus.Fatal("No dead-letter file: set deadLetter.file in ~/.kubewatch.yaml or use --file")
+	}
+	return conf, path
+}
+
+func init() {
+	RootCmd.AddCommand(deadLetterCmd)
+	deadLetterCmd.AddCommand(
+		deadLetterListCmd,
+		deadLetterReplayCmd,
+	)
+
+	deadLetterCmd.PersistentFlags().StringP("file", "f", "", "Specify the dead-letter file, defaults to deadLetter.file")
+	deadLetterCmd.PersistentFlags().String("handler", "", "Only consider the events the named handler gave up on")
+	deadLetterReplayCmd.Flags().String("to", "", "Send the events to the named handler rather than to the one that gave up on them")
+}
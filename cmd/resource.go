@@ -18,6 +18,7 @@ package cmd
 
 import (
 	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/resources"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -75,101 +76,24 @@ remove specific resources being watched`,
 
 // configures resource in config based on operation add/remove
 func configureResource(operation string, cmd *cobra.Command, conf *config.Config) {
-
-	// flags struct
-	flags := []struct {
-		resourceStr     string
-		resourceToWatch *bool
-	}{
-		{
-			"svc",
-			&conf.Resource.Services,
-		},
-		{
-			"deploy",
-			&conf.Resource.Deployment,
-		},
-		{
-			"po",
-			&conf.Resource.Pod,
-		},
-		{
-			"rs",
-			&conf.Resource.ReplicaSet,
-		},
-		{
-			"rc",
-			&conf.Resource.ReplicationController,
-		},
-		{
-			"ns",
-			&conf.Resource.Namespace,
-		},
-		{
-			"job",
-			&conf.Resource.Job,
-		},
-		{
-			"pv",
-			&conf.Resource.PersistentVolume,
-		},
-		{
-			"ds",
-			&conf.Resource.DaemonSet,
-		},
-		{
-			"secret",
-			&conf.Resource.Secret,
-		},
-		{
-			"cm",
-			&conf.Resource.ConfigMap,
-		},
-		{
-			"ing",
-			&conf.Resource.Ingress,
-		},
-		{
-			"node",
-			&conf.Resource.Node,
-		},
-		{
-			"clusterrole",
-			&conf.Resource.ClusterRole,
-		},
-		{
-			"clusterrolebinding",
-			&conf.Resource.ClusterRoleBinding,
-		},
-		{
-			"sa",
-			&conf.Resource.ServiceAccount,
-		},
-		{
-			"statefulset",
-			&conf.Resource.StatefulSet,
-		},
-		{
-			"coreevent",
-			&conf.Resource.CoreEvent,
-		},
-	}
-
-	for _, flag := range flags {
-		b, err := cmd.Flags().GetBool(flag.resourceStr)
-		if err == nil {
-			if b {
-				switch operation {
-				case "add":
-					*flag.resourceToWatch = true
-					logrus.Infof("resource %s configured", flag.resourceStr)
-				case "remove":
-					*flag.resourceToWatch = false
-					logrus.Infof("resource %s removed", flag.resourceStr)
-				}
-			}
-		} else {
-			logrus.Fatal(flag.resourceStr, err)
+	for _, res := range resources.Builtin {
+		if res.Flag == "" {
+			continue
+		}
+		b, err := cmd.Flags().GetBool(res.Flag)
+		if err != nil {
+			logrus.Fatal(res.Flag, err)
+		}
+		if !b {
+			continue
+		}
+		switch operation {
+		case "add":
+			conf.Watch(res.Name)
+			logrus.Infof("resource %s configured", res.Flag)
+		case "remove":
+			conf.Unwatch(append([]string{res.Name}, res.Aliases...)...)
+			logrus.Infof("resource %s removed", res.Flag)
 		}
 	}
 
@@ -185,21 +109,9 @@ func init() {
 		resourceConfigRemoveCmd,
 	)
 	// Add resource object flags as PersistentFlags to resourceConfigCmd
-	resourceConfigCmd.PersistentFlags().Bool("svc", false, "watch for services")
-	resourceConfigCmd.PersistentFlags().Bool("deploy", false, "watch for deployments")
-	resourceConfigCmd.PersistentFlags().Bool("po", false, "watch for pods")
-	resourceConfigCmd.PersistentFlags().Bool("rc", false, "watch for replication controllers")
-	resourceConfigCmd.PersistentFlags().Bool("rs", false, "watch for replicasets")
-	resourceConfigCmd.PersistentFlags().Bool("ns", false, "watch for namespaces")
-	resourceConfigCmd.PersistentFlags().Bool("pv", false, "watch for persistent volumes")
-	resourceConfigCmd.PersistentFlags().Bool("job", false, "watch for jobs")
-	resourceConfigCmd.PersistentFlags().Bool("ds", false, "watch for daemonsets")
-	resourceConfigCmd.PersistentFlags().Bool("secret", false, "watch for plain secrets")
-	resourceConfigCmd.PersistentFlags().Bool("cm", false, "watch for plain configmaps")
-	resourceConfigCmd.PersistentFlags().Bool("ing", false, "watch for ingresses")
-	resourceConfigCmd.PersistentFlags().Bool("node", false, "watch for Nodes")
-	resourceConfigCmd.PersistentFlags().Bool("clusterrole", false, "watch for cluster roles")
-	resourceConfigCmd.PersistentFlags().Bool("clusterrolebinding", false, "watch for cluster roles binding")
-	resourceConfigCmd.PersistentFlags().Bool("sa", false, "watch for service accounts")
-	resourceConfigCmd.PersistentFlags().Bool("coreevent", false, "watch for events (old events object)")
+	for _, res := range resources.Builtin {
+		if res.Flag != "" {
+			resourceConfigCmd.PersistentFlags().Bool(res.Flag, false, "watch for "+res.Description)
+		}
+	}
 }
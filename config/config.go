@@ -19,11 +19,17 @@ limitations under the License.
 package config
 
 import (
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"regexp"
 	"runtime"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/bitnami-labs/kubewatch/pkg/resources"
 	"gopkg.in/yaml.v3"
 )
 
@@ -49,28 +55,187 @@ type Handler struct {
 	Lark         Lark         `json:"lark"`
 }
 
-// Resource contains resource configuration
+// HandlerInstance contains the configuration of a named handler. In the config
+// file the handler settings sit next to its name and type:
+//
+//	handlers:
+//	- name: team-a-slack
+//	  type: slack
+//	  token: xoxb-xxxxx
+//	  channel: team-a
+type HandlerInstance struct {
+	// Name of the instance, used to refer to it in routes and to name its
+	// outbox; lowercase letters, digits and dashes.
+	Name string `json:"name"`
+	// Type of the handler, e.g. slack, webhook or ms-teams.
+	Type string `json:"type"`
+	// Handler settings; only the section matching Type is used.
+	Handler Handler `json:"-"`
+}
+
+// ConfigFor returns a pointer to the section of h configuring handlers of the
+// given type, or nil when the type is unknown.
+func (h *Handler) ConfigFor(handlerType string) interface{} {
+	switch handlerType {
+	case "slack":
+		return &h.Slack
+	case "slackwebhook":
+		return &h.SlackWebhook
+	case "hipchat":
+		return &h.Hipchat
+	case "mattermost":
+		return &h.Mattermost
+	case "flock":
+		return &h.Flock
+	case "webhook":
+		return &h.Webhook
+	case "cloudevent":
+		return &h.CloudEvent
+	case "ms-teams":
+		return &h.MSTeams
+	case "smtp":
+		return &h.SMTP
+	case "lark":
+		return &h.Lark
+	}
+	return nil
+}
+
+// DigestFor returns the digest settings of the section of h configuring
+// handlers of the given type, or none when those handlers cannot send
+// digests.
+func (h *Handler) DigestFor(handlerType string) Digest {
+	switch handlerType {
+	case "slack":
+		return h.Slack.Digest
+	case "slackwebhook":
+		return h.SlackWebhook.Digest
+	case "mattermost":
+		return h.Mattermost.Digest
+	case "ms-teams":
+		return h.MSTeams.Digest
+	case "smtp":
+		return h.SMTP.Digest
+	case "lark":
+		return h.Lark.Digest
+	}
+	return Digest{}
+}
+
+// Direct returns a copy of c whose handlers send each message right away:
+// without queues, digests, outbox or dead letters. Commands sending a few messages
+// and exiting use it.
+func (c *Config) Direct() *Config {
+	direct := *c
+	direct.Queue = Queue{Disabled: true}
+	direct.Outbox = Outbox{}
+	direct.DeadLetter = DeadLetter{}
+	direct.Handler.clearDigests()
+	direct.Handlers = make([]HandlerInstance, len(c.Handlers))
+	for i, instance := range c.Handlers {
+		instance.Handler.clearDigests()
+		direct.Handlers[i] = instance
+	}
+	return &direct
+}
+
+func (h *Handler) clearDigests() {
+	h.Slack.Digest = Digest{}
+	h.SlackWebhook.Digest = Digest{}
+	h.Mattermost.Digest = Digest{}
+	h.MSTeams.Digest = Digest{}
+	h.SMTP.Digest = Digest{}
+	h.Lark.Digest = Digest{}
+}
+
+var handlerName = regexp.MustCompile(`^[a-z0-9-]+$`)
+
+// CheckHandlerName returns an error unless name is a valid handler instance
+// name. Names end up in file paths, so only lowercase letters, digits and
+// dashes are allowed.
+func CheckHandlerName(name string) error {
+	if name == "" {
+		return fmt.Errorf("handler has no name")
+	}
+	if !handlerName.MatchString(name) {
+		return fmt.Errorf("invalid handler name %q: only lowercase letters, digits and dashes are allowed", name)
+	}
+	return nil
+}
+
+// UnmarshalYAML decodes the handler settings found next to name and type into
+// the section of Handler matching the type.
+func (i *HandlerInstance) UnmarshalYAML(node *yaml.Node) error {
+	var head struct {
+		Name string `yaml:"name"`
+		Type string `yaml:"type"`
+	}
+	if err := node.Decode(&head); err != nil {
+		return err
+	}
+	i.Name, i.Type = head.Name, head.Type
+	if err := CheckHandlerName(i.Name); err != nil {
+		return fmt.Errorf("handler of type %q: %v", i.Type, err)
+	}
+
+	section := i.Handler.ConfigFor(i.Type)
+	if section == nil {
+		return fmt.Errorf("handler %q: unknown handler type %q", i.Name, i.Type)
+	}
+	return node.Decode(section)
+}
+
+// MarshalYAML encodes the instance the way UnmarshalYAML expects it.
+func (i HandlerInstance) MarshalYAML() (interface{}, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	if section := i.Handler.ConfigFor(i.Type); section != nil {
+		if err := node.Encode(section); err != nil {
+			return nil, err
+		}
+	}
+	head := []*yaml.Node{
+		{Kind: yaml.ScalarNode, Value: "name"}, {Kind: yaml.ScalarNode, Value: i.Name},
+		{Kind: yaml.ScalarNode, Value: "type"}, {Kind: yaml.ScalarNode, Value: i.Type},
+	}
+	node.Content = append(head, node.Content...)
+	return node, nil
+}
+
+// Resource selects a kind of resource to watch. In the config file it is
+// either a name or a mapping with the name and the watch settings:
+//
+//	resources:
+//	- deployment
+//	- name: pod
+//	  labelSelector: app.kubernetes.io/part-of=payments
 type Resource struct {
-	Deployment            bool `json:"deployment"`
-	ReplicationController bool `json:"rc"`
-	ReplicaSet            bool `json:"rs"`
-	DaemonSet             bool `json:"ds"`
-	StatefulSet           bool `json:"statefulset"`
-	Services              bool `json:"svc"`
-	Pod                   bool `json:"po"`
-	Job                   bool `json:"job"`
-	Node                  bool `json:"node"`
-	ClusterRole           bool `json:"clusterrole"`
-	ClusterRoleBinding    bool `json:"clusterrolebinding"`
-	ServiceAccount        bool `json:"sa"`
-	PersistentVolume      bool `json:"pv"`
-	Namespace             bool `json:"ns"`
-	Secret                bool `json:"secret"`
-	ConfigMap             bool `json:"configmap"`
-	Ingress               bool `json:"ing"`
-	HPA                   bool `json:"hpa"`
-	Event                 bool `json:"event"`
-	CoreEvent             bool `json:"coreevent"`
+	// Name of the resource, e.g. pod, deployment or ingress; run
+	// "kubewatch resource --help" for the list. Any other resource the
+	// cluster serves can be named by its resource name, short name or kind,
+	// optionally with its group, e.g. cronjobs, Certificate or
+	// leases.coordination.k8s.io.
+	Name string `json:"name"`
+	// Label selector the API server filters the objects with, e.g.
+	// app.kubernetes.io/part-of=payments.
+	LabelSelector string `json:"labelSelector" yaml:"labelSelector,omitempty"`
+	// Field selector the API server filters the objects with, e.g.
+	// status.phase!=Succeeded. Only some fields of each resource can be
+	// selected on.
+	FieldSelector string `json:"fieldSelector" yaml:"fieldSelector,omitempty"`
+	// Watch the metadata of the objects only, e.g. of secrets or
+	// configmaps, which takes much less memory. Notifications then carry
+	// the metadata only, and updates are only noticed when it changes.
+	MetadataOnly bool `json:"metadataOnly" yaml:"metadataOnly,omitempty"`
+}
+
+// UnmarshalYAML accepts a bare name as well as a mapping.
+func (r *Resource) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		r.Name = node.Value
+		return nil
+	}
+	type plain Resource
+	return node.Decode((*plain)(r))
 }
 
 type CRD struct {
@@ -83,10 +248,19 @@ type Config struct {
 	// Handlers know how to send notifications to specific services.
 	Handler Handler `json:"handler"`
 
+	// Named handler instances, for sending to several destinations of the
+	// same type, e.g. two Slack channels. Instance names are made of
+	// lowercase letters, digits and dashes, and must be unique.
+	Handlers []HandlerInstance `json:"handlers"`
+
 	//Reason   []string `json:"reason"`
 
-	// Resources to watch.
-	Resource Resource `json:"resource"`
+	// Resources to watch, by name.
+	Resources []Resource `json:"resources"`
+
+	// Deprecated: use resources. Resources turned on and off by name, as in
+	// older configuration files.
+	LegacyResource map[string]bool `json:"resource" yaml:"resource,omitempty"`
 
 	// CustomResources to Watch
 	CustomResources []CRD `json:"customresources"`
@@ -94,6 +268,385 @@ type Config struct {
 	// For watching specific namespace, leave it empty for watching all.
 	// this config is ignored when watching namespaces
 	Namespace string `json:"namespace,omitempty"`
+
+	// Namespaces to watch, besides namespace; leave both empty for watching
+	// all. Resources outside of namespaces, such as nodes, are watched
+	// regardless.
+	Namespaces []string `json:"namespaces"`
+
+	// Namespaces not to watch, as glob patterns, e.g. kube-system or
+	// *-sandbox.
+	ExcludeNamespaces []string `json:"excludeNamespaces" yaml:"excludeNamespaces"`
+
+	// Label selector the namespaces to watch must match, e.g. team=foo.
+	// Namespaces are followed as they are created and relabelled.
+	// The selector only filters events: resources are still watched
+	// across the cluster unless namespaces lists them.
+	NamespaceSelector string `json:"namespaceSelector" yaml:"namespaceSelector"`
+
+	// Routes decide which handlers receive each event.
+	Routes Routes `json:"routes"`
+
+	// Filter decides which events are sent at all.
+	Filter Filter `json:"filter"`
+
+	// IgnorePaths lists fields whose changes alone do not make an update
+	// worth sending, on top of built-in ones such as
+	// metadata.resourceVersion.
+	IgnorePaths []IgnorePathRule `json:"ignorePaths" yaml:"ignorePaths"`
+
+	// PrunePaths lists fields removed from the objects as soon as they are
+	// received, before they are cached, compared or sent, e.g.
+	// metadata.managedFields, of which the manager of the latest write is
+	// kept for message templates.
+	PrunePaths []PrunePathRule `json:"prunePaths" yaml:"prunePaths"`
+
+	// Dedup folds repeats of an event into periodic summaries.
+	Dedup Dedup `json:"dedup"`
+
+	// Retry sets how failed deliveries are retried.
+	Retry Retry `json:"retry"`
+
+	// Outbox keeps undelivered events on disk.
+	Outbox Outbox `json:"outbox"`
+
+	// DeadLetter receives the events handlers give up on.
+	DeadLetter DeadLetter `json:"deadLetter" yaml:"deadLetter"`
+
+	// Limits protect destinations from bursts of messages and kubewatch
+	// from slow destinations.
+	Limits Limits `json:"limits"`
+
+	// Queue hands events over to the handlers without waiting for their
+	// delivery.
+	Queue Queue `json:"queue"`
+
+	// Workers sets how many events of each kind of resource are processed
+	// at once.
+	Workers Workers `json:"workers"`
+}
+
+// Workers contains the worker counts of the resource controllers. The events
+// of an object always go to the same worker, so they keep their order.
+type Workers struct {
+	// Workers of each kind of resource; defaults to 1.
+	Default int `json:"default" yaml:"default,omitempty"`
+	// Workers of single kinds, e.g. Pod or Event, overriding the default.
+	// Kinds are matched case-insensitively; qualify one by its API group,
+	// e.g. Event.events.k8s.io, to tell it from the kinds of other groups.
+	Kinds map[string]int `json:"kinds" yaml:"kinds,omitempty"`
+}
+
+// For returns the number of workers processing the events of the given kind
+// of resource in the given API group, empty for the core group. A key
+// qualified by the group wins over the bare kind.
+func (w Workers) For(kind, group string) int {
+	bare, qualified := 0, 0
+	for key, n := range w.Kinds {
+		if !KindKeyMatches(key, kind, group) {
+			continue
+		}
+		if strings.Contains(key, ".") {
+			qualified = n
+		} else {
+			bare = n
+		}
+	}
+	switch {
+	case qualified > 0:
+		return qualified
+	case bare > 0:
+		return bare
+	case w.Default > 0:
+		return w.Default
+	}
+	return 1
+}
+
+// KindKeyMatches reports whether key, a kind optionally qualified by its API
+// group such as Pod or Event.events.k8s.io, names the kind in group, empty
+// for the core group. Kinds and groups are matched case-insensitively.
+func KindKeyMatches(key, kind, group string) bool {
+	keyKind, keyGroup, qualified := strings.Cut(key, ".")
+	if !strings.EqualFold(keyKind, kind) {
+		return false
+	}
+	return !qualified || strings.EqualFold(keyGroup, group)
+}
+
+// Back-pressure policies of handler queues
+const (
+	QueueBlock      = "block"
+	QueueDropOldest = "drop-oldest"
+	QueueDropNewest = "drop-newest"
+)
+
+// Default queue settings of handlers
+const (
+	DefaultQueueSize    = 1000
+	DefaultQueueWorkers = 1
+)
+
+// Queue contains the dispatch queue settings of handlers. Every handler gets
+// its own queue, so a slow destination does not hold back the others or the
+// resource controllers.
+type Queue struct {
+	// Send events from the resource controllers, waiting for every
+	// delivery, rather than through queues.
+	Disabled bool `json:"disabled" yaml:"disabled,omitempty"`
+	// Events waiting for each handler; defaults to 1000.
+	Size int `json:"size" yaml:"size,omitempty"`
+	// Deliveries running at once for each handler; defaults to 1, which
+	// keeps the events of a handler in order.
+	Workers int `json:"workers" yaml:"workers,omitempty"`
+	// What to do with an event when the queue is full: block, waiting for
+	// room, drop-oldest or drop-newest. Defaults to block.
+	Policy string `json:"policy" yaml:"policy,omitempty"`
+	// Queue settings of single handlers, by handler name, overriding the
+	// ones above.
+	Handlers map[string]Queue `json:"handlers" yaml:"handlers,omitempty"`
+}
+
+// For returns the queue settings of the handler named name: its own settings
+// where set, the ones of q elsewhere, and the defaults for the rest.
+func (q Queue) For(name string) (Queue, error) {
+	queue := q
+	queue.Handlers = nil
+	if own, ok := q.Handlers[name]; ok {
+		if own.Disabled {
+			queue.Disabled = true
+		}
+		if own.Size != 0 {
+			queue.Size = own.Size
+		}
+		if own.Workers != 0 {
+			queue.Workers = own.Workers
+		}
+		if own.Policy != "" {
+			queue.Policy = own.Policy
+		}
+	}
+
+	if queue.Size < 0 || queue.Workers < 0 {
+		return Queue{}, fmt.Errorf("handler %s: queue size and workers cannot be negative", name)
+	}
+	if queue.Size == 0 {
+		queue.Size = DefaultQueueSize
+	}
+	if queue.Workers == 0 {
+		queue.Workers = DefaultQueueWorkers
+	}
+	switch queue.Policy {
+	case "":
+		queue.Policy = QueueBlock
+	case QueueBlock, QueueDropOldest, QueueDropNewest:
+	default:
+		return Queue{}, fmt.Errorf("handler %s: unknown queue policy %q, use %s, %s or %s", name, queue.Policy, QueueBlock, QueueDropOldest, QueueDropNewest)
+	}
+	return queue, nil
+}
+
+// Default limits of handlers
+const (
+	DefaultTimeout         = 10 * time.Second
+	DefaultBreakerFailures = 5
+	DefaultBreakerCooldown = 30 * time.Second
+)
+
+// Limits contains the rate limit, request timeout and circuit breaker
+// settings of handlers. Every handler gets its own rate limit and circuit
+// breaker.
+type Limits struct {
+	// Messages per second sent to a destination, e.g. 0.5 for one message
+	// every two seconds. Leave it empty for no limit.
+	Rate float64 `json:"rate" yaml:"rate,omitempty"`
+	// Messages sent at once before the rate applies; defaults to 1.
+	Burst int `json:"burst" yaml:"burst,omitempty"`
+	// Timeout of a request to a destination; defaults to 10s.
+	Timeout time.Duration `json:"timeout" yaml:"timeout,omitempty"`
+	// Consecutive failed deliveries after which a destination is deemed
+	// unhealthy and left alone for a while; defaults to 5.
+	BreakerFailures int `json:"breakerFailures" yaml:"breakerFailures,omitempty"`
+	// How long an unhealthy destination is left alone before a delivery
+	// probes it again; defaults to 30s.
+	BreakerCooldown time.Duration `json:"breakerCooldown" yaml:"breakerCooldown,omitempty"`
+	// Limits of single handlers, by handler name, overriding the ones
+	// above.
+	Handlers map[string]Limits `json:"handlers" yaml:"handlers,omitempty"`
+}
+
+// For returns the limits of the handler named name: its own settings where
+// set, the ones of l elsewhere, and the defaults for the rest.
+func (l Limits) For(name string) (Limits, error) {
+	limits := l
+	limits.Handlers = nil
+	if own, ok := l.Handlers[name]; ok {
+		if own.Rate != 0 {
+			limits.Rate = own.Rate
+		}
+		if own.Burst != 0 {
+			limits.Burst = own.Burst
+		}
+		if own.Timeout != 0 {
+			limits.Timeout = own.Timeout
+		}
+		if own.BreakerFailures != 0 {
+			limits.BreakerFailures = own.BreakerFailures
+		}
+		if own.BreakerCooldown != 0 {
+			limits.BreakerCooldown = own.BreakerCooldown
+		}
+	}
+
+	if limits.Rate < 0 || limits.Burst < 0 || limits.Timeout < 0 || limits.BreakerFailures < 0 || limits.BreakerCooldown < 0 {
+		return Limits{}, fmt.Errorf("handler %s: limits cannot be negative", name)
+	}
+	if limits.Burst == 0 {
+		limits.Burst = 1
+	}
+	if limits.Timeout == 0 {
+		limits.Timeout = DefaultTimeout
+	}
+	if limits.BreakerFailures == 0 {
+		limits.BreakerFailures = DefaultBreakerFailures
+	}
+	if limits.BreakerCooldown == 0 {
+		limits.BreakerCooldown = DefaultBreakerCooldown
+	}
+	return limits, nil
+}
+
+// DeadLetter contains the dead-letter configuration. Set at most one of its
+// fields.
+type DeadLetter struct {
+	// File to append the events given up on to, one JSON object per line.
+	// Use "kubewatch deadletter" to inspect and replay them.
+	File string `json:"file"`
+	// Name of the handler, from the handlers list, receiving the events
+	// given up on instead.
+	Handler string `json:"handler"`
+}
+
+// Outbox contains the durable outbox configuration
+type Outbox struct {
+	// Directory where each handler keeps the messages waiting for
+	// delivery, so that they survive restarts and outages of the
+	// destination. Leave it empty to keep them in memory only.
+	Dir string `json:"dir"`
+}
+
+// Retry contains the retry policy of handlers. Network errors, HTTP 429 and
+// 5xx responses are retried with exponential backoff; other failures are not.
+type Retry struct {
+	// How many times an event is sent before giving up; defaults to 5.
+	// Set it to 1 to disable retries.
+	Attempts int `json:"attempts" yaml:"attempts,omitempty"`
+	// Wait before the first retry, doubled on every retry; defaults to 1s.
+	InitialBackoff time.Duration `json:"initialBackoff" yaml:"initialBackoff,omitempty"`
+	// Upper bound of the wait between retries; defaults to 30s.
+	MaxBackoff time.Duration `json:"maxBackoff" yaml:"maxBackoff,omitempty"`
+}
+
+// Dedup contains event deduplication configuration
+type Dedup struct {
+	// Window during which repeats of an event are counted rather than sent,
+	// e.g. 5m. Leave it empty to send every event.
+	Window time.Duration `json:"window"`
+	// Fields of the object telling events apart besides their kind,
+	// namespace, name and reason, written as in ignorePaths, e.g.
+	// status.phase or metadata.annotations["kubernetes.io/change-cause"].
+	Fields []string `json:"fields"`
+}
+
+// IgnorePathRule ignores changes to a set of fields of some kinds of objects
+type IgnorePathRule struct {
+	// Kinds of the objects, e.g. Node or Endpoints; leave it empty for every
+	// kind.
+	Kinds []string `json:"kinds"`
+	// Paths of the fields, e.g. status.conditions[].lastTransitionTime or
+	// metadata.annotations["example.com/checked-at"]. [] matches any list
+	// index and a path covers every field below it.
+	Paths []string `json:"paths"`
+}
+
+// PrunePathRule removes a set of fields from some kinds of objects
+type PrunePathRule struct {
+	// Kinds of the objects, e.g. ConfigMap; leave it empty for every kind.
+	Kinds []string `json:"kinds"`
+	// Paths of the fields, written as in ignorePaths, e.g.
+	// metadata.managedFields or
+	// metadata.annotations["kubectl.kubernetes.io/last-applied-configuration"].
+	Paths []string `json:"paths"`
+}
+
+// Filter contains event filtering configuration
+type Filter struct {
+	// Built-in rule sets applied to the events no rule matched, e.g.
+	// "advanced".
+	Presets []string `json:"presets"`
+	// Rules are evaluated in order; the first matching rule decides whether
+	// the event is sent. Events no rule or preset matched are sent.
+	Rules []FilterRule `json:"rules"`
+	// CEL expressions over object, oldObject and event; events the rules
+	// let through are only sent when every expression is true.
+	Expressions []string `json:"expressions"`
+}
+
+// FilterRule includes or excludes the events it matches; empty fields match
+// every event
+type FilterRule struct {
+	// Either include or exclude.
+	Action string `json:"action"`
+	// Kinds of the objects, e.g. Pod or Deployment.
+	Kinds []string `json:"kinds"`
+	// Namespaces, as glob patterns.
+	Namespaces []string `json:"namespaces"`
+	// Regular expression the object name must match.
+	Name string `json:"name"`
+	// Label selector the object must match, e.g. "app=api,tier!=cache".
+	LabelSelector string `json:"labelSelector" yaml:"labelSelector"`
+	// Annotations the object must carry; values are glob patterns.
+	Annotations map[string]string `json:"annotations"`
+	// Event reasons: Created, Updated or Deleted.
+	Reasons []string `json:"reasons"`
+	// Types of Kubernetes Event objects: Normal or Warning.
+	EventTypes []string `json:"eventTypes" yaml:"eventTypes"`
+	// Reasons of Kubernetes Event objects, e.g. Evicted or BackOff.
+	EventReasons []string `json:"eventReasons" yaml:"eventReasons"`
+}
+
+// Routes contains event routing configuration
+type Routes struct {
+	// Handlers receiving the events no rule matched. Leave it empty to send
+	// them to every handler.
+	Default []string `json:"default"`
+	// Rules are evaluated in order; the first matching rule wins unless it
+	// sets continue.
+	Rules []Route `json:"rules"`
+}
+
+// Route sends the events it matches to a list of handlers
+type Route struct {
+	// Events the rule applies to.
+	Match RouteMatch `json:"match"`
+	// Names of the handlers receiving matching events.
+	Handlers []string `json:"handlers"`
+	// Keep evaluating the following rules after this one matched.
+	Continue bool `json:"continue"`
+}
+
+// RouteMatch selects events; empty fields match every event
+type RouteMatch struct {
+	// Namespaces, as glob patterns.
+	Namespaces []string `json:"namespaces"`
+	// Kinds of the objects, e.g. Pod or Deployment.
+	Kinds []string `json:"kinds"`
+	// Event reasons: Created, Updated or Deleted.
+	Reasons []string `json:"reasons"`
+	// Event statuses: Normal, Warning or Danger.
+	Statuses []string `json:"statuses"`
+	// Labels the object must carry.
+	Labels map[string]string `json:"labels"`
 }
 
 // Slack contains slack configuration
@@ -104,6 +657,11 @@ type Slack struct {
 	Channel string `json:"channel"`
 	// Title of the message.
 	Title string `json:"title"`
+	// Go template of the message text; leave it empty for the default
+	// message.
+	Template string `json:"template"`
+	// Send the events of a window as a single digest message.
+	Digest Digest `json:"digest"`
 }
 
 // SlackWebhook contains slack configuration
@@ -116,6 +674,11 @@ type SlackWebhook struct {
 	Emoji string `json:"emoji"`
 	// Slack Webhook Url.
 	Slackwebhookurl string `json:"slackwebhookurl"`
+	// Go template of the message text; leave it empty for the default
+	// message.
+	Template string `json:"template"`
+	// Send the events of a window as a single digest message.
+	Digest Digest `json:"digest"`
 }
 
 // Hipchat contains hipchat configuration
@@ -126,6 +689,9 @@ type Hipchat struct {
 	Room string `json:"room"`
 	// URL of the hipchat server.
 	Url string `json:"url"`
+	// Go template of the message text; leave it empty for the default
+	// message.
+	Template string `json:"template"`
 }
 
 // Mattermost contains mattermost configuration
@@ -133,12 +699,20 @@ type Mattermost struct {
 	Channel  string `json:"room"`
 	Url      string `json:"url"`
 	Username string `json:"username"`
+	// Go template of the message text; leave it empty for the default
+	// message.
+	Template string `json:"template"`
+	// Send the events of a window as a single digest message.
+	Digest Digest `json:"digest"`
 }
 
 // Flock contains flock configuration
 type Flock struct {
 	// URL of the flock API.
 	Url string `json:"url"`
+	// Go template of the message text; leave it empty for the default
+	// message.
+	Template string `json:"template"`
 }
 
 // Webhook contains webhook configuration
@@ -147,23 +721,39 @@ type Webhook struct {
 	Url     string `json:"url"`
 	Cert    string `json:"cert"`
 	TlsSkip bool   `json:"tlsskip"`
+	// Go template of the message text; leave it empty for the default
+	// message.
+	Template string `json:"template"`
 }
 
 // Lark contains lark configuration
 type Lark struct {
 	// Webhook URL.
 	WebhookURL string `json:"webhookurl"`
+	// Go template of the message text; leave it empty for the default
+	// message.
+	Template string `json:"template"`
+	// Send the events of a window as a single digest message.
+	Digest Digest `json:"digest"`
 }
 
 // CloudEvent contains CloudEvent configuration
 type CloudEvent struct {
 	Url string `json:"url"`
+	// Go template of the message text; leave it empty for the default
+	// message.
+	Template string `json:"template"`
 }
 
 // MSTeams contains MSTeams configuration
 type MSTeams struct {
 	// MSTeams API Webhook URL.
 	WebhookURL string `json:"webhookurl"`
+	// Go template of the message text; leave it empty for the default
+	// message.
+	Template string `json:"template"`
+	// Send the events of a window as a single digest message.
+	Digest Digest `json:"digest"`
 }
 
 // SMTP contains SMTP configuration.
@@ -184,6 +774,20 @@ type SMTP struct {
 	RequireTLS bool `json:"requireTLS" yaml:"requireTLS"`
 	// SMTP hello field (optional)
 	Hello string `json:"hello" yaml:"hello,omitempty"`
+	// Go template of the message text; leave it empty for the default
+	// message.
+	Template string `json:"template" yaml:"template,omitempty"`
+	// Send the events of a window as a single digest e-mail.
+	Digest Digest `json:"digest" yaml:"digest,omitempty"`
+}
+
+// Digest contains batching configuration
+type Digest struct {
+	// Window during which events are collected and then sent as one
+	// message, e.g. 1m. Leave it empty to send each event on its own.
+	Window time.Duration `json:"window" yaml:"window,omitempty"`
+	// How many object names each group of a digest lists; defaults to 5.
+	TopNames int `json:"topNames" yaml:"topNames,omitempty"`
 }
 
 type SMTPAuth struct {
@@ -243,61 +847,32 @@ func (c *Config) Load() error {
 	}
 
 	if len(b) != 0 {
-		return yaml.Unmarshal(b, c)
+		if err := yaml.Unmarshal(b, c); err != nil {
+			return err
+		}
 	}
 
+	return c.checkHandlerNames()
+}
+
+// checkHandlerNames returns an error when two handler instances share a name
+func (c *Config) checkHandlerNames() error {
+	seen := map[string]bool{}
+	for _, i := range c.Handlers {
+		if seen[i.Name] {
+			return fmt.Errorf("handler name %q is used more than once", i.Name)
+		}
+		seen[i.Name] = true
+	}
 	return nil
 }
 
 // CheckMissingResourceEnvvars will read the environment for equivalent config variables to set
 func (c *Config) CheckMissingResourceEnvvars() {
-	if !c.Resource.DaemonSet && os.Getenv("KW_DAEMONSET") == "true" {
-		c.Resource.DaemonSet = true
-	}
-	if !c.Resource.ReplicaSet && os.Getenv("KW_REPLICASET") == "true" {
-		c.Resource.ReplicaSet = true
-	}
-	if !c.Resource.Namespace && os.Getenv("KW_NAMESPACE") == "true" {
-		c.Resource.Namespace = true
-	}
-	if !c.Resource.Deployment && os.Getenv("KW_DEPLOYMENT") == "true" {
-		c.Resource.Deployment = true
-	}
-	if !c.Resource.Pod && os.Getenv("KW_POD") == "true" {
-		c.Resource.Pod = true
-	}
-	if !c.Resource.ReplicationController && os.Getenv("KW_REPLICATION_CONTROLLER") == "true" {
-		c.Resource.ReplicationController = true
-	}
-	if !c.Resource.Services && os.Getenv("KW_SERVICE") == "true" {
-		c.Resource.Services = true
-	}
-	if !c.Resource.Job && os.Getenv("KW_JOB") == "true" {
-		c.Resource.Job = true
-	}
-	if !c.Resource.PersistentVolume && os.Getenv("KW_PERSISTENT_VOLUME") == "true" {
-		c.Resource.PersistentVolume = true
-	}
-	if !c.Resource.Secret && os.Getenv("KW_SECRET") == "true" {
-		c.Resource.Secret = true
-	}
-	if !c.Resource.ConfigMap && os.Getenv("KW_CONFIGMAP") == "true" {
-		c.Resource.ConfigMap = true
-	}
-	if !c.Resource.Ingress && os.Getenv("KW_INGRESS") == "true" {
-		c.Resource.Ingress = true
-	}
-	if !c.Resource.Node && os.Getenv("KW_NODE") == "true" {
-		c.Resource.Node = true
-	}
-	if !c.Resource.ServiceAccount && os.Getenv("KW_SERVICE_ACCOUNT") == "true" {
-		c.Resource.ServiceAccount = true
-	}
-	if !c.Resource.ClusterRole && os.Getenv("KW_CLUSTER_ROLE") == "true" {
-		c.Resource.ClusterRole = true
-	}
-	if !c.Resource.ClusterRoleBinding && os.Getenv("KW_CLUSTER_ROLE_BINDING") == "true" {
-		c.Resource.ClusterRoleBinding = true
+	for _, res := range resources.Builtin {
+		if res.Env != "" && os.Getenv(res.Env) == "true" {
+			c.Watch(res.Name)
+		}
 	}
 	if (c.Handler.Slack.Channel == "") && (os.Getenv("SLACK_CHANNEL") != "") {
 		c.Handler.Slack.Channel = os.Getenv("SLACK_CHANNEL")
@@ -313,6 +888,56 @@ func (c *Config) CheckMissingResourceEnvvars() {
 	}
 }
 
+// WatchedResources returns the resources to watch: the ones of Resources,
+// then the ones turned on in the legacy resource section, sorted by name.
+func (c *Config) WatchedResources() []Resource {
+	watched := append([]Resource(nil), c.Resources...)
+	var legacy []string
+	for name, on := range c.LegacyResource {
+		if on {
+			legacy = append(legacy, name)
+		}
+	}
+	sort.Strings(legacy)
+	for _, name := range legacy {
+		watched = append(watched, Resource{Name: name})
+	}
+	return watched
+}
+
+// Watch adds the named resource to the watched ones, unless it is there
+// already.
+func (c *Config) Watch(name string) {
+	for _, r := range c.WatchedResources() {
+		if r.Name == name {
+			return
+		}
+	}
+	c.Resources = append(c.Resources, Resource{Name: name})
+}
+
+// Unwatch removes the resource going by any of names from the watched ones,
+// in Resources and in the legacy resource section alike.
+func (c *Config) Unwatch(names ...string) {
+	drop := map[string]bool{}
+	for _, name := range names {
+		drop[name] = true
+	}
+
+	kept := c.Resources[:0]
+	for _, r := range c.Resources {
+		if !drop[r.Name] {
+			kept = append(kept, r)
+		}
+	}
+	c.Resources = kept
+	for name := range c.LegacyResource {
+		if drop[name] {
+			delete(c.LegacyResource, name)
+		}
+	}
+}
+
 func (c *Config) Write() error {
 	f, err := os.OpenFile(getConfigFile(), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
 	if err != nil {
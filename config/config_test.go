@@ -17,9 +17,14 @@ limitations under the License.
 package config
 
 import (
-//"io/ioutil"
-"os"
-"testing"
+	//"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
 var configStr = `
@@ -60,3 +65,220 @@ func TestCheckMissingResourceEnvvars_Webhook(t *testing.T) {
 		t.Errorf("Expected Webhook URL to be %q, but got %q", expectedURL, c.Handler.Webhook.Url)
 	}
 }
+
+func TestHandlerInstancesYAML(t *testing.T) {
+	in := `
+handlers:
+- name: team-a-slack
+  type: slack
+  token: token-a
+  channel: team-a
+- name: team-b-slack
+  type: slack
+  token: token-b
+  channel: team-b
+- name: audit
+  type: webhook
+  url: http://audit.example.com
+`
+	c := &Config{}
+	if err := yaml.Unmarshal([]byte(in), c); err != nil {
+		t.Fatalf("Unmarshal(): %v", err)
+	}
+
+	want := []HandlerInstance{
+		{Name: "team-a-slack", Type: "slack", Handler: Handler{Slack: Slack{Token: "token-a", Channel: "team-a"}}},
+		{Name: "team-b-slack", Type: "slack", Handler: Handler{Slack: Slack{Token: "token-b", Channel: "team-b"}}},
+		{Name: "audit", Type: "webhook", Handler: Handler{Webhook: Webhook{Url: "http://audit.example.com"}}},
+	}
+	if !reflect.DeepEqual(c.Handlers, want) {
+		t.Fatalf("Unmarshal() = %+v, want %+v", c.Handlers, want)
+	}
+
+	out, err := yaml.Marshal(c)
+	if err != nil {
+		t.Fatalf("Marshal(): %v", err)
+	}
+	roundTripped := &Config{}
+	if err := yaml.Unmarshal(out, roundTripped); err != nil {
+		t.Fatalf("Unmarshal(Marshal()): %v", err)
+	}
+	if !reflect.DeepEqual(roundTripped.Handlers, want) {
+		t.Errorf("round trip = %+v, want %+v", roundTripped.Handlers, want)
+	}
+}
+
+func TestHandlerInstanceUnknownType(t *testing.T) {
+	c := &Config{}
+	err := yaml.Unmarshal([]byte("handlers:\n- name: pager\n  type: pagerduty\n"), c)
+	if err == nil || !strings.Contains(err.Error(), `unknown handler type "pagerduty"`) {
+		t.Errorf("Unmarshal() error = %v, want an unknown handler type error", err)
+	}
+}
+
+func TestHandlerInstanceNames(t *testing.T) {
+	var Tests = []struct {
+		name string
+		err  string
+	}{
+		{"team-a-slack", ""},
+		{"", "has no name"},
+		{"../x", "invalid handler name"},
+		{"a/b", "invalid handler name"},
+		{"Team-A", "invalid handler name"},
+	}
+
+	for _, tt := range Tests {
+		c := &Config{}
+		err := yaml.Unmarshal([]byte("handlers:\n- name: \""+tt.name+"\"\n  type: slack\n"), c)
+		if tt.err == "" && err != nil {
+			t.Errorf("Unmarshal() of name %q error = %v", tt.name, err)
+		}
+		if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("Unmarshal() of name %q error = %v, want one containing %q", tt.name, err, tt.err)
+		}
+	}
+
+	c := &Config{Handlers: []HandlerInstance{{Name: "audit", Type: "webhook"}, {Name: "audit", Type: "slack"}}}
+	if err := c.checkHandlerNames(); err == nil || !strings.Contains(err.Error(), "more than once") {
+		t.Errorf("checkHandlerNames() error = %v, want a duplicate name error", err)
+	}
+}
+
+func TestDedupYAML(t *testing.T) {
+	c := &Config{}
+	if err := yaml.Unmarshal([]byte("dedup:\n  window: 5m\n  fields: [status.phase]\n"), c); err != nil {
+		t.Fatalf("Unmarshal(): %v", err)
+	}
+	want := Dedup{Window: 5 * time.Minute, Fields: []string{"status.phase"}}
+	if !reflect.DeepEqual(c.Dedup, want) {
+		t.Errorf("Unmarshal() = %+v, want %+v", c.Dedup, want)
+	}
+}
+
+func TestLimitsFor(t *testing.T) {
+	limits := Limits{
+		Rate:    2,
+		Timeout: 5 * time.Second,
+		Handlers: map[string]Limits{
+			"webhook": {Rate: 0.5, BreakerFailures: 3},
+		},
+	}
+
+	var Tests = []struct {
+		name string
+		want Limits
+	}{
+		{"slack", Limits{Rate: 2, Burst: 1, Timeout: 5 * time.Second, BreakerFailures: DefaultBreakerFailures, BreakerCooldown: DefaultBreakerCooldown}},
+		{"webhook", Limits{Rate: 0.5, Burst: 1, Timeout: 5 * time.Second, BreakerFailures: 3, BreakerCooldown: DefaultBreakerCooldown}},
+	}
+
+	for _, tt := range Tests {
+		got, err := limits.For(tt.name)
+		if err != nil {
+			t.Fatalf("For(%q): %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("For(%q) = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := (Limits{Timeout: -time.Second}).For("slack"); err == nil {
+		t.Errorf("For() with a negative timeout succeeded")
+	}
+}
+
+func TestQueueFor(t *testing.T) {
+	queue := Queue{
+		Workers: 2,
+		Handlers: map[string]Queue{
+			"smtp": {Size: 10, Policy: QueueDropOldest},
+		},
+	}
+
+	var Tests = []struct {
+		name string
+		want Queue
+	}{
+		{"slack", Queue{Size: DefaultQueueSize, Workers: 2, Policy: QueueBlock}},
+		{"smtp", Queue{Size: 10, Workers: 2, Policy: QueueDropOldest}},
+	}
+
+	for _, tt := range Tests {
+		got, err := queue.For(tt.name)
+		if err != nil {
+			t.Fatalf("For(%q): %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("For(%q) = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := (Queue{Policy: "drop-all"}).For("slack"); err == nil {
+		t.Errorf("For() with an unknown policy succeeded")
+	}
+}
+
+func TestWorkersFor(t *testing.T) {
+	workers := Workers{Default: 2, Kinds: map[string]int{"pod": 8, "Event": 4, "event.Events.k8s.io": 6}}
+
+	var Tests = []struct {
+		workers Workers
+		kind    string
+		group   string
+		want    int
+	}{
+		{workers, "Pod", "", 8},
+		{workers, "Service", "", 2},
+		{workers, "Event", "", 4},
+		{workers, "Event", "events.k8s.io", 6},
+		{Workers{}, "Pod", "", 1},
+	}
+
+	for _, tt := range Tests {
+		if got := tt.workers.For(tt.kind, tt.group); got != tt.want {
+			t.Errorf("%+v.For(%q, %q) = %d, want %d", tt.workers, tt.kind, tt.group, got, tt.want)
+		}
+	}
+}
+
+func TestResourcesYAML(t *testing.T) {
+	c := &Config{}
+	data := "resources:\n- pod\n- name: deployment\n  labelSelector: app=api\n  fieldSelector: metadata.name!=canary\n- name: secret\n  metadataOnly: true\nresource:\n  rc: true\n  secret: false\n"
+	if err := yaml.Unmarshal([]byte(data), c); err != nil {
+		t.Fatalf("Unmarshal(): %v", err)
+	}
+	want := []Resource{{Name: "pod"}, {Name: "deployment", LabelSelector: "app=api", FieldSelector: "metadata.name!=canary"}, {Name: "secret", MetadataOnly: true}, {Name: "rc"}}
+	if got := c.WatchedResources(); !reflect.DeepEqual(got, want) {
+		t.Errorf("WatchedResources() = %+v, want %+v", got, want)
+	}
+}
+
+func TestWatchUnwatch(t *testing.T) {
+	c := &Config{
+		Resources:      []Resource{{Name: "pod"}},
+		LegacyResource: map[string]bool{"rc": true},
+	}
+	c.Watch("pod")
+	c.Watch("service")
+	c.Unwatch("replicationcontroller", "rc")
+
+	want := []Resource{{Name: "pod"}, {Name: "service"}}
+	if got := c.WatchedResources(); !reflect.DeepEqual(got, want) {
+		t.Errorf("WatchedResources() = %+v, want %+v", got, want)
+	}
+}
+
+func TestCheckMissingResourceEnvvars_Resources(t *testing.T) {
+	os.Setenv("KW_CRONJOB", "true")
+	os.Setenv("KW_STORAGE_CLASS", "false")
+	defer os.Unsetenv("KW_CRONJOB")
+	defer os.Unsetenv("KW_STORAGE_CLASS")
+
+	c := &Config{}
+	c.CheckMissingResourceEnvvars()
+	want := []Resource{{Name: "cronjob"}}
+	if got := c.WatchedResources(); !reflect.DeepEqual(got, want) {
+		t.Errorf("WatchedResources() = %+v, want %+v", got, want)
+	}
+}
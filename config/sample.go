@@ -9,6 +9,16 @@ handler:
     channel: ""
     # Title of the message.
     title: ""
+    # Go template of the message text; leave it empty for the default
+    # message.
+    template: ""
+    # Send the events of a window as a single digest message.
+    digest:
+      # Window during which events are collected and then sent as one
+      # message, e.g. 1m. Leave it empty to send each event on its own.
+      window: 0s
+      # How many object names each group of a digest lists; defaults to 5.
+      topNames: 0
   hipchat:
     # Hipchat token.
     token: ""
@@ -16,13 +26,29 @@ handler:
     room: ""
     # URL of the hipchat server.
     url: ""
+    # Go template of the message text; leave it empty for the default
+    # message.
+    template: ""
   mattermost:
     room: ""
     url: ""
     username: ""
+    # Go template of the message text; leave it empty for the default
+    # message.
+    template: ""
+    # Send the events of a window as a single digest message.
+    digest:
+      # Window during which events are collected and then sent as one
+      # message, e.g. 1m. Leave it empty to send each event on its own.
+      window: 0s
+      # How many object names each group of a digest lists; defaults to 5.
+      topNames: 0
   flock:
     # URL of the flock API.
     url: ""
+    # Go template of the message text; leave it empty for the default
+    # message.
+    template: ""
   webhook:
     # Webhook URL.
     url: ""
@@ -30,12 +56,28 @@ handler:
     tlsskip: ""
     # Path of webhook cert. Default value is false.
     cert: ""
+    # Go template of the message text; leave it empty for the default
+    # message.
+    template: ""
   cloudevent:
     # CloudEvent webhook URL.
     url: ""
+    # Go template of the message text; leave it empty for the default
+    # message.
+    template: ""
   msteams:
     # MSTeams API Webhook URL.
     webhookurl: ""
+    # Go template of the message text; leave it empty for the default
+    # message.
+    template: ""
+    # Send the events of a window as a single digest message.
+    digest:
+      # Window during which events are collected and then sent as one
+      # message, e.g. 1m. Leave it empty to send each event on its own.
+      window: 0s
+      # How many object names each group of a digest lists; defaults to 5.
+      topNames: 0
   smtp:
     # Destination e-mail address.
     to: ""
@@ -61,26 +103,149 @@ handler:
     requireTLS: false
     # SMTP hello field (optional)
     hello: ""
-# Resources to watch.
-resource:
-  deployment: false
-  rc: false
-  rs: false
-  ds: false
-  svc: false
-  po: false
-  job: false
-  node: false
-  clusterrole: false
-  clusterrolebinding: false
-  sa: false
-  pv: false
-  ns: false
-  hpa: false
-  secret: false
-  configmap: false
-  ing: false
+    # Go template of the message text; leave it empty for the default
+    # message.
+    template: ""
+    # Send the events of a window as a single digest e-mail.
+    digest:
+      # Window during which events are collected and then sent as one
+      # message, e.g. 1m. Leave it empty to send each event on its own.
+      window: 0s
+      # How many object names each group of a digest lists; defaults to 5.
+      topNames: 0
+# Named handler instances, for sending to several destinations of the
+# same type, e.g. two Slack channels. Instance names are made of
+# lowercase letters, digits and dashes, and must be unique.
+handlers: []
+# Resources to watch, by name, e.g. pod or deployment. Other resources the
+# cluster serves are found through API discovery by resource name, short
+# name or kind, optionally with their group, e.g. Certificate or
+# leases.coordination.k8s.io. Resources the cluster does not serve yet are
+# watched once their CRD is installed. Each entry can also set a
+# labelSelector and a fieldSelector the API server filters the objects with,
+# and metadataOnly to only watch the metadata of the objects.
+resources: []
+# Deprecated: use resources. Resources turned on and off by name, as in
+# older configuration files.
+resource: {}
 # For watching specific namespace, leave it empty for watching all.
 # this config is ignored when watching namespaces
 namespace: ""
+# Namespaces to watch, besides namespace; leave both empty for watching
+# all. Resources outside of namespaces, such as nodes, are watched
+# regardless.
+namespaces: []
+# Namespaces not to watch, as glob patterns, e.g. kube-system or
+# *-sandbox.
+excludeNamespaces: []
+# Label selector the namespaces to watch must match, e.g. team=foo.
+# Namespaces are followed as they are created and relabelled.
+# The selector only filters events: resources are still watched
+# across the cluster unless namespaces lists them.
+namespaceSelector: ""
+# Routes decide which handlers receive each event.
+routes:
+  # Handlers receiving the events no rule matched. Leave it empty to send
+  # them to every handler.
+  default: []
+  # Rules are evaluated in order; the first matching rule wins unless it
+  # sets continue.
+  rules: []
+# Filter decides which events are sent at all.
+filter:
+  # Built-in rule sets applied to the events no rule matched, e.g.
+  # "advanced".
+  presets: []
+  # Rules are evaluated in order; the first matching rule decides whether
+  # the event is sent. Events no rule or preset matched are sent.
+  rules: []
+  # CEL expressions over object, oldObject and event; events the rules
+  # let through are only sent when every expression is true.
+  expressions: []
+# IgnorePaths lists fields whose changes alone do not make an update
+# worth sending, on top of built-in ones such as
+# metadata.resourceVersion.
+ignorePaths: []
+# PrunePaths lists fields removed from the objects as soon as they are
+# received, before they are cached, compared or sent, e.g.
+# metadata.managedFields, of which the manager of the latest write is
+# kept for message templates.
+prunePaths: []
+# Dedup folds repeats of an event into periodic summaries.
+dedup:
+  # Window during which repeats of an event are counted rather than sent,
+  # e.g. 5m. Leave it empty to send every event.
+  window: 0s
+  # Fields of the object telling events apart besides their kind,
+  # namespace, name and reason, written as in ignorePaths, e.g.
+  # status.phase or metadata.annotations["kubernetes.io/change-cause"].
+  fields: []
+# Retry sets how failed deliveries are retried.
+retry:
+  # How many times an event is sent before giving up; defaults to 5.
+  # Set it to 1 to disable retries.
+  attempts: 0
+  # Wait before the first retry, doubled on every retry; defaults to 1s.
+  initialBackoff: 0s
+  # Upper bound of the wait between retries; defaults to 30s.
+  maxBackoff: 0s
+# Outbox keeps undelivered events on disk.
+outbox:
+  # Directory where each handler keeps the messages waiting for
+  # delivery, so that they survive restarts and outages of the
+  # destination. Leave it empty to keep them in memory only.
+  dir: ""
+# DeadLetter receives the events handlers give up on.
+deadLetter:
+  # File to append the events given up on to, one JSON object per line.
+  # Use "kubewatch deadletter" to inspect and replay them.
+  file: ""
+  # Name of the handler, from the handlers list, receiving the events
+  # given up on instead.
+  handler: ""
+# Limits protect destinations from bursts of messages and kubewatch
+# from slow destinations.
+limits:
+  # Messages per second sent to a destination, e.g. 0.5 for one message
+  # every two seconds. Leave it empty for no limit.
+  rate: 0
+  # Messages sent at once before the rate applies; defaults to 1.
+  burst: 0
+  # Timeout of a request to a destination; defaults to 10s.
+  timeout: 0s
+  # Consecutive failed deliveries after which a destination is deemed
+  # unhealthy and left alone for a while; defaults to 5.
+  breakerFailures: 0
+  # How long an unhealthy destination is left alone before a delivery
+  # probes it again; defaults to 30s.
+  breakerCooldown: 0s
+  # Limits of single handlers, by handler name, overriding the ones
+  # above.
+  handlers: {}
+# Queue hands events over to the handlers without waiting for their
+# delivery.
+queue:
+  # Send events from the resource controllers, waiting for every
+  # delivery, rather than through queues.
+  disabled: false
+  # Events waiting for each handler; defaults to 1000.
+  size: 0
+  # Deliveries running at once for each handler; defaults to 1, which
+  # keeps the events of a handler in order.
+  workers: 0
+  # What to do with an event when the queue is full: block, waiting for
+  # room, drop-oldest or drop-newest. Defaults to block.
+  policy: ""
+  # Queue settings of single handlers, by handler name, overriding the
+  # ones above.
+  handlers: {}
+# Workers sets how many events of each kind of resource are processed
+# at once.
+workers:
+  # Workers of each kind of resource; defaults to 1.
+  default: 0
+  # Workers of single kinds, e.g. Pod or Event, overriding the default.
+  # Kinds are matched case-insensitively; qualify one by its API group,
+  # e.g. Event.events.k8s.io, to tell it from the kinds of other groups.
+  kinds: {}
 `
@@ -0,0 +1,93 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"fmt"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/deadletter"
+	"github.com/bitnami-labs/kubewatch/pkg/digest"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers"
+	"github.com/sirupsen/logrus"
+)
+
+// ReplayDeadLetters sends the letters of the dead-letter file at path again,
+// each to the handler that gave up on it, or to the handler named target when
+// it is set. Letters selected by keep are left alone. Replayed letters are
+// removed from the file and the ones failing again are kept. It returns how
+// many letters were replayed and how many failed.
+func ReplayDeadLetters(conf *config.Config, path, target string, keep func(deadletter.Letter) bool) (int, int, error) {
+	letters, err := deadletter.Read(path)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// Replayed messages are sent right away, and the ones failing again stay
+	// in the file rather than being dead-lettered anew.
+	direct := conf.Direct()
+	group := newGroup(direct, nil)
+	if err := group.Init(direct); err != nil {
+		return 0, 0, err
+	}
+
+	var kept []deadletter.Letter
+	replayed, failed := 0, 0
+	for _, l := range letters {
+		if keep != nil && keep(l) {
+			kept = append(kept, l)
+			continue
+		}
+		if err := replay(group, l, target); err != nil {
+			logrus.Errorf("Cannot replay %s: %v", l.Subject(), err)
+			kept = append(kept, l)
+			failed++
+			continue
+		}
+		replayed++
+	}
+	return replayed, failed, deadletter.Write(path, kept)
+}
+
+// replay sends a letter to its handler, or to target when it is set. Letters
+// naming no handler are refused rather than sent to every handler, which
+// would skip the routes and filters the event went through.
+func replay(group *handlers.Group, l deadletter.Letter, target string) error {
+	name := l.Handler
+	if target != "" {
+		name = target
+	}
+	if name == "" {
+		return fmt.Errorf("the letter names no handler, replay it with --to")
+	}
+	h, ok := group.Get(name)
+	if !ok {
+		return fmt.Errorf("no handler is named %q", name)
+	}
+
+	switch {
+	case l.Event != nil:
+		return h.Handle(*l.Event)
+	case l.Digest != nil:
+		dh, ok := h.(digest.Handler)
+		if !ok {
+			return fmt.Errorf("handler %s cannot send digests", name)
+		}
+		return dh.HandleDigest(l.Digest)
+	}
+	return fmt.Errorf("the letter holds nothing to send")
+}
@@ -19,7 +19,6 @@ package client
 import (
 	"net/http"
 	"os"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	"github.com/bitnami-labs/kubewatch/config"
 	"github.com/bitnami-labs/kubewatch/pkg/controller"
@@ -34,6 +33,8 @@ import (
 	"github.com/bitnami-labs/kubewatch/pkg/handlers/slackwebhook"
 	"github.com/bitnami-labs/kubewatch/pkg/handlers/smtp"
 	"github.com/bitnami-labs/kubewatch/pkg/handlers/webhook"
+	"github.com/bitnami-labs/kubewatch/pkg/pipeline"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/sirupsen/logrus"
 )
 
@@ -52,40 +53,101 @@ func Run(conf *config.Config) {
 		}
 	}()
 
-	var eventHandler = ParseEventHandler(conf)
+	var eventHandler = pipeline.New(newEventHandler(conf))
+	if err := eventHandler.Init(conf); err != nil {
+		logrus.Fatal(err)
+	}
 	controller.Start(conf, eventHandler)
 }
 
-// ParseEventHandler returns the respective handler object specified in the config file.
+// ParseEventHandler returns the initialized handler objects specified in the
+// config file. They send events right away, without digests or outbox.
 func ParseEventHandler(conf *config.Config) handlers.Handler {
+	direct := conf.Direct()
+	eventHandler := newEventHandler(direct)
+	if err := eventHandler.Init(direct); err != nil {
+		logrus.Fatal(err)
+	}
+	return eventHandler
+}
+
+// newEventHandler returns the handler objects specified in the config file.
+// Handlers configured under `handler` are named after their type, named
+// instances under `handlers` by their name. When more than one handler is
+// configured, every event is fanned out to all of them, or to the ones selected
+// by the routing rules. Handlers with a digest window receive the events of
+// each window as one digest, and every handler retries its failed deliveries,
+// keeping them in its outbox meanwhile when one is configured.
+func newEventHandler(conf *config.Config) handlers.Handler {
+	deadLetter, err := handlers.NewDeadLetter(conf.DeadLetter)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	group := newGroup(conf, deadLetter)
+	if err := deadLetter.Resolve(group); err != nil {
+		logrus.Fatal(err)
+	}
 
 	var eventHandler handlers.Handler
 	switch {
-	case len(conf.Handler.Slack.Channel) > 0 || len(conf.Handler.Slack.Token) > 0:
-		eventHandler = new(slack.Slack)
-	case len(conf.Handler.SlackWebhook.Channel) > 0 || len(conf.Handler.SlackWebhook.Username) > 0 || len(conf.Handler.SlackWebhook.Slackwebhookurl) > 0:
-		eventHandler = new(slackwebhook.SlackWebhook)
-	case len(conf.Handler.Hipchat.Room) > 0 || len(conf.Handler.Hipchat.Token) > 0:
-		eventHandler = new(hipchat.Hipchat)
-	case len(conf.Handler.Mattermost.Channel) > 0 || len(conf.Handler.Mattermost.Url) > 0:
-		eventHandler = new(mattermost.Mattermost)
-	case len(conf.Handler.Flock.Url) > 0:
-		eventHandler = new(flock.Flock)
-	case len(conf.Handler.Webhook.Url) > 0:
-		eventHandler = new(webhook.Webhook)
-	case len(conf.Handler.CloudEvent.Url) > 0:
-		eventHandler = new(cloudevent.CloudEvent)
-	case len(conf.Handler.MSTeams.WebhookURL) > 0:
-		eventHandler = new(msteam.MSTeams)
-	case len(conf.Handler.SMTP.Smarthost) > 0 || len(conf.Handler.SMTP.To) > 0:
-		eventHandler = new(smtp.SMTP)
-	case len(conf.Handler.Lark.WebhookURL) > 0:
-		eventHandler = new(lark.Webhook)
-	default:
+	case len(conf.Routes.Rules) > 0 || len(conf.Routes.Default) > 0:
+		router, err := handlers.NewRouter(group, conf.Routes)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		eventHandler = router
+	case group.Len() == 0:
 		eventHandler = new(handlers.Default)
-	}
-	if err := eventHandler.Init(conf); err != nil {
-		logrus.Fatal(err)
+	case group.Len() == 1:
+		eventHandler = group.Members()[0]
+	default:
+		eventHandler = group
 	}
 	return eventHandler
 }
+
+// newGroup returns the handlers specified in the config file, named after
+// their type or instance name. The messages they give up on go to
+// deadLetter, which may be nil.
+func newGroup(conf *config.Config, deadLetter *handlers.DeadLetter) *handlers.Group {
+	configured := []struct {
+		name    string
+		enabled bool
+		handler handlers.Handler
+	}{
+		{"slack", len(conf.Handler.Slack.Channel) > 0 || len(conf.Handler.Slack.Token) > 0, new(slack.Slack)},
+		{"slackwebhook", len(conf.Handler.SlackWebhook.Channel) > 0 || len(conf.Handler.SlackWebhook.Username) > 0 || len(conf.Handler.SlackWebhook.Slackwebhookurl) > 0, new(slackwebhook.SlackWebhook)},
+		{"hipchat", len(conf.Handler.Hipchat.Room) > 0 || len(conf.Handler.Hipchat.Token) > 0, new(hipchat.Hipchat)},
+		{"mattermost", len(conf.Handler.Mattermost.Channel) > 0 || len(conf.Handler.Mattermost.Url) > 0, new(mattermost.Mattermost)},
+		{"flock", len(conf.Handler.Flock.Url) > 0, new(flock.Flock)},
+		{"webhook", len(conf.Handler.Webhook.Url) > 0, new(webhook.Webhook)},
+		{"cloudevent", len(conf.Handler.CloudEvent.Url) > 0, new(cloudevent.CloudEvent)},
+		{"ms-teams", len(conf.Handler.MSTeams.WebhookURL) > 0, new(msteam.MSTeams)},
+		{"smtp", len(conf.Handler.SMTP.Smarthost) > 0 || len(conf.Handler.SMTP.To) > 0, new(smtp.SMTP)},
+		{"lark", len(conf.Handler.Lark.WebhookURL) > 0, new(lark.Webhook)},
+	}
+
+	group := handlers.NewGroup()
+	for _, h := range configured {
+		if !h.enabled {
+			continue
+		}
+		handler, err := handlers.NewBatcher(h.name, handlers.NewOutbox(h.name, handlers.NewRetrier(h.name, handlers.NewGuard(h.name, h.handler)), deadLetter), conf.Handler.DigestFor(h.name))
+		if err != nil {
+			logrus.Fatalf("handler %s: %v", h.name, err)
+		}
+		group.Add(h.name, handlers.NewQueue(h.name, handler))
+	}
+	for _, i := range conf.Handlers {
+		instance, err := handlers.NewInstance(i, deadLetter)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		if group.Has(i.Name) {
+			logrus.Fatalf("handler name %q is used more than once", i.Name)
+		}
+		group.Add(i.Name, instance)
+	}
+
+	return group
+}
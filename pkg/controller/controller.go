@@ -17,54 +17,40 @@ limitations under the License.
 package controller
 
 import (
-	"context"
 	"fmt"
-	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
-	"k8s.io/apimachinery/pkg/runtime/schema"
+	"hash/fnv"
 	"os"
 	"os/signal"
-	"reflect"
 	"strings"
 	"syscall"
 	"time"
 
 	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/diff"
 	"github.com/bitnami-labs/kubewatch/pkg/event"
 	"github.com/bitnami-labs/kubewatch/pkg/handlers"
+	"github.com/bitnami-labs/kubewatch/pkg/prune"
 	"github.com/bitnami-labs/kubewatch/pkg/redact"
+	"github.com/bitnami-labs/kubewatch/pkg/resources"
 	"github.com/bitnami-labs/kubewatch/pkg/utils"
 	"github.com/sirupsen/logrus"
 
-	apps_v1 "k8s.io/api/apps/v1"
-	autoscaling_v1 "k8s.io/api/autoscaling/v1"
-	batch_v1 "k8s.io/api/batch/v1"
-	api_v1 "k8s.io/api/core/v1"
-	events_v1 "k8s.io/api/events/v1"
-	networking_v1 "k8s.io/api/networking/v1"
-	rbac_v1 "k8s.io/api/rbac/v1"
-	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
-	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/metadata"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
 
 	"github.com/prometheus/client_golang/prometheus"
-    "github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
-const maxRetries = 5
 const V1 = "v1"
-const AUTOSCALING_V1 = "autoscaling/v1"
-const APPS_V1 = "apps/v1"
-const BATCH_V1 = "batch/v1"
-const RBAC_V1 = "rbac.authorization.k8s.io/v1"
-const NETWORKING_V1 = "networking.k8s.io/v1"
-const EVENTS_V1 = "events.k8s.io/v1"
 
 var serverStartTime time.Time
 
@@ -77,19 +63,20 @@ type Event struct {
 	apiVersion   string
 	obj          runtime.Object
 	oldObj       runtime.Object
+	// changes made by an update, redacted. Queued events must be comparable,
+	// hence the pointer.
+	changes *[]diff.Change
 }
 
 // Controller object
 type Controller struct {
 	logger       *logrus.Entry
 	clientset    kubernetes.Interface
-	queue        workqueue.RateLimitingInterface
 	informer     cache.SharedIndexInformer
 	eventHandler handlers.Handler
-}
-
-func objName(obj interface{}) string {
-	return reflect.TypeOf(obj).Name()
+	// queues hold the events to process, one queue per worker. The events
+	// of an object always go to the same queue, so they keep their order.
+	queues []workqueue.RateLimitingInterface
 }
 
 // TODO: we don't need the informer to be indexed
@@ -97,7 +84,8 @@ func objName(obj interface{}) string {
 func Start(conf *config.Config, eventHandler handlers.Handler) {
 	var kubeClient kubernetes.Interface
 	var dynamicClient dynamic.Interface
-	
+	var metadataClient metadata.Interface
+
 	kubewatchEventsMetrics := promauto.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "kubewatch_events_total",
@@ -106,490 +94,86 @@ func Start(conf *config.Config, eventHandler handlers.Handler) {
 		[]string{"resourceType", "eventType"},
 	)
 
+	ignore, err := diff.NewIgnoreRules(conf.IgnorePaths)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+
+	pruned, err := prune.NewRules(conf.PrunePaths)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+
 	if _, err := rest.InClusterConfig(); err != nil {
 		kubeClient = utils.GetClientOutOfCluster()
 		dynamicClient = utils.GetDynamicClientOutOfCluster()
+		metadataClient = utils.GetMetadataClientOutOfCluster()
 	} else {
 		kubeClient = utils.GetClient()
 		dynamicClient = utils.GetDynamicClient()
+		metadataClient = utils.GetMetadataClient()
 	}
 
-	// User Configured Events
-	if conf.Resource.CoreEvent {
-		allCoreEventsInformer := cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					options.FieldSelector = ""
-					return kubeClient.CoreV1().Events(conf.Namespace).List(context.Background(), options)
-				},
-				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					options.FieldSelector = ""
-					return kubeClient.CoreV1().Events(conf.Namespace).Watch(context.Background(), options)
-				},
-			},
-			&api_v1.Event{},
-			0, //Skip resync
-			cache.Indexers{},
-		)
-
-		allCoreEventsController := newResourceController(kubeClient, eventHandler, allCoreEventsInformer, objName(api_v1.Event{}), V1, kubewatchEventsMetrics)
-		stopAllCoreEventsCh := make(chan struct{})
-		defer close(stopAllCoreEventsCh)
-
-		go allCoreEventsController.Run(stopAllCoreEventsCh)
-	}
-
-	if conf.Resource.Event {
-		allEventsInformer := cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					options.FieldSelector = ""
-					return kubeClient.EventsV1().Events(conf.Namespace).List(context.Background(), options)
-				},
-				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					options.FieldSelector = ""
-					return kubeClient.EventsV1().Events(conf.Namespace).Watch(context.Background(), options)
-				},
-			},
-			&events_v1.Event{},
-			0, //Skip resync
-			cache.Indexers{},
-		)
-
-		allEventsController := newResourceController(kubeClient, eventHandler, allEventsInformer, objName(events_v1.Event{}), EVENTS_V1, kubewatchEventsMetrics)
-		stopAllEventsCh := make(chan struct{})
-		defer close(stopAllEventsCh)
-
-		go allEventsController.Run(stopAllEventsCh)
-	}
-
-	if conf.Resource.Pod {
-		informer := cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.CoreV1().Pods(conf.Namespace).List(context.Background(), options)
-				},
-				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.CoreV1().Pods(conf.Namespace).Watch(context.Background(), options)
-				},
-			},
-			&api_v1.Pod{},
-			0, //Skip resync
-			cache.Indexers{},
-		)
-
-		c := newResourceController(kubeClient, eventHandler, informer, objName(api_v1.Pod{}), V1, kubewatchEventsMetrics)
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
-	}
-
-	if conf.Resource.HPA {
-		informer := cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.AutoscalingV1().HorizontalPodAutoscalers(conf.Namespace).List(context.Background(), options)
-				},
-				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.AutoscalingV1().HorizontalPodAutoscalers(conf.Namespace).Watch(context.Background(), options)
-				},
-			},
-			&autoscaling_v1.HorizontalPodAutoscaler{},
-			0, //Skip resync
-			cache.Indexers{},
-		)
-
-		c := newResourceController(kubeClient, eventHandler, informer, objName(autoscaling_v1.HorizontalPodAutoscaler{}), AUTOSCALING_V1, kubewatchEventsMetrics)
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
-
-	}
-
-	if conf.Resource.DaemonSet {
-		informer := cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.AppsV1().DaemonSets(conf.Namespace).List(context.Background(), options)
-				},
-				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.AppsV1().DaemonSets(conf.Namespace).Watch(context.Background(), options)
-				},
-			},
-			&apps_v1.DaemonSet{},
-			0, //Skip resync
-			cache.Indexers{},
-		)
-
-		c := newResourceController(kubeClient, eventHandler, informer, objName(apps_v1.DaemonSet{}), APPS_V1, kubewatchEventsMetrics)
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
-	}
-
-	if conf.Resource.StatefulSet {
-		informer := cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.AppsV1().StatefulSets(conf.Namespace).List(context.Background(), options)
-				},
-				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.AppsV1().StatefulSets(conf.Namespace).Watch(context.Background(), options)
-				},
-			},
-			&apps_v1.StatefulSet{},
-			0, //Skip resync
-			cache.Indexers{},
-		)
-
-		c := newResourceController(kubeClient, eventHandler, informer, objName(apps_v1.StatefulSet{}), APPS_V1, kubewatchEventsMetrics)
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
-	}
-
-	if conf.Resource.ReplicaSet {
-		informer := cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.AppsV1().ReplicaSets(conf.Namespace).List(context.Background(), options)
-				},
-				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.AppsV1().ReplicaSets(conf.Namespace).Watch(context.Background(), options)
-				},
-			},
-			&apps_v1.ReplicaSet{},
-			0, //Skip resync
-			cache.Indexers{},
-		)
-
-		c := newResourceController(kubeClient, eventHandler, informer, objName(apps_v1.ReplicaSet{}), APPS_V1, kubewatchEventsMetrics)
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
-	}
-
-	if conf.Resource.Services {
-		informer := cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.CoreV1().Services(conf.Namespace).List(context.Background(), options)
-				},
-				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.CoreV1().Services(conf.Namespace).Watch(context.Background(), options)
-				},
-			},
-			&api_v1.Service{},
-			0, //Skip resync
-			cache.Indexers{},
-		)
-
-		c := newResourceController(kubeClient, eventHandler, informer, objName(api_v1.Service{}), V1, kubewatchEventsMetrics)
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
-	}
-
-	if conf.Resource.Deployment {
-		informer := cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.AppsV1().Deployments(conf.Namespace).List(context.Background(), options)
-				},
-				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.AppsV1().Deployments(conf.Namespace).Watch(context.Background(), options)
-				},
-			},
-			&apps_v1.Deployment{},
-			0, //Skip resync
-			cache.Indexers{},
-		)
-
-		c := newResourceController(kubeClient, eventHandler, informer, objName(apps_v1.Deployment{}), APPS_V1, kubewatchEventsMetrics)
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
-	}
-
-	if conf.Resource.Namespace {
-		informer := cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.CoreV1().Namespaces().List(context.Background(), options)
-				},
-				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.CoreV1().Namespaces().Watch(context.Background(), options)
-				},
-			},
-			&api_v1.Namespace{},
-			0, //Skip resync
-			cache.Indexers{},
-		)
-
-		c := newResourceController(kubeClient, eventHandler, informer, objName(api_v1.Namespace{}), V1, kubewatchEventsMetrics)
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
-	}
-
-	if conf.Resource.ReplicationController {
-		informer := cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.CoreV1().ReplicationControllers(conf.Namespace).List(context.Background(), options)
-				},
-				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.CoreV1().ReplicationControllers(conf.Namespace).Watch(context.Background(), options)
-				},
-			},
-			&api_v1.ReplicationController{},
-			0, //Skip resync
-			cache.Indexers{},
-		)
-
-		c := newResourceController(kubeClient, eventHandler, informer, objName(api_v1.ReplicationController{}), V1, kubewatchEventsMetrics)
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
-	}
-
-	if conf.Resource.Job {
-		informer := cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.BatchV1().Jobs(conf.Namespace).List(context.Background(), options)
-				},
-				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.BatchV1().Jobs(conf.Namespace).Watch(context.Background(), options)
-				},
-			},
-			&batch_v1.Job{},
-			0, //Skip resync
-			cache.Indexers{},
-		)
-
-		c := newResourceController(kubeClient, eventHandler, informer, objName(batch_v1.Job{}), BATCH_V1, kubewatchEventsMetrics)
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
-	}
-
-	if conf.Resource.Node {
-		informer := cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.CoreV1().Nodes().List(context.Background(), options)
-				},
-				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.CoreV1().Nodes().Watch(context.Background(), options)
-				},
-			},
-			&api_v1.Node{},
-			0, //Skip resync
-			cache.Indexers{},
-		)
-
-		c := newResourceController(kubeClient, eventHandler, informer, objName(api_v1.Node{}), V1, kubewatchEventsMetrics)
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
-	}
-
-	if conf.Resource.ServiceAccount {
-		informer := cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.CoreV1().ServiceAccounts(conf.Namespace).List(context.Background(), options)
-				},
-				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.CoreV1().ServiceAccounts(conf.Namespace).Watch(context.Background(), options)
-				},
-			},
-			&api_v1.ServiceAccount{},
-			0, //Skip resync
-			cache.Indexers{},
-		)
-
-		c := newResourceController(kubeClient, eventHandler, informer, objName(api_v1.ServiceAccount{}), V1, kubewatchEventsMetrics)
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
-	}
-
-	if conf.Resource.ClusterRole {
-		informer := cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.RbacV1().ClusterRoles().List(context.Background(), options)
-				},
-				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.RbacV1().ClusterRoles().Watch(context.Background(), options)
-				},
-			},
-			&rbac_v1.ClusterRole{},
-			0, //Skip resync
-			cache.Indexers{},
-		)
-
-		c := newResourceController(kubeClient, eventHandler, informer, objName(rbac_v1.ClusterRole{}), RBAC_V1, kubewatchEventsMetrics)
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
-	}
-
-	if conf.Resource.ClusterRoleBinding {
-		informer := cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.RbacV1().ClusterRoleBindings().List(context.Background(), options)
-				},
-				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.RbacV1().ClusterRoleBindings().Watch(context.Background(), options)
-				},
-			},
-			&rbac_v1.ClusterRoleBinding{},
-			0, //Skip resync
-			cache.Indexers{},
-		)
-
-		c := newResourceController(kubeClient, eventHandler, informer, objName(rbac_v1.ClusterRoleBinding{}), RBAC_V1, kubewatchEventsMetrics)
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
+	scope, err := newNamespaceScope(conf)
+	if err != nil {
+		logrus.Fatal(err)
 	}
-
-	if conf.Resource.PersistentVolume {
-		informer := cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.CoreV1().PersistentVolumes().List(context.Background(), options)
-				},
-				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.CoreV1().PersistentVolumes().Watch(context.Background(), options)
-				},
-			},
-			&api_v1.PersistentVolume{},
-			0, //Skip resync
-			cache.Indexers{},
-		)
-
-		c := newResourceController(kubeClient, eventHandler, informer, objName(api_v1.PersistentVolume{}), V1, kubewatchEventsMetrics)
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
+	resolver := resources.NewResolver(resources.NewRegistry(), kubeClient.Discovery())
+	watched, pending, err := watchedResources(conf, resolver)
+	if err != nil {
+		logrus.Fatal(err)
 	}
-
-	if conf.Resource.Secret {
-		informer := cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.CoreV1().Secrets(conf.Namespace).List(context.Background(), options)
-				},
-				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.CoreV1().Secrets(conf.Namespace).Watch(context.Background(), options)
-				},
-			},
-			&api_v1.Secret{},
-			0, //Skip resync
-			cache.Indexers{},
-		)
-
-		c := newResourceController(kubeClient, eventHandler, informer, objName(api_v1.Secret{}), V1, kubewatchEventsMetrics)
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
+	if err := checkWorkers(conf.Workers, watched, pending); err != nil {
+		logrus.Fatal(err)
 	}
 
-	if conf.Resource.ConfigMap {
-		informer := cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.CoreV1().ConfigMaps(conf.Namespace).List(context.Background(), options)
-				},
-				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.CoreV1().ConfigMaps(conf.Namespace).Watch(context.Background(), options)
-				},
-			},
-			&api_v1.ConfigMap{},
-			0, //Skip resync
-			cache.Indexers{},
-		)
-
-		c := newResourceController(kubeClient, eventHandler, informer, objName(api_v1.ConfigMap{}), V1, kubewatchEventsMetrics)
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
+	// Informers share the clients and, through their factories, the caches
+	// of the resources they watch.
+	factories := newFactories(kubeClient, dynamicClient, metadataClient, scope, pruned)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+
+	// The namespaces matching the namespace selector are known before any
+	// object is, so that none is dropped for want of its namespace.
+	if scope.selector != nil {
+		informer, err := factories.namespaceInformer(scope.selector)
+		if err != nil {
+			logrus.Fatalf("Cannot watch namespaces: %v", err)
+		}
+		scope.track(informer)
+		factories.start(stopCh)
+		if !cache.WaitForCacheSync(stopCh, informer.HasSynced) {
+			logrus.Fatal("Timed out waiting for the namespaces to sync")
+		}
 	}
 
-	if conf.Resource.Ingress {
-		informer := cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.NetworkingV1().Ingresses(conf.Namespace).List(context.Background(), options)
-				},
-				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.NetworkingV1().Ingresses(conf.Namespace).Watch(context.Background(), options)
-				},
-			},
-			&networking_v1.Ingress{},
-			0, //Skip resync
-			cache.Indexers{},
-		)
-
-		c := newResourceController(kubeClient, eventHandler, informer, objName(networking_v1.Ingress{}), NETWORKING_V1, kubewatchEventsMetrics)
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
+	watch := func(w watchedResource) {
+		informers, inScope, err := factories.informers(w)
+		if err != nil {
+			logrus.Fatalf("Cannot watch %s: %v", w.Name, err)
+		}
+		for _, informer := range informers {
+			c := newResourceController(kubeClient, eventHandler, informer, w.Kind, w.APIVersion(), kubewatchEventsMetrics, ignore, conf.Workers, inScope)
+			go c.Run(stopCh)
+		}
 	}
-
-	for _, curRes := range conf.CustomResources {
-		crd := curRes
-		informer := cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return dynamicClient.Resource(schema.GroupVersionResource{
-						Group:    crd.Group,
-						Version:  crd.Version,
-						Resource: crd.Resource,
-					}).List(context.Background(), options)
-				},
-				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return dynamicClient.Resource(schema.GroupVersionResource{
-						Group:    crd.Group,
-						Version:  crd.Version,
-						Resource: crd.Resource,
-					}).Watch(context.Background(), options)
-				},
-			},
-			&unstructured.Unstructured{},
-			0, //Skip resync
-			cache.Indexers{},
-		)
-
-		c := newResourceController(kubeClient, eventHandler, informer, crd.Resource, fmt.Sprintf("%s/%s", crd.Group, crd.Version), kubewatchEventsMetrics)
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
+	for _, w := range watched {
+		watch(w)
 	}
+	factories.start(stopCh)
+
+	// Resources found later, such as custom resources whose CRD was
+	// installed since, get their informers started as they come.
+	go resolvePending(resolver, pending, rediscoverInterval, stopCh, func(w watchedResource) {
+		for _, other := range watched {
+			if other.key() == w.key() {
+				return
+			}
+		}
+		watched = append(watched, w)
+		watch(w)
+		factories.start(stopCh)
+	})
 
 	sigterm := make(chan os.Signal, 1)
 	signal.Notify(sigterm, syscall.SIGTERM)
@@ -597,16 +181,25 @@ func Start(conf *config.Config, eventHandler handlers.Handler) {
 	<-sigterm
 }
 
-func newResourceController(client kubernetes.Interface, eventHandler handlers.Handler, informer cache.SharedIndexInformer, resourceType string, apiVersion string, kubewatchEventsMetrics *prometheus.CounterVec) *Controller {
-	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+func newResourceController(client kubernetes.Interface, eventHandler handlers.Handler, informer cache.SharedIndexInformer, resourceType string, apiVersion string, kubewatchEventsMetrics *prometheus.CounterVec, ignore *diff.IgnoreRules, workers config.Workers, inScope func(namespace string) bool) *Controller {
+	gv, _ := schema.ParseGroupVersion(apiVersion)
+	queues := make([]workqueue.RateLimitingInterface, workers.For(resourceType, gv.Group))
+	for i := range queues {
+		queues[i] = workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	}
+	queue := func(key string) workqueue.RateLimitingInterface {
+		return queueFor(queues, key)
+	}
+	ignoredPaths := ignore.For(resourceType)
 	var newEvent Event
 	var err error
-	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+	var handler cache.ResourceEventHandler = cache.ResourceEventHandlerFuncs{
 		AddFunc: func(obj interface{}) {
 			var ok bool
 			newEvent.namespace = "" // namespace retrived in processItem incase namespace value is empty
 			newEvent.key, err = cache.MetaNamespaceKeyFunc(obj)
 			newEvent.eventType = "create"
+			newEvent.changes = nil
 			newEvent.resourceType = resourceType
 			newEvent.apiVersion = apiVersion
 			newEvent.obj, ok = obj.(runtime.Object)
@@ -615,7 +208,7 @@ func newResourceController(client kubernetes.Interface, eventHandler handlers.Ha
 			}
 			logrus.WithField("pkg", "kubewatch-"+resourceType).Infof("Processing add to %v: %s", resourceType, newEvent.key)
 			if err == nil {
-				queue.Add(newEvent)
+				queue(newEvent.key).Add(newEvent)
 			}
 
 			kubewatchEventsMetrics.WithLabelValues(resourceType, "create").Inc()
@@ -635,9 +228,21 @@ func newResourceController(client kubernetes.Interface, eventHandler handlers.Ha
 			if !ok {
 				logrus.WithField("pkg", "kubewatch-"+resourceType).Errorf("cannot convert old to runtime.Object for update on %v", old)
 			}
-			logrus.WithField("pkg", "kubewatch-"+resourceType).Infof("Processing update to %v: %s", resourceType, newEvent.key)
-			if err == nil {
-				queue.Add(newEvent)
+			// Changes are computed before redaction, so rotating a Secret's
+			// data still counts as a change, and redacted afterwards.
+			changes, diffErr := diff.ComputeIgnoring(newEvent.oldObj, newEvent.obj, ignoredPaths)
+			newEvent.changes = nil
+			if diffErr != nil {
+				logrus.WithField("pkg", "kubewatch-"+resourceType).Warnf("cannot compute changes to %s: %v", newEvent.key, diffErr)
+			} else if len(changes) == 0 {
+				logrus.WithField("pkg", "kubewatch-"+resourceType).Debugf("Ignoring update to %v: %s, only ignored fields changed", resourceType, newEvent.key)
+			} else {
+				changes = redact.Changes(newEvent.obj, changes)
+				newEvent.changes = &changes
+			}
+			if err == nil && (diffErr != nil || len(changes) > 0) {
+				logrus.WithField("pkg", "kubewatch-"+resourceType).Infof("Processing update to %v: %s", resourceType, newEvent.key)
+				queue(newEvent.key).Add(newEvent)
 			}
 
 			kubewatchEventsMetrics.WithLabelValues(resourceType, "update").Inc()
@@ -647,6 +252,7 @@ func newResourceController(client kubernetes.Interface, eventHandler handlers.Ha
 			newEvent.namespace = "" // namespace retrived in processItem incase namespace value is empty
 			newEvent.key, err = cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
 			newEvent.eventType = "delete"
+			newEvent.changes = nil
 			newEvent.resourceType = resourceType
 			newEvent.apiVersion = apiVersion
 			newEvent.obj, ok = obj.(runtime.Object)
@@ -655,40 +261,76 @@ func newResourceController(client kubernetes.Interface, eventHandler handlers.Ha
 			}
 			logrus.WithField("pkg", "kubewatch-"+resourceType).Infof("Processing delete to %v: %s", resourceType, newEvent.key)
 			if err == nil {
-				queue.Add(newEvent)
+				queue(newEvent.key).Add(newEvent)
 			}
 
 			kubewatchEventsMetrics.WithLabelValues(resourceType, "delete").Inc()
 		},
-	})
+	}
+	// Informers watching the whole cluster for a few namespaces hand over
+	// the objects of the others too. Namespace objects, the only
+	// cluster-scoped ones filtered, stand for the namespace they name.
+	if inScope != nil {
+		handler = cache.FilteringResourceEventHandler{
+			FilterFunc: func(obj interface{}) bool {
+				key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+				if err != nil {
+					return false
+				}
+				namespace, name, _ := cache.SplitMetaNamespaceKey(key)
+				if namespace == "" {
+					namespace = name
+				}
+				return inScope(namespace)
+			},
+			Handler: handler,
+		}
+	}
+	informer.AddEventHandler(handler)
 
 	return &Controller{
 		logger:       logrus.WithField("pkg", "kubewatch-"+resourceType),
 		clientset:    client,
 		informer:     informer,
-		queue:        queue,
+		queues:       queues,
 		eventHandler: eventHandler,
 	}
 }
 
-// Run starts the kubewatch controller
+// Run starts the kubewatch controller once its informer, started by the
+// caller, has synced
 func (c *Controller) Run(stopCh <-chan struct{}) {
 	defer utilruntime.HandleCrash()
-	defer c.queue.ShutDown()
+	defer func() {
+		for _, queue := range c.queues {
+			queue.ShutDown()
+		}
+	}()
 
 	c.logger.Info("Starting kubewatch controller")
 	serverStartTime = time.Now().Local()
 
-	go c.informer.Run(stopCh)
-
 	if !cache.WaitForCacheSync(stopCh, c.HasSynced) {
 		utilruntime.HandleError(fmt.Errorf("Timed out waiting for caches to sync"))
 		return
 	}
 
-	c.logger.Info("Kubewatch controller synced and ready")
+	c.logger.Infof("Kubewatch controller synced and ready, with %d workers", len(c.queues))
 
-	wait.Until(c.runWorker, time.Second, stopCh)
+	for _, queue := range c.queues {
+		go wait.Until(func() { c.runWorker(queue) }, time.Second, stopCh)
+	}
+	<-stopCh
+}
+
+// queueFor returns the queue of the events of the object with the given key
+func queueFor(queues []workqueue.RateLimitingInterface, key string) workqueue.RateLimitingInterface {
+	if len(queues) == 1 {
+		return queues[0]
+	}
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	return queues[hash.Sum32()%uint32(len(queues))]
 }
 
 // HasSynced is required for the cache.Controller interface.
@@ -701,32 +343,27 @@ func (c *Controller) LastSyncResourceVersion() string {
 	return c.informer.LastSyncResourceVersion()
 }
 
-func (c *Controller) runWorker() {
-	for c.processNextItem() {
+func (c *Controller) runWorker(queue workqueue.RateLimitingInterface) {
+	for c.processNextItem(queue) {
 		// continue looping
 	}
 }
 
-func (c *Controller) processNextItem() bool {
-	newEvent, quit := c.queue.Get()
+func (c *Controller) processNextItem(queue workqueue.RateLimitingInterface) bool {
+	newEvent, quit := queue.Get()
 
 	if quit {
 		return false
 	}
-	defer c.queue.Done(newEvent)
-	err := c.processItem(newEvent.(Event))
-	if err == nil {
-		// No error, reset the ratelimit counters
-		c.queue.Forget(newEvent)
-	} else if c.queue.NumRequeues(newEvent) < maxRetries {
-		c.logger.Errorf("Error processing %s (will retry): %v", newEvent.(Event).key, err)
-		c.queue.AddRateLimited(newEvent)
-	} else {
-		// err != nil and too many retries
-		c.logger.Errorf("Error processing %s (giving up): %v", newEvent.(Event).key, err)
-		c.queue.Forget(newEvent)
+	defer queue.Done(newEvent)
+	// Events are not requeued: each handler retries its failed deliveries
+	// and keeps the ones it gives up on in the dead letters, and requeueing
+	// would send the event again to the handlers that did get it.
+	if err := c.processItem(newEvent.(Event)); err != nil {
+		c.logger.Errorf("Error processing %s: %v", newEvent.(Event).key, err)
 		utilruntime.HandleError(err)
 	}
+	queue.Forget(newEvent)
 
 	return true
 }
@@ -786,13 +423,10 @@ func (c *Controller) processItem(newEvent Event) error {
 				Reason:     "Created",
 				Obj:        redact.Object(newEvent.obj),
 			}
-			c.eventHandler.Handle(kbEvent)
+			c.deliver(kbEvent)
 			return nil
 		}
 	case "update":
-		/* TODOs
-		- enahace update event processing in such a way that, it send alerts about what got changed.
-		*/
 		switch newEvent.resourceType {
 		case "Backoff":
 			status = "Danger"
@@ -809,7 +443,10 @@ func (c *Controller) processItem(newEvent Event) error {
 			Obj:        redact.Object(newEvent.obj),
 			OldObj:     redact.Object(newEvent.oldObj),
 		}
-		c.eventHandler.Handle(kbEvent)
+		if newEvent.changes != nil {
+			kbEvent.Changes = *newEvent.changes
+		}
+		c.deliver(kbEvent)
 		return nil
 	case "delete":
 		kbEvent := event.Event{
@@ -821,8 +458,17 @@ func (c *Controller) processItem(newEvent Event) error {
 			Reason:     "Deleted",
 			Obj:        redact.Object(newEvent.obj),
 		}
-		c.eventHandler.Handle(kbEvent)
+		c.deliver(kbEvent)
 		return nil
 	}
 	return nil
 }
+
+// deliver hands an event to the handlers. Each destination retries on its
+// own, so a failed delivery is logged rather than requeued, which would send
+// the event again to the destinations that did get it.
+func (c *Controller) deliver(e event.Event) {
+	if err := c.eventHandler.Handle(e); err != nil {
+		c.logger.Errorf("Error sending %s %s: %v", e.Kind, e.Name, err)
+	}
+}
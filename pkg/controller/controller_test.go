@@ -20,15 +20,20 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"fmt"
+	"math/rand"
+	"reflect"
 	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/diff"
 	"github.com/bitnami-labs/kubewatch/pkg/event"
 	"github.com/prometheus/client_golang/prometheus"
 	api_v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/watch"
@@ -49,10 +54,11 @@ type recordingHandler struct {
 
 func (h *recordingHandler) Init(*config.Config) error { return nil }
 
-func (h *recordingHandler) Handle(e event.Event) {
+func (h *recordingHandler) Handle(e event.Event) error {
 	h.mutex.Lock()
 	defer h.mutex.Unlock()
 	h.events = append(h.events, e)
+	return nil
 }
 
 func (h *recordingHandler) recorded() []event.Event {
@@ -104,9 +110,10 @@ func TestSecretEventsReachHandlersRedacted(t *testing.T) {
 	handler := &recordingHandler{}
 	metrics := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_events_total"}, []string{"resource", "type"})
 
-	controller := newResourceController(client, handler, secretInformer(client), "secret", V1, metrics)
+	controller := newResourceController(client, handler, secretInformer(client), "secret", V1, metrics, nil, config.Workers{}, nil)
 	stop := make(chan struct{})
 	defer close(stop)
+	go controller.informer.Run(stop)
 	go controller.Run(stop)
 
 	if !cache.WaitForCacheSync(stop, controller.HasSynced) {
@@ -152,7 +159,7 @@ func TestSecretEventsReachHandlersRedacted(t *testing.T) {
 		sawReason[e.Reason] = true
 
 		// Serialize the event the way a handler would and look for the bytes.
-		payload, err := json.Marshal(map[string]interface{}{"obj": e.Obj, "oldObj": e.OldObj})
+		payload, err := json.Marshal(map[string]interface{}{"obj": e.Obj, "oldObj": e.OldObj, "changes": e.Changes})
 		if err != nil {
 			t.Fatalf("marshalling %s event: %v", e.Reason, err)
 		}
@@ -170,6 +177,10 @@ func TestSecretEventsReachHandlersRedacted(t *testing.T) {
 		}
 	}
 
+	if got := events[1].Changes; len(got) != 1 || got[0].Path != "data.password" {
+		t.Errorf("Updated event: Changes = %v, want the password only", got)
+	}
+
 	for _, reason := range []string{"Created", "Updated", "Deleted"} {
 		if !sawReason[reason] {
 			t.Errorf("no %s event was emitted; got %v", reason, sawReason)
@@ -186,9 +197,10 @@ func TestSecretRedactionLeavesInformerCacheIntact(t *testing.T) {
 	metrics := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "cache_test_events_total"}, []string{"resource", "type"})
 
 	informer := secretInformer(client)
-	controller := newResourceController(client, handler, informer, "secret", V1, metrics)
+	controller := newResourceController(client, handler, informer, "secret", V1, metrics, nil, config.Workers{}, nil)
 	stop := make(chan struct{})
 	defer close(stop)
+	go controller.informer.Run(stop)
 	go controller.Run(stop)
 
 	if !cache.WaitForCacheSync(stop, controller.HasSynced) {
@@ -217,3 +229,138 @@ func TestSecretRedactionLeavesInformerCacheIntact(t *testing.T) {
 		t.Errorf("informer cache was mutated: Data[password] = %q, want %q", got, sentinel)
 	}
 }
+
+// TestUpdatesChangingOnlyIgnoredPathsAreDropped checks that an update touching
+// nothing but ignored fields never reaches a handler, and that the ignored
+// fields are left out of the changes of the updates that do.
+func TestUpdatesChangingOnlyIgnoredPathsAreDropped(t *testing.T) {
+	client := k8sfake.NewSimpleClientset()
+	handler := &recordingHandler{}
+	metrics := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "ignore_test_events_total"}, []string{"resource", "type"})
+	ignore, err := diff.NewIgnoreRules([]config.IgnorePathRule{
+		{Kinds: []string{"Secret"}, Paths: []string{`metadata.annotations["example.com/checked-at"]`}},
+	})
+	if err != nil {
+		t.Fatalf("NewIgnoreRules(): %v", err)
+	}
+
+	controller := newResourceController(client, handler, secretInformer(client), "Secret", V1, metrics, ignore, config.Workers{}, nil)
+	stop := make(chan struct{})
+	defer close(stop)
+	go controller.informer.Run(stop)
+	go controller.Run(stop)
+
+	if !cache.WaitForCacheSync(stop, controller.HasSynced) {
+		t.Fatal("informer cache never synced")
+	}
+
+	secret, err := client.CoreV1().Secrets("default").Create(context.Background(), &api_v1.Secret{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:              "creds",
+			Namespace:         "default",
+			CreationTimestamp: meta_v1.NewTime(time.Now().Add(time.Minute)),
+			Annotations:       map[string]string{"example.com/checked-at": "10:00"},
+		},
+	}, meta_v1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("creating secret: %v", err)
+	}
+	handler.waitForEvents(t, 1)
+
+	secret = secret.DeepCopy()
+	secret.Annotations["example.com/checked-at"] = "10:01"
+	if secret, err = client.CoreV1().Secrets("default").Update(context.Background(), secret, meta_v1.UpdateOptions{}); err != nil {
+		t.Fatalf("updating secret: %v", err)
+	}
+	secret = secret.DeepCopy()
+	secret.Annotations["example.com/checked-at"] = "10:02"
+	secret.Labels = map[string]string{"team": "payments"}
+	if _, err = client.CoreV1().Secrets("default").Update(context.Background(), secret, meta_v1.UpdateOptions{}); err != nil {
+		t.Fatalf("updating secret: %v", err)
+	}
+
+	events := handler.waitForEvents(t, 2)
+	want := []diff.Change{{Path: "metadata.labels", New: map[string]interface{}{"team": "payments"}}}
+	if !reflect.DeepEqual(events[1].Changes, want) {
+		t.Errorf("second event: Changes = %v, want %v", events[1].Changes, want)
+	}
+}
+
+// jitteryHandler takes a random while to handle each event, so that workers
+// sharing the events of an object would deliver them out of order.
+type jitteryHandler struct {
+	recordingHandler
+}
+
+func (h *jitteryHandler) Handle(e event.Event) error {
+	time.Sleep(time.Duration(rand.Intn(2000)) * time.Microsecond)
+	return h.recordingHandler.Handle(e)
+}
+
+// TestWorkersKeepTheOrderOfEachObject runs several workers and checks that the
+// events of every object still reach the handler in the order they happened.
+func TestWorkersKeepTheOrderOfEachObject(t *testing.T) {
+	client := k8sfake.NewSimpleClientset()
+	handler := &jitteryHandler{}
+	metrics := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "workers_test_events_total"}, []string{"resource", "type"})
+
+	controller := newResourceController(client, handler, secretInformer(client), "Secret", V1, metrics, nil, config.Workers{Kinds: map[string]int{"Secret": 4}}, nil)
+	if len(controller.queues) != 4 {
+		t.Fatalf("controller has %d workers, want 4", len(controller.queues))
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	go controller.informer.Run(stop)
+	go controller.Run(stop)
+
+	if !cache.WaitForCacheSync(stop, controller.HasSynced) {
+		t.Fatal("informer cache never synced")
+	}
+
+	const objects, updates = 8, 5
+	var secrets []*api_v1.Secret
+	for i := 0; i < objects; i++ {
+		secret, err := client.CoreV1().Secrets("default").Create(context.Background(), &api_v1.Secret{
+			ObjectMeta: meta_v1.ObjectMeta{
+				Name:              fmt.Sprintf("creds-%d", i),
+				Namespace:         "default",
+				CreationTimestamp: meta_v1.NewTime(time.Now().Add(time.Minute)),
+				Annotations:       map[string]string{"seq": "0"},
+			},
+		}, meta_v1.CreateOptions{})
+		if err != nil {
+			t.Fatalf("creating secret: %v", err)
+		}
+		secrets = append(secrets, secret)
+	}
+	for seq := 1; seq <= updates; seq++ {
+		for i, secret := range secrets {
+			secret = secret.DeepCopy()
+			secret.Annotations["seq"] = fmt.Sprint(seq)
+			updated, err := client.CoreV1().Secrets("default").Update(context.Background(), secret, meta_v1.UpdateOptions{})
+			if err != nil {
+				t.Fatalf("updating secret: %v", err)
+			}
+			secrets[i] = updated
+		}
+	}
+
+	seen := map[string][]string{}
+	for _, e := range handler.waitForEvents(t, objects*(1+updates)) {
+		accessor, err := meta.Accessor(e.Obj)
+		if err != nil {
+			t.Fatalf("%s event of %s: %v", e.Reason, e.Name, err)
+		}
+		if e.Reason == "Created" {
+			seen[e.Name] = append(seen[e.Name], "created")
+			continue
+		}
+		seen[e.Name] = append(seen[e.Name], accessor.GetAnnotations()["seq"])
+	}
+	want := []string{"created", "1", "2", "3", "4", "5"}
+	for name, got := range seen {
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("events of %s = %v, want %v", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/sirupsen/logrus"
+	api_v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/tools/cache"
+)
+
+// maxNamespaceInformers is how many namespaces get an informer each; more
+// namespaces are watched across the cluster and filtered by kubewatch
+const maxNamespaceInformers = 10
+
+// namespaceScope tells which namespaces kubewatch watches
+type namespaceScope struct {
+	// include lists the namespaces to watch, all of them when empty
+	include []string
+	// exclude lists glob patterns of namespaces not to watch
+	exclude []string
+	// selector, when set, restricts the watched namespaces to the ones
+	// whose labels match, as tracked by labelled
+	selector labels.Selector
+	labelled *namespaceSet
+}
+
+// newNamespaceScope returns the namespaces the configuration asks to watch.
+func newNamespaceScope(conf *config.Config) (namespaceScope, error) {
+	var scope namespaceScope
+	for _, pattern := range conf.ExcludeNamespaces {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return scope, fmt.Errorf("invalid excluded namespace pattern %q: %v", pattern, err)
+		}
+		scope.exclude = append(scope.exclude, pattern)
+	}
+
+	if conf.NamespaceSelector != "" {
+		selector, err := labels.Parse(conf.NamespaceSelector)
+		if err != nil {
+			return scope, fmt.Errorf("invalid namespace selector %q: %v", conf.NamespaceSelector, err)
+		}
+		scope.selector = selector
+		scope.labelled = &namespaceSet{names: map[string]bool{}}
+	}
+
+	names := conf.Namespaces
+	if conf.Namespace != "" {
+		names = append([]string{conf.Namespace}, names...)
+	}
+	seen := map[string]bool{}
+	for _, name := range names {
+		if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+			return scope, fmt.Errorf("invalid namespace %q: %s", name, strings.Join(errs, ", "))
+		}
+		if seen[name] || scope.excluded(name) {
+			continue
+		}
+		seen[name] = true
+		scope.include = append(scope.include, name)
+	}
+	if len(names) > 0 && len(scope.include) == 0 {
+		return scope, fmt.Errorf("every namespace to watch is excluded")
+	}
+	return scope, nil
+}
+
+// contains reports whether objects of namespace are watched.
+func (s namespaceScope) contains(namespace string) bool {
+	if s.labelled != nil && !s.labelled.has(namespace) {
+		return false
+	}
+	return s.named(namespace)
+}
+
+// named reports whether a namespace is in scope by its name: included and
+// not excluded, whatever its labels.
+func (s namespaceScope) named(namespace string) bool {
+	if s.excluded(namespace) {
+		return false
+	}
+	if len(s.include) == 0 {
+		return true
+	}
+	for _, name := range s.include {
+		if name == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+func (s namespaceScope) excluded(namespace string) bool {
+	for _, pattern := range s.exclude {
+		if ok, _ := path.Match(pattern, namespace); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// perNamespace reports whether the namespaces are few enough to get an
+// informer each, rather than sharing one watching the whole cluster.
+func (s namespaceScope) perNamespace() bool {
+	return len(s.include) > 0 && len(s.include) <= maxNamespaceInformers
+}
+
+// informerNamespaces returns the namespaces to open an informer in for a
+// namespaced resource; the empty namespace stands for the whole cluster.
+func (s namespaceScope) informerNamespaces() []string {
+	if s.perNamespace() {
+		return s.include
+	}
+	return []string{""}
+}
+
+// fieldSelector returns the field selector leaving out the excluded
+// namespaces an informer watching the whole cluster can leave to the API
+// server: the ones spelled out rather than matched by a pattern.
+func (s namespaceScope) fieldSelector() string {
+	if s.perNamespace() {
+		return ""
+	}
+	var selectors []fields.Selector
+	for _, pattern := range s.exclude {
+		if !strings.ContainsAny(pattern, `*?[\`) {
+			selectors = append(selectors, fields.OneTermNotEqualSelector("metadata.namespace", pattern))
+		}
+	}
+	return fields.AndSelectors(selectors...).String()
+}
+
+// filter returns the test the objects of the informers must pass, or nil
+// when the informers only get the objects of the watched namespaces.
+func (s namespaceScope) filter() func(namespace string) bool {
+	if s.labelled == nil && (s.perNamespace() || (len(s.include) == 0 && len(s.exclude) == 0)) {
+		return nil
+	}
+	return s.contains
+}
+
+// namespaceFilter returns the test the names of Namespace objects must pass,
+// or nil when they all do: the Namespace objects of the namespaces out of
+// scope are left out like the objects in them. Their labels are left to the
+// API server, through labelSelector.
+func (s namespaceScope) namespaceFilter() func(name string) bool {
+	if len(s.include) == 0 && len(s.exclude) == 0 {
+		return nil
+	}
+	return s.named
+}
+
+// labelSelector returns the namespace selector as the API server takes it
+func (s namespaceScope) labelSelector() string {
+	if s.selector == nil {
+		return ""
+	}
+	return s.selector.String()
+}
+
+// namespaceSet holds the namespaces whose labels match the namespace
+// selector, kept up to date by a namespace informer
+type namespaceSet struct {
+	mutex sync.RWMutex
+	names map[string]bool
+}
+
+func (s *namespaceSet) has(namespace string) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.names[namespace]
+}
+
+// set adds or removes a namespace, and reports whether that changed the set.
+func (s *namespaceSet) set(namespace string, in bool) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.names[namespace] == in {
+		return false
+	}
+	if in {
+		s.names[namespace] = true
+	} else {
+		delete(s.names, namespace)
+	}
+	return true
+}
+
+// track keeps the set of the scope up to date with the namespaces of
+// informer, which lists the namespaces matching the selector. The labels
+// are checked again, so that a namespace stops being watched as soon as its
+// labels no longer match. The set only filters the objects the informers
+// hand over: no informer is started or stopped as namespaces come and go.
+func (s namespaceScope) track(informer cache.SharedIndexInformer) {
+	update := func(obj interface{}) {
+		namespace, ok := obj.(*api_v1.Namespace)
+		if !ok {
+			return
+		}
+		in := s.selector.Matches(labels.Set(namespace.Labels))
+		if !s.labelled.set(namespace.Name, in) {
+			return
+		}
+		if in {
+			logrus.Infof("Watching namespace %s, its labels match %s", namespace.Name, s.selector)
+		} else {
+			logrus.Infof("No longer watching namespace %s, its labels do not match %s", namespace.Name, s.selector)
+		}
+	}
+
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    update,
+		UpdateFunc: func(old, new interface{}) { update(new) },
+		DeleteFunc: func(obj interface{}) {
+			key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+			if err == nil && s.labelled.set(key, false) {
+				logrus.Infof("No longer watching namespace %s, it is gone", key)
+			}
+		},
+	})
+}
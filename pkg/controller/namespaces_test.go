@@ -0,0 +1,403 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/prune"
+	"github.com/bitnami-labs/kubewatch/pkg/resources"
+	"github.com/prometheus/client_golang/prometheus"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	metadatafake "k8s.io/client-go/metadata/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestNamespaceScope(t *testing.T) {
+	many := make([]string, maxNamespaceInformers+1)
+	for i := range many {
+		many[i] = fmt.Sprintf("team-%d", i)
+	}
+
+	var Tests = []struct {
+		conf       config.Config
+		informers  []string
+		field      string
+		contains   []string
+		notContain []string
+	}{
+		{
+			conf:      config.Config{},
+			informers: []string{""},
+			contains:  []string{"default", "kube-system"},
+		},
+		{
+			conf:       config.Config{Namespace: "prod", Namespaces: []string{"staging", "prod"}},
+			informers:  []string{"prod", "staging"},
+			contains:   []string{"prod", "staging"},
+			notContain: []string{"default"},
+		},
+		{
+			conf:       config.Config{Namespaces: []string{"prod", "prod-sandbox"}, ExcludeNamespaces: []string{"*-sandbox"}},
+			informers:  []string{"prod"},
+			contains:   []string{"prod"},
+			notContain: []string{"prod-sandbox"},
+		},
+		{
+			conf:       config.Config{ExcludeNamespaces: []string{"kube-system", "*-sandbox"}},
+			informers:  []string{""},
+			field:      "metadata.namespace!=kube-system",
+			contains:   []string{"default"},
+			notContain: []string{"kube-system", "team-sandbox"},
+		},
+		{
+			conf:       config.Config{Namespaces: many},
+			informers:  []string{""},
+			contains:   many,
+			notContain: []string{"default"},
+		},
+	}
+
+	for i, tt := range Tests {
+		scope, err := newNamespaceScope(&tt.conf)
+		if err != nil {
+			t.Fatalf("%d: newNamespaceScope(): %v", i, err)
+		}
+		if got := scope.informerNamespaces(); !reflect.DeepEqual(got, tt.informers) {
+			t.Errorf("%d: informerNamespaces() = %q, want %q", i, got, tt.informers)
+		}
+		if got := scope.fieldSelector(); got != tt.field {
+			t.Errorf("%d: fieldSelector() = %q, want %q", i, got, tt.field)
+		}
+		for _, namespace := range tt.contains {
+			if !scope.contains(namespace) {
+				t.Errorf("%d: contains(%q) = false, want true", i, namespace)
+			}
+		}
+		for _, namespace := range tt.notContain {
+			if scope.contains(namespace) {
+				t.Errorf("%d: contains(%q) = true, want false", i, namespace)
+			}
+		}
+	}
+}
+
+func TestNamespaceScopeErrors(t *testing.T) {
+	for _, conf := range []config.Config{
+		{ExcludeNamespaces: []string{"[kube"}},
+		{Namespaces: []string{"Not_A_Namespace"}},
+		{Namespaces: []string{"kube-system"}, ExcludeNamespaces: []string{"kube-*"}},
+	} {
+		if _, err := newNamespaceScope(&conf); err == nil {
+			t.Errorf("newNamespaceScope(%+v) succeeded", conf)
+		}
+	}
+}
+
+func TestInformersPerNamespace(t *testing.T) {
+	scope, _ := newNamespaceScope(&config.Config{Namespaces: []string{"prod", "staging"}})
+	factories := newFactories(k8sfake.NewSimpleClientset(), dynamicfake.NewSimpleDynamicClient(runtime.NewScheme()), metadatafake.NewSimpleMetadataClient(metadatafake.NewTestScheme()), scope, nil)
+	registry := resources.NewRegistry()
+
+	var Tests = []struct {
+		resource  string
+		informers int
+	}{
+		{"pod", 2},
+		{"node", 1},
+	}
+
+	for _, tt := range Tests {
+		res, _ := registry.Lookup(tt.resource)
+		informers, inScope, err := factories.informers(watchedResource{Resource: res})
+		if err != nil {
+			t.Fatalf("informers(%s): %v", tt.resource, err)
+		}
+		if len(informers) != tt.informers || inScope != nil {
+			t.Errorf("informers(%s) = %d informers, filtered %t, want %d unfiltered", tt.resource, len(informers), inScope != nil, tt.informers)
+		}
+	}
+}
+
+func TestControllerSkipsNamespacesOutOfScope(t *testing.T) {
+	client := k8sfake.NewSimpleClientset()
+	handler := &recordingHandler{}
+	metrics := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_events_total"}, []string{"resource", "type"})
+	scope, _ := newNamespaceScope(&config.Config{ExcludeNamespaces: []string{"kube-*"}})
+
+	informer := cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
+				return client.CoreV1().Secrets("").List(context.Background(), options)
+			},
+			WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
+				return client.CoreV1().Secrets("").Watch(context.Background(), options)
+			},
+		},
+		&api_v1.Secret{},
+		0,
+		cache.Indexers{},
+	)
+	controller := newResourceController(client, handler, informer, "Secret", V1, metrics, nil, config.Workers{}, scope.filter())
+	stop := make(chan struct{})
+	defer close(stop)
+	go controller.informer.Run(stop)
+	go controller.Run(stop)
+	if !cache.WaitForCacheSync(stop, controller.HasSynced) {
+		t.Fatal("informer cache never synced")
+	}
+
+	for _, namespace := range []string{"kube-system", "default"} {
+		secret := &api_v1.Secret{ObjectMeta: meta_v1.ObjectMeta{
+			Name:              "creds",
+			Namespace:         namespace,
+			CreationTimestamp: meta_v1.NewTime(time.Now().Add(time.Minute)),
+		}}
+		if _, err := client.CoreV1().Secrets(namespace).Create(context.Background(), secret, meta_v1.CreateOptions{}); err != nil {
+			t.Fatalf("Create(): %v", err)
+		}
+	}
+
+	events := handler.waitForEvents(t, 1)
+	time.Sleep(100 * time.Millisecond)
+	if events = handler.recorded(); len(events) != 1 || events[0].Namespace != "default" {
+		t.Errorf("controller emitted %+v, want the event of the default namespace only", events)
+	}
+}
+
+func TestControllerKeepsNamespaceObjectsInScope(t *testing.T) {
+	var Tests = []struct {
+		conf config.Config
+		want []string
+	}{
+		{config.Config{ExcludeNamespaces: []string{"kube-*"}}, []string{"default", "team-a"}},
+		{config.Config{Namespaces: []string{"default"}}, []string{"default"}},
+		{config.Config{Namespaces: []string{"default", "team-a"}, ExcludeNamespaces: []string{"team-*"}}, []string{"default"}},
+	}
+
+	for _, tt := range Tests {
+		client := k8sfake.NewSimpleClientset()
+		handler := &recordingHandler{}
+		metrics := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_events_total"}, []string{"resource", "type"})
+		scope, err := newNamespaceScope(&tt.conf)
+		if err != nil {
+			t.Fatalf("newNamespaceScope(): %v", err)
+		}
+		factories := newFactories(client, dynamicfake.NewSimpleDynamicClient(runtime.NewScheme()), metadatafake.NewSimpleMetadataClient(metadatafake.NewTestScheme()), scope, nil)
+		namespaces, _ := resources.NewRegistry().Lookup("namespace")
+
+		informers, inScope, err := factories.informers(watchedResource{Resource: namespaces})
+		if err != nil {
+			t.Fatalf("informers(): %v", err)
+		}
+		controller := newResourceController(client, handler, informers[0], namespaces.Kind, namespaces.APIVersion(), metrics, nil, config.Workers{}, inScope)
+		stop := make(chan struct{})
+		factories.start(stop)
+		go controller.Run(stop)
+		if !cache.WaitForCacheSync(stop, controller.HasSynced) {
+			t.Fatal("informer cache never synced")
+		}
+
+		for _, name := range []string{"kube-system", "default", "team-a"} {
+			namespace := &api_v1.Namespace{ObjectMeta: meta_v1.ObjectMeta{
+				Name:              name,
+				CreationTimestamp: meta_v1.NewTime(time.Now().Add(time.Minute)),
+			}}
+			if _, err := client.CoreV1().Namespaces().Create(context.Background(), namespace, meta_v1.CreateOptions{}); err != nil {
+				t.Fatalf("Create(): %v", err)
+			}
+		}
+
+		handler.waitForEvents(t, len(tt.want))
+		time.Sleep(100 * time.Millisecond)
+		var got []string
+		for _, e := range handler.recorded() {
+			got = append(got, e.Name)
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("with namespaces %v excluding %v, controller emitted the events of %v, want %v", tt.conf.Namespaces, tt.conf.ExcludeNamespaces, got, tt.want)
+		}
+		close(stop)
+	}
+}
+
+func TestNamespaceObjectsAreListedWithTheNamespaceSelector(t *testing.T) {
+	client := k8sfake.NewSimpleClientset()
+	scope, err := newNamespaceScope(&config.Config{NamespaceSelector: "team=payments"})
+	if err != nil {
+		t.Fatalf("newNamespaceScope(): %v", err)
+	}
+	factories := newFactories(client, dynamicfake.NewSimpleDynamicClient(runtime.NewScheme()), metadatafake.NewSimpleMetadataClient(metadatafake.NewTestScheme()), scope, nil)
+	namespaces, _ := resources.NewRegistry().Lookup("namespace")
+	settings := config.Resource{Name: "namespace", LabelSelector: "env=prod"}
+
+	informers, _, err := factories.informers(watchedResource{Resource: namespaces, settings: settings})
+	if err != nil {
+		t.Fatalf("informers(): %v", err)
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	factories.start(stop)
+	if !cache.WaitForCacheSync(stop, informers[0].HasSynced) {
+		t.Fatal("informer cache never synced")
+	}
+
+	for _, action := range client.Actions() {
+		if list, ok := action.(k8stesting.ListAction); ok {
+			if selector := list.GetListRestrictions().Labels.String(); selector != "env=prod,team=payments" {
+				t.Errorf("List() label selector = %q, want the resource and namespace selectors", selector)
+			}
+			return
+		}
+	}
+	t.Error("the informer did not list namespaces")
+}
+
+func TestClusterScopedCustomResourcesIgnoreNamespaces(t *testing.T) {
+	gvr := schema.GroupVersionResource{Group: "cert-manager.io", Version: "v1", Resource: "clusterissuers"}
+	discovery := k8sfake.NewSimpleClientset().Discovery().(*fakediscovery.FakeDiscovery)
+	discovery.Resources = []*meta_v1.APIResourceList{{
+		GroupVersion: "cert-manager.io/v1",
+		APIResources: []meta_v1.APIResource{{Name: "clusterissuers", Kind: "ClusterIssuer"}},
+	}}
+	conf := &config.Config{
+		Namespaces:      []string{"team-a", "team-b"},
+		CustomResources: []config.CRD{{Group: gvr.Group, Version: gvr.Version, Resource: gvr.Resource}},
+	}
+	watched, _, err := watchedResources(conf, resources.NewResolver(resources.NewRegistry(), discovery))
+	if err != nil || len(watched) != 1 {
+		t.Fatalf("watchedResources() = %v, %v, want the ClusterIssuer resource", watched, err)
+	}
+
+	client := k8sfake.NewSimpleClientset()
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{gvr: "ClusterIssuerList"})
+	scope, _ := newNamespaceScope(conf)
+	factories := newFactories(client, dynamicClient, metadatafake.NewSimpleMetadataClient(metadatafake.NewTestScheme()), scope, nil)
+	informers, inScope, err := factories.informers(watched[0])
+	if err != nil {
+		t.Fatalf("informers(): %v", err)
+	}
+	if len(informers) != 1 || inScope != nil {
+		t.Fatalf("informers() = %d informers, filtered %t, want one unfiltered informer for the whole cluster", len(informers), inScope != nil)
+	}
+
+	handler := &recordingHandler{}
+	metrics := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_events_total"}, []string{"resource", "type"})
+	controller := newResourceController(client, handler, informers[0], watched[0].Kind, watched[0].APIVersion(), metrics, nil, config.Workers{}, inScope)
+	stop := make(chan struct{})
+	defer close(stop)
+	factories.start(stop)
+	go controller.Run(stop)
+	if !cache.WaitForCacheSync(stop, controller.HasSynced) {
+		t.Fatal("informer cache never synced")
+	}
+
+	issuer := &unstructured.Unstructured{}
+	issuer.SetAPIVersion("cert-manager.io/v1")
+	issuer.SetKind("ClusterIssuer")
+	issuer.SetName("letsencrypt")
+	issuer.SetCreationTimestamp(meta_v1.NewTime(time.Now().Add(time.Minute)))
+	if _, err := dynamicClient.Resource(gvr).Create(context.Background(), issuer, meta_v1.CreateOptions{}); err != nil {
+		t.Fatalf("Create(): %v", err)
+	}
+
+	events := handler.waitForEvents(t, 1)
+	if e := events[0]; e.Kind != "ClusterIssuer" || e.Name != "letsencrypt" || e.Reason != "Created" {
+		t.Errorf("controller emitted %+v, want the creation of ClusterIssuer letsencrypt", e)
+	}
+}
+
+func TestNamespaceSelectorFollowsLabels(t *testing.T) {
+	client := k8sfake.NewSimpleClientset(
+		&api_v1.Namespace{ObjectMeta: meta_v1.ObjectMeta{Name: "foo-1", Labels: map[string]string{"team": "foo"}}},
+		&api_v1.Namespace{ObjectMeta: meta_v1.ObjectMeta{Name: "bar-1", Labels: map[string]string{"team": "bar"}}},
+	)
+	scope, err := newNamespaceScope(&config.Config{NamespaceSelector: "team=foo"})
+	if err != nil {
+		t.Fatalf("newNamespaceScope(): %v", err)
+	}
+	if scope.informerNamespaces()[0] != "" || scope.filter() == nil {
+		t.Fatalf("a namespace selector must watch the whole cluster and filter it")
+	}
+
+	// Rules pruning the labels of every kind leave the tracked namespaces
+	// alone.
+	rules, err := prune.NewRules([]config.PrunePathRule{{Paths: []string{"metadata.labels"}}})
+	if err != nil {
+		t.Fatalf("NewRules(): %v", err)
+	}
+	factories := newFactories(client, dynamicfake.NewSimpleDynamicClient(runtime.NewScheme()), metadatafake.NewSimpleMetadataClient(metadatafake.NewTestScheme()), scope, rules)
+	informer, err := factories.namespaceInformer(scope.selector)
+	if err != nil {
+		t.Fatalf("namespaceInformer(): %v", err)
+	}
+	scope.track(informer)
+	stop := make(chan struct{})
+	defer close(stop)
+	factories.start(stop)
+	if !cache.WaitForCacheSync(stop, informer.HasSynced) {
+		t.Fatal("informer cache never synced")
+	}
+
+	eventually := func(namespace string, want bool) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for scope.contains(namespace) != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("contains(%q) = %t, want %t", namespace, !want, want)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	eventually("foo-1", true)
+	eventually("bar-1", false)
+
+	// A new tenant namespace gets watched, and stops being watched when
+	// relabelled.
+	ctx := context.Background()
+	created, err := client.CoreV1().Namespaces().Create(ctx, &api_v1.Namespace{ObjectMeta: meta_v1.ObjectMeta{Name: "foo-2", Labels: map[string]string{"team": "foo"}}}, meta_v1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("Create(): %v", err)
+	}
+	eventually("foo-2", true)
+	created.Labels["team"] = "bar"
+	if _, err := client.CoreV1().Namespaces().Update(ctx, created, meta_v1.UpdateOptions{}); err != nil {
+		t.Fatalf("Update(): %v", err)
+	}
+	eventually("foo-2", false)
+
+	if err := client.CoreV1().Namespaces().Delete(ctx, "foo-1", meta_v1.DeleteOptions{}); err != nil {
+		t.Fatalf("Delete(): %v", err)
+	}
+	eventually("foo-1", false)
+}
@@ -0,0 +1,368 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/prune"
+	"github.com/bitnami-labs/kubewatch/pkg/resources"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/meta"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/metadata/metadatainformer"
+	"k8s.io/client-go/tools/cache"
+)
+
+// rediscoverInterval is how often the resources the cluster did not serve
+// are looked up again
+const rediscoverInterval = time.Minute
+
+// watchedResource is a resource to watch along with its settings
+type watchedResource struct {
+	resources.Resource
+	settings config.Resource
+}
+
+// watchKey tells watched resources apart: the same resource can be watched
+// with different selectors, and in full or metadata only
+type watchKey struct {
+	gvr          schema.GroupVersionResource
+	selectors    selectors
+	metadataOnly bool
+}
+
+func (w watchedResource) key() watchKey {
+	return watchKey{gvr: w.GVR, selectors: w.selectors(), metadataOnly: w.settings.MetadataOnly}
+}
+
+func (w watchedResource) selectors() selectors {
+	return selectors{label: w.settings.LabelSelector, field: w.settings.FieldSelector}
+}
+
+// pendingResource is a resource the cluster does not serve yet: a named one,
+// or a custom resource, which is looked up by its group, version and
+// resource rather than by name
+type pendingResource struct {
+	settings config.Resource
+	gvr      *schema.GroupVersionResource
+}
+
+func (p pendingResource) resolve(resolver *resources.Resolver) (resources.Resource, error) {
+	if p.gvr != nil {
+		res, err := resolver.ResolveGVR(*p.gvr)
+		if err != nil {
+			return resources.Resource{}, fmt.Errorf("custom resource %s: %w", p.settings.Name, err)
+		}
+		return res, nil
+	}
+	return resolver.Resolve(p.settings.Name)
+}
+
+// watchedResources returns the resources the configuration asks to watch,
+// each one once per settings: the named ones, then the custom resources,
+// with the kind and scope the cluster serves them with. It also returns the
+// ones the cluster does not serve yet, to be resolved again later.
+func watchedResources(conf *config.Config, resolver *resources.Resolver) ([]watchedResource, []pendingResource, error) {
+	var candidates []pendingResource
+	for _, r := range conf.WatchedResources() {
+		if err := checkSettings(r); err != nil {
+			return nil, nil, err
+		}
+		candidates = append(candidates, pendingResource{settings: r})
+	}
+	for _, crd := range conf.CustomResources {
+		gvr := schema.GroupVersionResource{Group: crd.Group, Version: crd.Version, Resource: crd.Resource}
+		candidates = append(candidates, pendingResource{settings: config.Resource{Name: gvr.GroupResource().String()}, gvr: &gvr})
+	}
+
+	var watched []watchedResource
+	var pending []pendingResource
+	seen := map[watchKey]bool{}
+	for _, p := range candidates {
+		res, err := p.resolve(resolver)
+		if meta.IsNoMatchError(err) {
+			logrus.Errorf("%v; watching it once the cluster serves it", err)
+			pending = append(pending, p)
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		w := watchedResource{Resource: res, settings: p.settings}
+		if !seen[w.key()] {
+			seen[w.key()] = true
+			watched = append(watched, w)
+		}
+	}
+	return watched, pending, nil
+}
+
+// checkSettings makes sure the API server will accept the selectors of a
+// resource.
+func checkSettings(r config.Resource) error {
+	if _, err := labels.Parse(r.LabelSelector); err != nil {
+		return fmt.Errorf("resource %s: invalid label selector %q: %v", r.Name, r.LabelSelector, err)
+	}
+	if _, err := fields.ParseSelector(r.FieldSelector); err != nil {
+		return fmt.Errorf("resource %s: invalid field selector %q: %v", r.Name, r.FieldSelector, err)
+	}
+	return nil
+}
+
+// checkWorkers makes sure every kind given workers is the kind of a watched
+// resource. Kinds that may belong to the resources waiting for the cluster to
+// serve them are only warned about.
+func checkWorkers(workers config.Workers, watched []watchedResource, pending []pendingResource) error {
+	for key := range workers.Kinds {
+		known := false
+		for _, w := range watched {
+			if config.KindKeyMatches(key, w.Kind, w.GVR.Group) {
+				known = true
+				break
+			}
+		}
+		switch {
+		case known:
+		case len(pending) > 0:
+			logrus.Warnf("workers are set for kind %s, which no watched resource has yet", key)
+		default:
+			return fmt.Errorf("workers are set for kind %s, which no watched resource has", key)
+		}
+	}
+	return nil
+}
+
+// resolvePending looks up the given resources again every interval, until
+// the cluster serves them all, e.g. once their CRDs are installed, and hands
+// the ones it finds to watch.
+func resolvePending(resolver *resources.Resolver, pending []pendingResource, interval time.Duration, stopCh <-chan struct{}, watch func(watchedResource)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for len(pending) > 0 {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+		}
+
+		resolver.Reset()
+		var still []pendingResource
+		for _, p := range pending {
+			res, err := p.resolve(resolver)
+			switch {
+			case meta.IsNoMatchError(err):
+				still = append(still, p)
+			case err != nil:
+				logrus.Errorf("Cannot watch %s: %v", p.settings.Name, err)
+			default:
+				logrus.Infof("The cluster now serves %s, watching it", p.settings.Name)
+				watch(watchedResource{Resource: res, settings: p.settings})
+			}
+		}
+		pending = still
+	}
+}
+
+// selectors are the selectors a resource is watched with
+type selectors struct {
+	label string
+	field string
+}
+
+// listOptions are what an informer passes to the API server
+type listOptions struct {
+	namespace string
+	selectors
+}
+
+// factories hand out shared informers, from a set of factories per set of
+// list options, so that the resources watched with the same options share
+// the clients and the factories while the options reach the API server.
+// The informers remove the fields pruned by the rules before caching the
+// objects.
+type factories struct {
+	kubeClient     kubernetes.Interface
+	dynamicClient  dynamic.Interface
+	metadataClient metadata.Interface
+	scope          namespaceScope
+	prune          *prune.Rules
+
+	typed    map[listOptions]informers.SharedInformerFactory
+	dynamic  map[listOptions]dynamicinformer.DynamicSharedInformerFactory
+	metadata map[listOptions]metadatainformer.SharedInformerFactory
+	// transformed holds the informers whose transform was set
+	transformed map[cache.SharedIndexInformer]bool
+}
+
+func newFactories(kubeClient kubernetes.Interface, dynamicClient dynamic.Interface, metadataClient metadata.Interface, scope namespaceScope, rules *prune.Rules) *factories {
+	return &factories{
+		kubeClient:     kubeClient,
+		dynamicClient:  dynamicClient,
+		metadataClient: metadataClient,
+		scope:          scope,
+		prune:          rules,
+		typed:          map[listOptions]informers.SharedInformerFactory{},
+		dynamic:        map[listOptions]dynamicinformer.DynamicSharedInformerFactory{},
+		metadata:       map[listOptions]metadatainformer.SharedInformerFactory{},
+		transformed:    map[cache.SharedIndexInformer]bool{},
+	}
+}
+
+// informers returns the shared informers of a resource within the watched
+// namespaces: one per namespace, or one for the whole cluster, along with the
+// test the namespaces of its objects must pass, which is nil when they all
+// do. Namespace objects are tested by their own name, and listed with the
+// namespace selector.
+func (f *factories) informers(w watchedResource) ([]cache.SharedIndexInformer, func(namespace string) bool, error) {
+	if w.ClusterScoped {
+		options := listOptions{selectors: w.selectors()}
+		var inScope func(namespace string) bool
+		if w.GVR == namespacesGVR {
+			options.label = joinSelectors(options.label, f.scope.labelSelector())
+			inScope = f.scope.namespaceFilter()
+		}
+		informer, err := f.informer(w, options)
+		if err != nil {
+			return nil, nil, err
+		}
+		return []cache.SharedIndexInformer{informer}, inScope, nil
+	}
+
+	var informers []cache.SharedIndexInformer
+	for _, namespace := range f.scope.informerNamespaces() {
+		options := listOptions{namespace: namespace, selectors: w.selectors()}
+		options.field = joinSelectors(options.field, f.scope.fieldSelector())
+		informer, err := f.informer(w, options)
+		if err != nil {
+			return nil, nil, err
+		}
+		informers = append(informers, informer)
+	}
+	return informers, f.scope.filter(), nil
+}
+
+// informer returns the shared informer of a resource, pruning the objects
+// of its kind.
+func (f *factories) informer(w watchedResource, options listOptions) (cache.SharedIndexInformer, error) {
+	informer, err := f.sharedInformer(w, options)
+	if err != nil {
+		return nil, err
+	}
+	// Informers handed out again may have started, and a started informer
+	// cannot be given a transform.
+	if !f.transformed[informer] {
+		f.transformed[informer] = true
+		if transform := f.prune.Transform(w.Kind); transform != nil {
+			if err := informer.SetTransform(transform); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return informer, nil
+}
+
+// sharedInformer returns the shared informer of a resource: from a metadata
+// factory when only its metadata is watched, from a typed factory for the
+// resources of Kubernetes itself and from a dynamic one for the others.
+func (f *factories) sharedInformer(w watchedResource, options listOptions) (cache.SharedIndexInformer, error) {
+	tweak := func(list *meta_v1.ListOptions) {
+		list.LabelSelector = options.label
+		list.FieldSelector = options.field
+	}
+
+	if w.settings.MetadataOnly {
+		factory, ok := f.metadata[options]
+		if !ok {
+			factory = metadatainformer.NewFilteredSharedInformerFactory(f.metadataClient, 0, options.namespace, tweak)
+			f.metadata[options] = factory
+		}
+		return factory.ForResource(w.GVR).Informer(), nil
+	}
+
+	if w.Dynamic {
+		factory, ok := f.dynamic[options]
+		if !ok {
+			factory = dynamicinformer.NewFilteredDynamicSharedInformerFactory(f.dynamicClient, 0, options.namespace, tweak)
+			f.dynamic[options] = factory
+		}
+		return factory.ForResource(w.GVR).Informer(), nil
+	}
+
+	factory, ok := f.typed[options]
+	if !ok {
+		factory = informers.NewSharedInformerFactoryWithOptions(f.kubeClient, 0, informers.WithNamespace(options.namespace), informers.WithTweakListOptions(tweak))
+		f.typed[options] = factory
+	}
+	informer, err := factory.ForResource(w.GVR)
+	if err != nil {
+		return nil, err
+	}
+	return informer.Informer(), nil
+}
+
+// namespaceInformer returns the informer of the namespaces whose labels
+// match selector. Its objects are never pruned, since the labels they are
+// tracked by must be kept whatever the rules, which also holds for the
+// namespaces watched without a label selector of their own and so sharing it.
+func (f *factories) namespaceInformer(selector labels.Selector) (cache.SharedIndexInformer, error) {
+	namespaces := resources.Resource{GVR: namespacesGVR, Kind: "Namespace", ClusterScoped: true}
+	informer, err := f.sharedInformer(watchedResource{Resource: namespaces}, listOptions{selectors: selectors{label: selector.String()}})
+	if err != nil {
+		return nil, err
+	}
+	f.transformed[informer] = true
+	return informer, nil
+}
+
+var namespacesGVR = schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}
+
+// joinSelectors returns the selector requiring every one of selectors
+func joinSelectors(selectors ...string) string {
+	var terms []string
+	for _, selector := range selectors {
+		if selector != "" {
+			terms = append(terms, selector)
+		}
+	}
+	return strings.Join(terms, ",")
+}
+
+// start starts the informers handed out since the last call.
+func (f *factories) start(stopCh <-chan struct{}) {
+	for _, factory := range f.typed {
+		factory.Start(stopCh)
+	}
+	for _, factory := range f.dynamic {
+		factory.Start(stopCh)
+	}
+	for _, factory := range f.metadata {
+		factory.Start(stopCh)
+	}
+}
@@ -0,0 +1,330 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/prune"
+	"github.com/bitnami-labs/kubewatch/pkg/resources"
+	"github.com/prometheus/client_golang/prometheus"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	metadatafake "k8s.io/client-go/metadata/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestWatchedResourcesWaitForMissingOnes(t *testing.T) {
+	client := k8sfake.NewSimpleClientset().Discovery().(*fakediscovery.FakeDiscovery)
+	client.Resources = []*meta_v1.APIResourceList{
+		{
+			GroupVersion: "coordination.k8s.io/v1",
+			APIResources: []meta_v1.APIResource{{Name: "leases", Kind: "Lease", Namespaced: true}},
+		},
+		{
+			GroupVersion: "monitoring.coreos.com/v1",
+			APIResources: []meta_v1.APIResource{{Name: "prometheusrules", Kind: "PrometheusRule", Namespaced: true}},
+		},
+	}
+	conf := &config.Config{
+		Resources: []config.Resource{{Name: "pod"}, {Name: "leases"}, {Name: "Widget"}},
+		CustomResources: []config.CRD{
+			{Group: "monitoring.coreos.com", Version: "v1", Resource: "prometheusrules"},
+			{Group: "cert-manager.io", Version: "v1", Resource: "clusterissuers"},
+		},
+	}
+
+	resolver := resources.NewResolver(resources.NewRegistry(), client)
+	watched, pending, err := watchedResources(conf, resolver)
+	if err != nil {
+		t.Fatalf("watchedResources(): %v", err)
+	}
+	var kinds []string
+	for _, res := range watched {
+		kinds = append(kinds, res.Kind)
+	}
+	if got := strings.Join(kinds, ","); got != "Pod,Lease,PrometheusRule" {
+		t.Errorf("watchedResources() kinds = %s, want Pod,Lease,PrometheusRule", got)
+	}
+	if len(pending) != 2 || pending[0].settings.Name != "Widget" || pending[1].settings.Name != "clusterissuers.cert-manager.io" {
+		t.Fatalf("watchedResources() pending = %v, want Widget and clusterissuers", pending)
+	}
+
+	// The CRDs get installed; custom resources are found in the version
+	// they are configured with, and with their scope.
+	client.Resources = append(client.Resources,
+		&meta_v1.APIResourceList{
+			GroupVersion: "example.com/v1",
+			APIResources: []meta_v1.APIResource{{Name: "widgets", Kind: "Widget", Namespaced: true}},
+		},
+		&meta_v1.APIResourceList{
+			GroupVersion: "cert-manager.io/v1",
+			APIResources: []meta_v1.APIResource{{Name: "clusterissuers", Kind: "ClusterIssuer"}},
+		},
+	)
+	found := make(chan watchedResource, 2)
+	stop := make(chan struct{})
+	defer close(stop)
+	go resolvePending(resolver, pending, 10*time.Millisecond, stop, func(w watchedResource) { found <- w })
+
+	for _, want := range []struct {
+		kind, apiVersion string
+		clusterScoped    bool
+	}{{"Widget", "example.com/v1", false}, {"ClusterIssuer", "cert-manager.io/v1", true}} {
+		select {
+		case res := <-found:
+			if res.Kind != want.kind || res.APIVersion() != want.apiVersion || res.ClusterScoped != want.clusterScoped {
+				t.Errorf("resolvePending() found %+v, want the %s resource", res, want.kind)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("resolvePending() did not find the installed %s resource", want.kind)
+		}
+	}
+}
+
+func TestCustomResourcesHaveTheScopeTheClusterServes(t *testing.T) {
+	client := k8sfake.NewSimpleClientset().Discovery().(*fakediscovery.FakeDiscovery)
+	client.Resources = []*meta_v1.APIResourceList{{
+		GroupVersion: "cert-manager.io/v1",
+		APIResources: []meta_v1.APIResource{
+			{Name: "clusterissuers", Kind: "ClusterIssuer"},
+			{Name: "issuers", Kind: "Issuer", Namespaced: true},
+		},
+	}}
+	conf := &config.Config{CustomResources: []config.CRD{
+		{Group: "cert-manager.io", Version: "v1", Resource: "clusterissuers"},
+		{Group: "cert-manager.io", Version: "v1", Resource: "issuers"},
+	}}
+
+	watched, _, err := watchedResources(conf, resources.NewResolver(resources.NewRegistry(), client))
+	if err != nil {
+		t.Fatalf("watchedResources(): %v", err)
+	}
+	if len(watched) != 2 || watched[0].Kind != "ClusterIssuer" || !watched[0].ClusterScoped || watched[1].Kind != "Issuer" || watched[1].ClusterScoped {
+		t.Errorf("watchedResources() = %+v, want the cluster-scoped ClusterIssuer and the namespaced Issuer", watched)
+	}
+}
+
+func TestWatchedResourcesAmbiguous(t *testing.T) {
+	client := k8sfake.NewSimpleClientset().Discovery().(*fakediscovery.FakeDiscovery)
+	for _, group := range []string{"cert-manager.io/v1", "acme.example.com/v1"} {
+		client.Resources = append(client.Resources, &meta_v1.APIResourceList{
+			GroupVersion: group,
+			APIResources: []meta_v1.APIResource{{Name: "certificates", Kind: "Certificate", Namespaced: true}},
+		})
+	}
+	conf := &config.Config{Resources: []config.Resource{{Name: "Certificate"}}}
+
+	_, _, err := watchedResources(conf, resources.NewResolver(resources.NewRegistry(), client))
+	if err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		t.Errorf("watchedResources() error = %v, want an ambiguity error", err)
+	}
+}
+
+func TestBuiltinResourcesHaveInformers(t *testing.T) {
+	factories := newFactories(k8sfake.NewSimpleClientset(), dynamicfake.NewSimpleDynamicClient(runtime.NewScheme()), metadatafake.NewSimpleMetadataClient(metadatafake.NewTestScheme()), namespaceScope{}, nil)
+
+	for _, res := range resources.Builtin {
+		if _, _, err := factories.informers(watchedResource{Resource: res}); err != nil {
+			t.Errorf("informers(%s): %v", res.Name, err)
+		}
+	}
+}
+
+func TestSelectorsReachTheAPIServer(t *testing.T) {
+	client := k8sfake.NewSimpleClientset()
+	factories := newFactories(client, dynamicfake.NewSimpleDynamicClient(runtime.NewScheme()), metadatafake.NewSimpleMetadataClient(metadatafake.NewTestScheme()), namespaceScope{}, nil)
+	pods, _ := resources.NewRegistry().Lookup("pod")
+	settings := config.Resource{Name: "pod", LabelSelector: "app.kubernetes.io/part-of=payments", FieldSelector: "status.phase!=Succeeded"}
+
+	filtered, _, err := factories.informers(watchedResource{Resource: pods, settings: settings})
+	if err != nil {
+		t.Fatalf("informers(): %v", err)
+	}
+	unfiltered, _, _ := factories.informers(watchedResource{Resource: pods})
+	informer := filtered[0]
+	if informer == unfiltered[0] {
+		t.Fatalf("informers() shared the informer of unfiltered pods")
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go informer.Run(stop)
+	if !cache.WaitForCacheSync(stop, informer.HasSynced) {
+		t.Fatalf("the informer did not sync")
+	}
+
+	for _, action := range client.Actions() {
+		list, ok := action.(k8stesting.ListAction)
+		if !ok {
+			continue
+		}
+		restrictions := list.GetListRestrictions()
+		if restrictions.Labels.String() != settings.LabelSelector || restrictions.Fields.String() != settings.FieldSelector {
+			t.Errorf("List() selectors = %q, %q, want %q, %q", restrictions.Labels, restrictions.Fields, settings.LabelSelector, settings.FieldSelector)
+		}
+		return
+	}
+	t.Errorf("the informer did not list pods")
+}
+
+func TestWatchedResourcesInvalidSelector(t *testing.T) {
+	conf := &config.Config{Resources: []config.Resource{{Name: "pod", FieldSelector: "status.phase"}}}
+	_, _, err := watchedResources(conf, resources.NewResolver(resources.NewRegistry(), k8sfake.NewSimpleClientset().Discovery()))
+	if err == nil || !strings.Contains(err.Error(), "invalid field selector") {
+		t.Errorf("watchedResources() error = %v, want an invalid field selector error", err)
+	}
+}
+
+func TestMetadataOnlyResources(t *testing.T) {
+	client := k8sfake.NewSimpleClientset()
+	metadataClient := metadatafake.NewSimpleMetadataClient(metadatafake.NewTestScheme())
+	factories := newFactories(client, dynamicfake.NewSimpleDynamicClient(runtime.NewScheme()), metadataClient, namespaceScope{}, nil)
+	secrets, _ := resources.NewRegistry().Lookup("secret")
+
+	informers, _, err := factories.informers(watchedResource{Resource: secrets, settings: config.Resource{Name: "secret", MetadataOnly: true}})
+	if err != nil {
+		t.Fatalf("informers(): %v", err)
+	}
+	handler := &recordingHandler{}
+	metrics := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_events_total"}, []string{"resource", "type"})
+	controller := newResourceController(client, handler, informers[0], secrets.Kind, secrets.APIVersion(), metrics, nil, config.Workers{}, nil)
+	stop := make(chan struct{})
+	defer close(stop)
+	factories.start(stop)
+	go controller.Run(stop)
+	if !cache.WaitForCacheSync(stop, controller.HasSynced) {
+		t.Fatal("informer cache never synced")
+	}
+
+	secret := &meta_v1.PartialObjectMetadata{
+		TypeMeta: meta_v1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:              "creds",
+			Namespace:         "default",
+			CreationTimestamp: meta_v1.NewTime(time.Now().Add(time.Minute)),
+		},
+	}
+	if err := metadataClient.Tracker().Create(secrets.GVR, secret, "default"); err != nil {
+		t.Fatalf("Create(): %v", err)
+	}
+
+	events := handler.waitForEvents(t, 1)
+	if e := events[0]; e.Kind != "Secret" || e.Namespace != "default" || e.Name != "creds" || e.Reason != "Created" {
+		t.Errorf("controller emitted %+v, want the creation of Secret default/creds", e)
+	}
+	if _, ok := events[0].Obj.(*meta_v1.PartialObjectMetadata); !ok {
+		t.Errorf("event object is a %T, want the metadata only", events[0].Obj)
+	}
+	for _, action := range client.Actions() {
+		t.Errorf("the typed client was used: %v", action)
+	}
+}
+
+func TestPrunedFieldsNeverReachHandlers(t *testing.T) {
+	client := k8sfake.NewSimpleClientset()
+	rules, err := prune.NewRules([]config.PrunePathRule{
+		{Paths: []string{"metadata.managedFields"}},
+		{Kinds: []string{"ConfigMap"}, Paths: []string{"data"}},
+	})
+	if err != nil {
+		t.Fatalf("NewRules(): %v", err)
+	}
+	factories := newFactories(client, dynamicfake.NewSimpleDynamicClient(runtime.NewScheme()), metadatafake.NewSimpleMetadataClient(metadatafake.NewTestScheme()), namespaceScope{}, rules)
+	configMaps, _ := resources.NewRegistry().Lookup("configmap")
+
+	informers, _, err := factories.informers(watchedResource{Resource: configMaps})
+	if err != nil {
+		t.Fatalf("informers(): %v", err)
+	}
+	// The informer is handed out again once started, as when a resource
+	// found later shares it.
+	if _, _, err := factories.informers(watchedResource{Resource: configMaps}); err != nil {
+		t.Fatalf("informers() again: %v", err)
+	}
+	handler := &recordingHandler{}
+	metrics := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_events_total"}, []string{"resource", "type"})
+	controller := newResourceController(client, handler, informers[0], configMaps.Kind, configMaps.APIVersion(), metrics, nil, config.Workers{}, nil)
+	stop := make(chan struct{})
+	defer close(stop)
+	factories.start(stop)
+	go controller.Run(stop)
+	if !cache.WaitForCacheSync(stop, controller.HasSynced) {
+		t.Fatal("informer cache never synced")
+	}
+
+	cm := &api_v1.ConfigMap{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:              "settings",
+			Namespace:         "default",
+			CreationTimestamp: meta_v1.NewTime(time.Now().Add(time.Minute)),
+			ManagedFields:     []meta_v1.ManagedFieldsEntry{{Manager: "kubectl"}},
+		},
+		Data: map[string]string{"config.yaml": "large"},
+	}
+	if _, err := client.CoreV1().ConfigMaps("default").Create(context.Background(), cm, meta_v1.CreateOptions{}); err != nil {
+		t.Fatalf("Create(): %v", err)
+	}
+
+	events := handler.waitForEvents(t, 1)
+	obj, ok := events[0].Obj.(*api_v1.ConfigMap)
+	if !ok {
+		t.Fatalf("event object is a %T, want a *v1.ConfigMap", events[0].Obj)
+	}
+	if obj.Name != "settings" || obj.Data != nil || obj.ManagedFields != nil {
+		t.Errorf("event object = %+v, want settings without data nor managed fields", obj)
+	}
+	cached, _, _ := informers[0].GetStore().GetByKey("default/settings")
+	if cached, ok := cached.(*api_v1.ConfigMap); !ok || cached.Data != nil {
+		t.Errorf("cached object = %+v, want settings without data", cached)
+	}
+}
+
+func TestCheckWorkers(t *testing.T) {
+	registry := resources.NewRegistry()
+	pods, _ := registry.Lookup("pod")
+	events, _ := registry.Lookup("event")
+	watched := []watchedResource{{Resource: pods}, {Resource: events}}
+
+	var Tests = []struct {
+		kinds   map[string]int
+		pending []pendingResource
+		ok      bool
+	}{
+		{map[string]int{"pod": 4, "Event.events.k8s.io": 2}, nil, true},
+		{map[string]int{"Event": 2}, nil, true},
+		{map[string]int{"Deployment": 2}, nil, false},
+		{map[string]int{"Event.example.com": 2}, nil, false},
+		{map[string]int{"Widget": 2}, []pendingResource{{settings: config.Resource{Name: "widgets.example.com"}}}, true},
+	}
+
+	for _, tt := range Tests {
+		err := checkWorkers(config.Workers{Kinds: tt.kinds}, watched, tt.pending)
+		if (err == nil) != tt.ok {
+			t.Errorf("checkWorkers(%v) = %v, want ok %v", tt.kinds, err, tt.ok)
+		}
+	}
+}
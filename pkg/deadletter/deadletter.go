@@ -0,0 +1,147 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package deadletter stores the messages handlers gave up on in a file of
+// JSON lines, so they can be inspected and sent again later.
+package deadletter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/pkg/digest"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+)
+
+// Letter is a message a handler gave up on: an event or a digest
+type Letter struct {
+	Time     time.Time      `json:"time"`
+	Handler  string         `json:"handler"`
+	Event    *event.Event   `json:"event,omitempty"`
+	Digest   *digest.Digest `json:"digest,omitempty"`
+	Error    string         `json:"error"`
+	Attempts int            `json:"attempts"`
+}
+
+// File appends letters to a file
+type File struct {
+	path  string
+	mutex sync.Mutex
+}
+
+// NewFile returns a File appending to path, creating it if needed.
+func NewFile(path string) (*File, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+	return &File{path: path}, nil
+}
+
+// Path returns the path of the file
+func (f *File) Path() string {
+	return f.path
+}
+
+// Append adds a letter at the end of the file
+func (f *File) Append(l Letter) error {
+	data, err := json.Marshal(l)
+	if err != nil {
+		return err
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Read returns the letters of the file at path, oldest first. A missing file
+// holds no letters.
+func Read(path string) ([]Letter, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var letters []Letter
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var l Letter
+		if err := json.Unmarshal(scanner.Bytes(), &l); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, line, err)
+		}
+		letters = append(letters, l)
+	}
+	return letters, scanner.Err()
+}
+
+// Write replaces the letters of the file at path
+func Write(path string, letters []Letter) error {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	for _, l := range letters {
+		if err := encoder.Encode(l); err != nil {
+			return err
+		}
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buffer.Bytes(), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Subject describes what a letter holds, e.g. "Pod prod/api Created"
+func (l *Letter) Subject() string {
+	switch {
+	case l.Event != nil:
+		name := l.Event.Name
+		if l.Event.Namespace != "" {
+			name = l.Event.Namespace + "/" + name
+		}
+		return fmt.Sprintf("%s %s %s", l.Event.Kind, name, l.Event.Reason)
+	case l.Digest != nil:
+		return "digest of " + l.Digest.Title()
+	}
+	return "nothing"
+}
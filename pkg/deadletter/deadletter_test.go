@@ -0,0 +1,91 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deadletter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/pkg/digest"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+)
+
+func TestAppendRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dead-letters.jsonl")
+	f, err := NewFile(path)
+	if err != nil {
+		t.Fatalf("NewFile(): %v", err)
+	}
+
+	letters := []Letter{
+		{Handler: "slack", Event: &event.Event{Kind: "Pod", Namespace: "prod", Name: "api", Reason: "Created"}, Error: "channel_not_found", Attempts: 1},
+		{Handler: "webhook", Digest: &digest.Digest{Total: 3, Window: time.Minute}, Error: "giving up after 5 attempts: 503", Attempts: 5},
+	}
+	for _, l := range letters {
+		if err := f.Append(l); err != nil {
+			t.Fatalf("Append(): %v", err)
+		}
+	}
+
+	got, err := Read(path)
+	if err != nil {
+		t.Fatalf("Read(): %v", err)
+	}
+	if len(got) != len(letters) {
+		t.Fatalf("Read() = %d letters, want %d", len(got), len(letters))
+	}
+	for i, l := range got {
+		if l.Handler != letters[i].Handler || l.Error != letters[i].Error || l.Attempts != letters[i].Attempts {
+			t.Errorf("letter %d = %+v, want %+v", i, l, letters[i])
+		}
+	}
+	if subject := got[0].Subject(); subject != "Pod prod/api Created" {
+		t.Errorf("Subject() = %q, want %q", subject, "Pod prod/api Created")
+	}
+	if subject := got[1].Subject(); subject != "digest of 3 events in the last 1m0s" {
+		t.Errorf("Subject() = %q, want %q", subject, "digest of 3 events in the last 1m0s")
+	}
+
+	if err := Write(path, got[1:]); err != nil {
+		t.Fatalf("Write(): %v", err)
+	}
+	got, err = Read(path)
+	if err != nil || len(got) != 1 || got[0].Handler != "webhook" {
+		t.Errorf("Read() after Write() = %+v, %v, want the webhook letter", got, err)
+	}
+}
+
+func TestReadMissingFile(t *testing.T) {
+	letters, err := Read(filepath.Join(t.TempDir(), "missing.jsonl"))
+	if letters != nil || err != nil {
+		t.Errorf("Read() = %v, %v, want no letters", letters, err)
+	}
+}
+
+func TestReadInvalidLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dead-letters.jsonl")
+	if err := os.WriteFile(path, []byte("{\"handler\":\"slack\"}\nnot json\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	_, err := Read(path)
+	if err == nil || !strings.Contains(err.Error(), path+":2:") {
+		t.Errorf("Read() = %v, want an error on line 2", err)
+	}
+}
@@ -0,0 +1,208 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package diff computes which fields changed between two versions of a
+// Kubernetes object.
+//
+// Objects are compared in their unstructured form, so typed objects and the
+// unstructured objects of custom resources are handled alike. Each change is
+// reported under a JSON path such as
+//
+//	spec.template.spec.containers[0].image
+//	metadata.labels["app.kubernetes.io/version"]
+//
+// Fields that change on every write without carrying meaning — the resource
+// version, managed fields, condition heartbeats — are ignored by default.
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/bitnami-labs/kubewatch/pkg/utils"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Change is a field whose value differs between two versions of an object.
+// Old is nil for added fields and New is nil for removed ones.
+type Change struct {
+	Path string      `json:"path"`
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+// DefaultIgnoredPaths are the paths Compute skips unless told otherwise. A
+// path ignores the whole subtree below it, and [] stands for any list index.
+var DefaultIgnoredPaths = []string{
+	"metadata.resourceVersion",
+	"metadata.managedFields",
+	`metadata.annotations["kubectl.kubernetes.io/last-applied-configuration"]`,
+	`metadata.annotations["endpoints.kubernetes.io/last-change-trigger-time"]`,
+	`metadata.annotations["control-plane.alpha.kubernetes.io/leader"]`,
+	"status.conditions[].lastHeartbeatTime",
+}
+
+// Compute returns the changes turning oldObj into newObj, skipping
+// DefaultIgnoredPaths.
+func Compute(oldObj, newObj runtime.Object) ([]Change, error) {
+	return ComputeIgnoring(oldObj, newObj, DefaultIgnoredPaths)
+}
+
+// ComputeIgnoring returns the changes turning oldObj into newObj, skipping
+// the given paths. Changes are sorted by path.
+func ComputeIgnoring(oldObj, newObj runtime.Object, ignored []string) ([]Change, error) {
+	oldMap, err := utils.ToUnstructured(oldObj)
+	if err != nil {
+		return nil, fmt.Errorf("converting %T to compute its changes: %v", oldObj, err)
+	}
+	newMap, err := utils.ToUnstructured(newObj)
+	if err != nil {
+		return nil, fmt.Errorf("converting %T to compute its changes: %v", newObj, err)
+	}
+
+	ignore := make(map[string]bool, len(ignored))
+	for _, p := range ignored {
+		ignore[p] = true
+	}
+
+	var changes []Change
+	compare(nil, oldMap, newMap, ignore, &changes)
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
+
+// segment is one step of a path: a map key, or a list index when key is
+// empty.
+type segment struct {
+	key   string
+	index int
+}
+
+func compare(path []segment, oldValue, newValue interface{}, ignore map[string]bool, changes *[]Change) {
+	if len(path) > 0 && ignore[render(path, true)] {
+		return
+	}
+
+	switch oldTyped := oldValue.(type) {
+	case map[string]interface{}:
+		if newTyped, ok := newValue.(map[string]interface{}); ok {
+			for _, key := range unionKeys(oldTyped, newTyped) {
+				compare(append(path, segment{key: key}), oldTyped[key], newTyped[key], ignore, changes)
+			}
+			return
+		}
+	case []interface{}:
+		if newTyped, ok := newValue.([]interface{}); ok {
+			for i := 0; i < len(oldTyped) || i < len(newTyped); i++ {
+				var o, n interface{}
+				if i < len(oldTyped) {
+					o = oldTyped[i]
+				}
+				if i < len(newTyped) {
+					n = newTyped[i]
+				}
+				compare(append(path, segment{index: i}), o, n, ignore, changes)
+			}
+			return
+		}
+	}
+
+	if !reflect.DeepEqual(oldValue, newValue) {
+		*changes = append(*changes, Change{Path: render(path, false), Old: oldValue, New: newValue})
+	}
+}
+
+func unionKeys(a, b map[string]interface{}) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// render formats a path; with anyIndex set, list indexes are written as [] so
+// the result can be looked up among the ignored paths.
+func render(path []segment, anyIndex bool) string {
+	var b strings.Builder
+	for _, s := range path {
+		switch {
+		case s.key == "" && anyIndex:
+			b.WriteString("[]")
+		case s.key == "":
+			fmt.Fprintf(&b, "[%d]", s.index)
+		case identifier.MatchString(s.key):
+			if b.Len() > 0 {
+				b.WriteByte('.')
+			}
+			b.WriteString(s.key)
+		default:
+			quoted, _ := json.Marshal(s.key)
+			fmt.Fprintf(&b, "[%s]", quoted)
+		}
+	}
+	return b.String()
+}
+
+// maxValueLength bounds how much of a value Summary prints
+const maxValueLength = 80
+
+// Summary renders changes as one "`path`: old → new" line each, listing at
+// most limit of them followed by a count of the rest. A limit of 0 or less
+// lists every change.
+func Summary(changes []Change, limit int) string {
+	var b strings.Builder
+	for i, c := range changes {
+		if limit > 0 && i == limit {
+			fmt.Fprintf(&b, "… and %d more\n", len(changes)-limit)
+			break
+		}
+		fmt.Fprintf(&b, "`%s`: %s → %s\n", c.Path, formatValue(c.Old), formatValue(c.New))
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func formatValue(v interface{}) string {
+	var s string
+	switch typed := v.(type) {
+	case nil:
+		return "<none>"
+	case string:
+		s = typed
+	default:
+		encoded, err := json.Marshal(typed)
+		if err != nil {
+			s = fmt.Sprint(typed)
+		} else {
+			s = string(encoded)
+		}
+	}
+	if runes := []rune(s); len(runes) > maxValueLength {
+		s = string(runes[:maxValueLength]) + "…"
+	}
+	return s
+}
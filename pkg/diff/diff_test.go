@@ -0,0 +1,150 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func pod(mutate func(p *api_v1.Pod)) *api_v1.Pod {
+	p := &api_v1.Pod{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:            "web",
+			Namespace:       "default",
+			ResourceVersion: "1",
+			Labels:          map[string]string{"app": "web"},
+		},
+		Spec: api_v1.PodSpec{
+			Containers: []api_v1.Container{{Name: "app", Image: "nginx:1.26"}},
+		},
+	}
+	if mutate != nil {
+		mutate(p)
+	}
+	return p
+}
+
+func TestCompute(t *testing.T) {
+	var Tests = []struct {
+		name string
+		new  *api_v1.Pod
+		want []Change
+	}{
+		{
+			name: "unchanged",
+			new:  pod(nil),
+		},
+		{
+			name: "image",
+			new:  pod(func(p *api_v1.Pod) { p.Spec.Containers[0].Image = "nginx:1.27" }),
+			want: []Change{{Path: "spec.containers[0].image", Old: "nginx:1.26", New: "nginx:1.27"}},
+		},
+		{
+			name: "added label with dots",
+			new:  pod(func(p *api_v1.Pod) { p.Labels["app.kubernetes.io/version"] = "2" }),
+			want: []Change{{Path: `metadata.labels["app.kubernetes.io/version"]`, New: "2"}},
+		},
+		{
+			name: "removed container",
+			new:  pod(func(p *api_v1.Pod) { p.Spec.Containers = nil }),
+			want: []Change{{Path: "spec.containers", Old: []interface{}{
+				map[string]interface{}{"name": "app", "image": "nginx:1.26", "resources": map[string]interface{}{}},
+			}}},
+		},
+		{
+			name: "ignored resource version and managed fields",
+			new: pod(func(p *api_v1.Pod) {
+				p.ResourceVersion = "2"
+				p.ManagedFields = []meta_v1.ManagedFieldsEntry{{Manager: "kubectl"}}
+			}),
+		},
+	}
+
+	for _, tt := range Tests {
+		got, err := Compute(pod(nil), tt.new)
+		if err != nil {
+			t.Fatalf("%s: Compute(): %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Compute() = %#v, want %#v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestComputeIgnoresHeartbeatsInAnyCondition(t *testing.T) {
+	node := func(heartbeat, status string) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"kind": "Node",
+			"status": map[string]interface{}{
+				"conditions": []interface{}{
+					map[string]interface{}{"type": "MemoryPressure", "status": "False", "lastHeartbeatTime": heartbeat},
+					map[string]interface{}{"type": "Ready", "status": status, "lastHeartbeatTime": heartbeat},
+				},
+			},
+		}}
+	}
+
+	got, err := Compute(node("10:00", "True"), node("10:01", "True"))
+	if err != nil {
+		t.Fatalf("Compute(): %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("Compute() = %v, want no changes", got)
+	}
+
+	got, err = Compute(node("10:00", "True"), node("10:01", "False"))
+	if err != nil {
+		t.Fatalf("Compute(): %v", err)
+	}
+	want := []Change{{Path: "status.conditions[1].status", Old: "True", New: "False"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Compute() = %v, want %v", got, want)
+	}
+}
+
+func TestSummary(t *testing.T) {
+	changes := []Change{
+		{Path: "spec.replicas", Old: int64(1), New: int64(3)},
+		{Path: "metadata.labels.tier", New: "frontend"},
+		{Path: "spec.paused", Old: true},
+	}
+
+	var Tests = []struct {
+		limit int
+		want  string
+	}{
+		{0, "`spec.replicas`: 1 → 3\n`metadata.labels.tier`: <none> → frontend\n`spec.paused`: true → <none>"},
+		{2, "`spec.replicas`: 1 → 3\n`metadata.labels.tier`: <none> → frontend\n… and 1 more"},
+	}
+
+	for _, tt := range Tests {
+		if got := Summary(changes, tt.limit); got != tt.want {
+			t.Errorf("Summary(%d) = %q, want %q", tt.limit, got, tt.want)
+		}
+	}
+
+	long := Summary([]Change{{Path: "data.script", New: strings.Repeat("x", 200)}}, 0)
+	if !strings.HasSuffix(long, "…") || len(long) > 120 {
+		t.Errorf("Summary() did not truncate a long value: %q", long)
+	}
+}
@@ -0,0 +1,148 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/bitnami-labs/kubewatch/config"
+)
+
+// IgnoreRules decides which paths are ignored when diffing each kind of
+// object
+type IgnoreRules struct {
+	// all applies to every kind, byKind to the lowercased kinds it is keyed by
+	all    []string
+	byKind map[string][]string
+}
+
+// NewIgnoreRules validates the configured rules. The DefaultIgnoredPaths
+// apply to every kind in addition to them.
+func NewIgnoreRules(c []config.IgnorePathRule) (*IgnoreRules, error) {
+	r := &IgnoreRules{
+		all:    append([]string(nil), DefaultIgnoredPaths...),
+		byKind: map[string][]string{},
+	}
+
+	for i, rule := range c {
+		if len(rule.Paths) == 0 {
+			return nil, fmt.Errorf("ignore path rule %d: no paths", i)
+		}
+		paths := make([]string, 0, len(rule.Paths))
+		for _, p := range rule.Paths {
+			canonical, err := canonicalPath(p)
+			if err != nil {
+				return nil, fmt.Errorf("ignore path rule %d: %v", i, err)
+			}
+			paths = append(paths, canonical)
+		}
+
+		if len(rule.Kinds) == 0 {
+			r.all = append(r.all, paths...)
+			continue
+		}
+		for _, kind := range rule.Kinds {
+			kind = strings.ToLower(kind)
+			r.byKind[kind] = append(r.byKind[kind], paths...)
+		}
+	}
+
+	return r, nil
+}
+
+// For returns the paths ignored for objects of the given kind, which is
+// matched case-insensitively.
+func (r *IgnoreRules) For(kind string) []string {
+	if r == nil {
+		return DefaultIgnoredPaths
+	}
+	return append(append([]string(nil), r.all...), r.byKind[strings.ToLower(kind)]...)
+}
+
+// canonicalPath parses a configured path and renders it the way compare
+// renders the paths it looks up, so that e.g. metadata.labels.app and
+// metadata.labels["app"] are the same path.
+func canonicalPath(p string) (string, error) {
+	path, err := parsePath(p)
+	if err != nil {
+		return "", err
+	}
+	return render(path, true), nil
+}
+
+// SplitPath parses a path written as in the configuration, e.g.
+// spec.containers[].env or metadata.annotations["example.com/key"], into
+// its keys. An empty key stands for any list index.
+func SplitPath(p string) ([]string, error) {
+	path, err := parsePath(p)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, len(path))
+	for i, s := range path {
+		keys[i] = s.key
+	}
+	return keys, nil
+}
+
+func parsePath(p string) ([]segment, error) {
+	var path []segment
+	rest := p
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, "[]"):
+			path = append(path, segment{})
+			rest = rest[2:]
+		case strings.HasPrefix(rest, `["`):
+			end := strings.Index(rest[2:], `"]`)
+			if end < 0 {
+				return nil, fmt.Errorf("invalid path %q: unterminated quoted key", p)
+			}
+			var key string
+			if err := json.Unmarshal([]byte(rest[1:end+3]), &key); err != nil || key == "" {
+				return nil, fmt.Errorf("invalid path %q: bad quoted key %s", p, rest[1:end+3])
+			}
+			path = append(path, segment{key: key})
+			rest = rest[end+4:]
+		case strings.HasPrefix(rest, "["):
+			return nil, fmt.Errorf(`invalid path %q: only [] and ["key"] may appear in brackets`, p)
+		default:
+			if len(path) > 0 {
+				if rest[0] != '.' {
+					return nil, fmt.Errorf("invalid path %q: expected . before %q", p, rest)
+				}
+				rest = rest[1:]
+			}
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("invalid path %q: empty key", p)
+			}
+			path = append(path, segment{key: rest[:end]})
+			rest = rest[end:]
+		}
+	}
+
+	if len(path) == 0 {
+		return nil, fmt.Errorf("invalid path %q: empty path", p)
+	}
+	return path, nil
+}
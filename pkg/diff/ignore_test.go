@@ -0,0 +1,124 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	api_v1 "k8s.io/api/core/v1"
+)
+
+func TestCanonicalPath(t *testing.T) {
+	var Tests = []struct {
+		path string
+		want string
+		err  string
+	}{
+		{"metadata.labels.app", "metadata.labels.app", ""},
+		{`metadata.labels["app"]`, "metadata.labels.app", ""},
+		{"metadata.annotations.checked-at", `metadata.annotations["checked-at"]`, ""},
+		{`metadata.annotations["example.com/checked-at"]`, `metadata.annotations["example.com/checked-at"]`, ""},
+		{"status.conditions[].lastTransitionTime", "status.conditions[].lastTransitionTime", ""},
+		{"spec.containers[0].image", "", "only [] and"},
+		{"metadata..labels", "", "empty key"},
+		{"metadata.", "", "empty key"},
+		{`metadata.labels["app`, "", "unterminated"},
+		{"", "", "empty path"},
+	}
+
+	for _, tt := range Tests {
+		got, err := canonicalPath(tt.path)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("canonicalPath(%q) error = %v, want one containing %q", tt.path, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("canonicalPath(%q) = %q, %v, want %q", tt.path, got, err, tt.want)
+		}
+	}
+}
+
+func TestSplitPath(t *testing.T) {
+	var Tests = []struct {
+		path string
+		want []string
+	}{
+		{"metadata.managedFields", []string{"metadata", "managedFields"}},
+		{`metadata.annotations["kubectl.kubernetes.io/last-applied-configuration"]`, []string{"metadata", "annotations", "kubectl.kubernetes.io/last-applied-configuration"}},
+		{"spec.containers[].env", []string{"spec", "containers", "", "env"}},
+	}
+
+	for _, tt := range Tests {
+		got, err := SplitPath(tt.path)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SplitPath(%q) = %q, %v, want %q", tt.path, got, err, tt.want)
+		}
+	}
+	if _, err := SplitPath("spec.containers[0]"); err == nil {
+		t.Errorf("SplitPath() of an invalid path returned no error")
+	}
+}
+
+func TestIgnoreRulesApplyPerKind(t *testing.T) {
+	rules, err := NewIgnoreRules([]config.IgnorePathRule{
+		{Kinds: []string{"Deployment"}, Paths: []string{"spec.replicas"}},
+		{Paths: []string{"metadata.labels.checked"}},
+	})
+	if err != nil {
+		t.Fatalf("NewIgnoreRules(): %v", err)
+	}
+
+	old := &api_v1.ReplicationController{}
+	old.Labels = map[string]string{"checked": "1"}
+	replicas := int32(2)
+	updated := old.DeepCopy()
+	updated.Labels["checked"] = "2"
+	updated.Spec.Replicas = &replicas
+
+	got, err := ComputeIgnoring(old, updated, rules.For("deployment"))
+	if err != nil {
+		t.Fatalf("ComputeIgnoring(): %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("ComputeIgnoring() for a deployment = %v, want no changes", got)
+	}
+
+	got, err = ComputeIgnoring(old, updated, rules.For("ReplicationController"))
+	if err != nil {
+		t.Fatalf("ComputeIgnoring(): %v", err)
+	}
+	want := []Change{{Path: "spec.replicas", New: int64(2)}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ComputeIgnoring() for a replication controller = %v, want %v", got, want)
+	}
+}
+
+func TestNewIgnoreRulesRejectsInvalidRules(t *testing.T) {
+	for _, rule := range []config.IgnorePathRule{
+		{Kinds: []string{"Node"}},
+		{Paths: []string{"status.conditions[0]"}},
+	} {
+		if _, err := NewIgnoreRules([]config.IgnorePathRule{rule}); err == nil {
+			t.Errorf("NewIgnoreRules(%+v) succeeded, want an error", rule)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package digest summarizes a batch of events as a single message, grouping
+// them by namespace, kind and reason.
+package digest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+)
+
+// DefaultTopNames is how many object names a group lists when not told
+// otherwise
+const DefaultTopNames = 5
+
+// maxListedGroups bounds the groups Text lists
+const maxListedGroups = 25
+
+// Handler is implemented by handlers able to send a digest as one message
+type Handler interface {
+	HandleDigest(d *Digest) error
+}
+
+// Digest summarizes the events collected during a window
+type Digest struct {
+	Window time.Duration
+	Total  int
+	// Status is the most severe status among the events
+	Status string
+	// Groups are sorted by decreasing count
+	Groups []Group
+	// Failure tells why a handler gave up on the digest, when it is handed
+	// to a dead-letter handler
+	Failure *event.Failure
+}
+
+// Group counts the events sharing a namespace, kind and reason
+type Group struct {
+	Namespace string
+	Kind      string
+	Reason    string
+	Count     int
+	// Names are the names of the objects with the most events, most
+	// frequent first
+	Names []string
+	// OtherNames counts the objects left out of Names
+	OtherNames int
+}
+
+// Title returns a one line summary of the digest
+func (d *Digest) Title() string {
+	return fmt.Sprintf("%d events in the last %s", d.Total, d.Window)
+}
+
+// Text lists the groups, one per line, followed by the failure if any
+func (d *Digest) Text() string {
+	var b strings.Builder
+	for i, g := range d.Groups {
+		if i == maxListedGroups {
+			fmt.Fprintf(&b, "… and %d more groups\n", len(d.Groups)-maxListedGroups)
+			break
+		}
+		b.WriteString(g.String())
+		b.WriteByte('\n')
+	}
+	if d.Failure != nil {
+		fmt.Fprintf(&b, "(%s)\n", d.Failure)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// String renders the group as "`namespace` Kind Reason ×count: names"
+func (g Group) String() string {
+	var b strings.Builder
+	if g.Namespace != "" {
+		fmt.Fprintf(&b, "`%s` ", g.Namespace)
+	}
+	fmt.Fprintf(&b, "%s %s ×%d: %s", g.Kind, g.Reason, g.Count, strings.Join(g.Names, ", "))
+	if g.OtherNames > 0 {
+		fmt.Fprintf(&b, " and %d more", g.OtherNames)
+	}
+	return b.String()
+}
+
+// severity orders event statuses, higher is worse
+var severity = map[string]int{
+	"Normal":  1,
+	"Warning": 2,
+	"Danger":  3,
+}
+
+type groupKey struct {
+	namespace, kind, reason string
+}
+
+// Builder collects events into a digest
+type Builder struct {
+	topNames int
+	first    event.Event
+	total    int
+	status   string
+	groups   map[groupKey]map[string]int
+}
+
+// NewBuilder returns an empty Builder whose groups list up to topNames
+// names, or DefaultTopNames when topNames is not positive.
+func NewBuilder(topNames int) *Builder {
+	if topNames <= 0 {
+		topNames = DefaultTopNames
+	}
+	return &Builder{topNames: topNames, groups: map[groupKey]map[string]int{}}
+}
+
+// Add counts an event
+func (b *Builder) Add(e event.Event) {
+	if b.total == 0 {
+		b.first = e
+	}
+	b.total++
+	if severity[e.Status] > severity[b.status] {
+		b.status = e.Status
+	}
+
+	key := groupKey{e.Namespace, e.Kind, e.Reason}
+	names, ok := b.groups[key]
+	if !ok {
+		names = map[string]int{}
+		b.groups[key] = names
+	}
+	names[e.Name]++
+}
+
+// Len returns the number of events added
+func (b *Builder) Len() int {
+	return b.total
+}
+
+// First returns the first event added
+func (b *Builder) First() event.Event {
+	return b.first
+}
+
+// Digest returns the digest of the events added during window
+func (b *Builder) Digest(window time.Duration) *Digest {
+	d := &Digest{Window: window, Total: b.total, Status: b.status}
+	for key, names := range b.groups {
+		g := Group{Namespace: key.namespace, Kind: key.kind, Reason: key.reason}
+		for name, count := range names {
+			g.Count += count
+			g.Names = append(g.Names, name)
+		}
+		sort.Slice(g.Names, func(i, j int) bool {
+			if names[g.Names[i]] != names[g.Names[j]] {
+				return names[g.Names[i]] > names[g.Names[j]]
+			}
+			return g.Names[i] < g.Names[j]
+		})
+		if len(g.Names) > b.topNames {
+			g.OtherNames = len(g.Names) - b.topNames
+			g.Names = g.Names[:b.topNames]
+		}
+		d.Groups = append(d.Groups, g)
+	}
+
+	sort.Slice(d.Groups, func(i, j int) bool {
+		a, c := d.Groups[i], d.Groups[j]
+		if a.Count != c.Count {
+			return a.Count > c.Count
+		}
+		if a.Namespace != c.Namespace {
+			return a.Namespace < c.Namespace
+		}
+		if a.Kind != c.Kind {
+			return a.Kind < c.Kind
+		}
+		return a.Reason < c.Reason
+	})
+	return d
+}
@@ -0,0 +1,60 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package digest
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+)
+
+func TestBuilderGroupsEvents(t *testing.T) {
+	b := NewBuilder(2)
+	for i := 0; i < 4; i++ {
+		b.Add(event.Event{Namespace: "prod", Kind: "Pod", Reason: "Created", Name: fmt.Sprintf("api-%d", i), Status: "Normal"})
+	}
+	b.Add(event.Event{Namespace: "prod", Kind: "Pod", Reason: "Created", Name: "api-3", Status: "Normal"})
+	b.Add(event.Event{Kind: "Node", Reason: "Updated", Name: "node-1", Status: "Warning"})
+
+	if b.Len() != 6 || b.First().Name != "api-0" {
+		t.Fatalf("Len() = %d, First() = %q, want 6 and api-0", b.Len(), b.First().Name)
+	}
+
+	d := b.Digest(time.Minute)
+	want := &Digest{
+		Window: time.Minute,
+		Total:  6,
+		Status: "Warning",
+		Groups: []Group{
+			{Namespace: "prod", Kind: "Pod", Reason: "Created", Count: 5, Names: []string{"api-3", "api-0"}, OtherNames: 2},
+			{Kind: "Node", Reason: "Updated", Count: 1, Names: []string{"node-1"}},
+		},
+	}
+	if !reflect.DeepEqual(d, want) {
+		t.Fatalf("Digest() = %+v, want %+v", d, want)
+	}
+
+	if got := d.Title(); got != "6 events in the last 1m0s" {
+		t.Errorf("Title() = %q", got)
+	}
+	if got, want := d.Text(), "`prod` Pod Created ×5: api-3, api-0 and 2 more\nNode Updated ×1: node-1"; got != want {
+		t.Errorf("Text() = %q, want %q", got, want)
+	}
+}
@@ -15,6 +15,8 @@ package event
 
 import (
 	"fmt"
+
+	"github.com/bitnami-labs/kubewatch/pkg/diff"
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -32,8 +34,35 @@ type Event struct {
 	Name       string
 	Obj        runtime.Object
 	OldObj     runtime.Object
+	// Changes lists the fields that differ between OldObj and Obj on updates
+	Changes []diff.Change
+	// Repeated counts the repeats of the event that deduplication held back
+	// and this event summarizes
+	Repeated int
+	// Failure tells why a handler gave up on the event, when it is handed to
+	// a dead-letter handler
+	Failure *Failure
+}
+
+// Failure describes a handler giving up on a message
+type Failure struct {
+	// Handler is the name of the handler that gave up
+	Handler string
+	// Error is the last error of the handler
+	Error    string
+	Attempts int
+}
+
+// String renders the failure as "handler slack gave up after 5 attempts:
+// error"
+func (f *Failure) String() string {
+	return fmt.Sprintf("handler %s gave up after %d attempts: %s", f.Handler, f.Attempts, f.Error)
 }
 
+// maxListedChanges is how many changes ChangesMessage lists before
+// summarizing the rest as a count
+const maxListedChanges = 10
+
 var m = map[string]string{
 	"created": "Normal",
 	"deleted": "Danger",
@@ -63,6 +92,14 @@ func (e *Event) Message() (msg string) {
 			e.Name,
 			e.Reason,
 		)
+	case "StorageClass", "PriorityClass", "ValidatingWebhookConfiguration", "MutatingWebhookConfiguration", "CustomResourceDefinition":
+		// Cluster-scoped, so there is no namespace to tell.
+		msg = fmt.Sprintf(
+			"A `%s` `%s` has been `%s`",
+			e.Kind,
+			e.Name,
+			e.Reason,
+		)
 	case "NodeReady":
 		msg = fmt.Sprintf(
 			"Node `%s` is Ready : \nNodeReady",
@@ -94,5 +131,17 @@ func (e *Event) Message() (msg string) {
 			e.Name,
 		)
 	}
+	if e.Repeated > 0 {
+		msg += fmt.Sprintf("\n(repeated %d times)", e.Repeated)
+	}
+	if e.Failure != nil {
+		msg += fmt.Sprintf("\n(%s)", e.Failure)
+	}
 	return msg
 }
+
+// ChangesMessage returns the changed fields of an update, one per line, or an
+// empty string when there are none.
+func (e *Event) ChangesMessage() string {
+	return diff.Summary(e.Changes, maxListedChanges)
+}
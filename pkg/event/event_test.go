@@ -0,0 +1,49 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package event
+
+import "testing"
+
+func TestMessage(t *testing.T) {
+	var Tests = []struct {
+		event Event
+		want  string
+	}{
+		{
+			Event{Kind: "CronJob", Namespace: "prod", Name: "backup", Reason: "Created"},
+			"A `CronJob` in namespace `prod` has been `Created`:\n`backup`",
+		},
+		{
+			Event{Kind: "StorageClass", Name: "fast", Reason: "Deleted"},
+			"A `StorageClass` `fast` has been `Deleted`",
+		},
+		{
+			Event{Kind: "ValidatingWebhookConfiguration", Name: "gatekeeper", Reason: "Updated", Repeated: 2},
+			"A `ValidatingWebhookConfiguration` `gatekeeper` has been `Updated`\n(repeated 2 times)",
+		},
+		{
+			Event{Kind: "StorageClass", Name: "fast", Reason: "Deleted", Failure: &Failure{Handler: "slack", Error: "timeout", Attempts: 5}},
+			"A `StorageClass` `fast` has been `Deleted`\n(handler slack gave up after 5 attempts: timeout)",
+		},
+	}
+
+	for _, tt := range Tests {
+		if got := tt.event.Message(); got != tt.want {
+			t.Errorf("Message() = %q, want %q", got, tt.want)
+		}
+	}
+}
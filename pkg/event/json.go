@@ -0,0 +1,53 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package event
+
+import (
+	"encoding/json"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// UnmarshalJSON decodes an event encoded by encoding/json, e.g. one read back
+// from disk. The objects of the event come back as unstructured objects,
+// since their Go types are not recorded.
+func (e *Event) UnmarshalJSON(data []byte) error {
+	// encoded has the fields of Event but not its methods, so decoding into
+	// it does not recurse
+	type encoded Event
+	var decoded struct {
+		encoded
+		Obj    map[string]interface{}
+		OldObj map[string]interface{}
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	*e = Event(decoded.encoded)
+	e.Obj = toObject(decoded.Obj)
+	e.OldObj = toObject(decoded.OldObj)
+	return nil
+}
+
+func toObject(object map[string]interface{}) runtime.Object {
+	if object == nil {
+		return nil
+	}
+	return &unstructured.Unstructured{Object: object}
+}
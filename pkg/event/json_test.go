@@ -0,0 +1,63 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package event
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/bitnami-labs/kubewatch/pkg/diff"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestEventJSONRoundTrip(t *testing.T) {
+	e := Event{
+		Namespace: "prod",
+		Kind:      "pod",
+		Name:      "api",
+		Reason:    "Updated",
+		Status:    "Warning",
+		Obj:       &api_v1.Pod{ObjectMeta: meta_v1.ObjectMeta{Name: "api", Labels: map[string]string{"app": "api"}}},
+		Changes:   []diff.Change{{Path: "spec.replicas", Old: 1, New: 3}},
+		Repeated:  2,
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		t.Fatalf("Marshal(): %v", err)
+	}
+	var decoded Event
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal(): %v", err)
+	}
+
+	if decoded.Name != "api" || decoded.Reason != "Updated" || decoded.Repeated != 2 || decoded.Message() != e.Message() {
+		t.Errorf("decoded %+v, want the fields of %+v", decoded, e)
+	}
+	if len(decoded.Changes) != 1 || decoded.Changes[0].Path != "spec.replicas" {
+		t.Errorf("decoded changes %+v, want the replicas change", decoded.Changes)
+	}
+	obj, ok := decoded.Obj.(*unstructured.Unstructured)
+	if !ok || obj.GetLabels()["app"] != "api" {
+		t.Errorf("decoded object %#v, want the pod as an unstructured object", decoded.Obj)
+	}
+	if decoded.OldObj != nil {
+		t.Errorf("decoded old object %#v, want none", decoded.OldObj)
+	}
+}
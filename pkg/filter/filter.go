@@ -17,11 +17,13 @@ limitations under the License.
 package filter
 
 import (
+	"fmt"
 	"os"
 	"reflect"
 	"strconv"
 	"strings"
 
+	"github.com/bitnami-labs/kubewatch/config"
 	"github.com/bitnami-labs/kubewatch/pkg/event"
 	"github.com/sirupsen/logrus"
 
@@ -32,11 +34,26 @@ import (
 
 // Filter is the main filter struct
 type Filter struct {
+	// rules are the user-configured rules, evaluated before any preset.
+	rules []rule
+	// enabled turns on the built-in "advanced" preset.
 	enabled bool
 }
 
+// AdvancedPreset is the name of the built-in preset dropping the noisiest
+// Event, Job and Pod notifications.
+const AdvancedPreset = "advanced"
+
 // NewFilter creates a new filter instance
 func NewFilter() *Filter {
+	f, _ := New(config.Filter{})
+	return f
+}
+
+// New creates a filter applying the configured rules and presets. The
+// ADVANCED_FILTERS environment variable, when true, enables the advanced
+// preset as well.
+func New(c config.Filter) (*Filter, error) {
 	enabled := false
 	if envVal := os.Getenv("ADVANCED_FILTERS"); envVal != "" {
 		parsedVal, err := strconv.ParseBool(envVal)
@@ -47,20 +64,50 @@ func NewFilter() *Filter {
 		}
 	}
 
+	for _, preset := range c.Presets {
+		switch preset {
+		case AdvancedPreset:
+			enabled = true
+		default:
+			return nil, fmt.Errorf("unknown filter preset %q, supported presets are: %s", preset, AdvancedPreset)
+		}
+	}
+
+	rules := make([]rule, 0, len(c.Rules))
+	for i, r := range c.Rules {
+		compiled, err := compileRule(r)
+		if err != nil {
+			return nil, fmt.Errorf("filter rule %d: %v", i+1, err)
+		}
+		rules = append(rules, compiled)
+	}
+
 	if enabled {
 		logrus.Info("Advanced filtering is ENABLED")
 	} else {
 		logrus.Info("Advanced filtering is DISABLED")
 	}
+	if len(rules) > 0 {
+		logrus.Infof("Filtering events with %d configured rules", len(rules))
+	}
 
 	return &Filter{
+		rules:   rules,
 		enabled: enabled,
-	}
+	}, nil
 }
 
-// ShouldSendEvent determines if an event should be sent to Robusta
+// ShouldSendEvent determines if an event should be sent to handlers
 func (f *Filter) ShouldSendEvent(e event.Event) bool {
-	// If filtering is disabled, send all events
+	// The first configured rule matching the event decides
+	for i, r := range f.rules {
+		if r.matches(e) {
+			logrus.Debugf("Filter rule %d %ss event - Kind: %s, Reason: %s, Name: %s", i+1, r.action, e.Kind, e.Reason, e.Name)
+			return r.action == include
+		}
+	}
+
+	// If the advanced preset is disabled, send all remaining events
 	if !f.enabled {
 		return true
 	}
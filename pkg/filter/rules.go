@@ -0,0 +1,177 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filter
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+
+	api_v1 "k8s.io/api/core/v1"
+	events_v1 "k8s.io/api/events/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	include = "include"
+	exclude = "exclude"
+)
+
+// rule is a compiled config.FilterRule
+type rule struct {
+	action        string
+	kinds         []string
+	namespaces    []string
+	name          *regexp.Regexp
+	labelSelector labels.Selector
+	annotations   map[string]string
+	reasons       []string
+	eventTypes    []string
+	eventReasons  []string
+}
+
+// compileRule validates a configured rule and prepares it for matching
+func compileRule(c config.FilterRule) (rule, error) {
+	r := rule{
+		action:       strings.ToLower(c.Action),
+		kinds:        c.Kinds,
+		namespaces:   c.Namespaces,
+		annotations:  c.Annotations,
+		reasons:      c.Reasons,
+		eventTypes:   c.EventTypes,
+		eventReasons: c.EventReasons,
+	}
+
+	if r.action != include && r.action != exclude {
+		return r, fmt.Errorf("action must be %q or %q, got %q", include, exclude, c.Action)
+	}
+	for _, pattern := range c.Namespaces {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return r, fmt.Errorf("invalid namespace pattern %q: %v", pattern, err)
+		}
+	}
+	for key, pattern := range c.Annotations {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return r, fmt.Errorf("invalid pattern %q for annotation %s: %v", pattern, key, err)
+		}
+	}
+	if c.Name != "" {
+		name, err := regexp.Compile(c.Name)
+		if err != nil {
+			return r, fmt.Errorf("invalid name regular expression %q: %v", c.Name, err)
+		}
+		r.name = name
+	}
+	if c.LabelSelector != "" {
+		selector, err := labels.Parse(c.LabelSelector)
+		if err != nil {
+			return r, fmt.Errorf("invalid label selector %q: %v", c.LabelSelector, err)
+		}
+		r.labelSelector = selector
+	}
+
+	return r, nil
+}
+
+// matches reports whether the event satisfies every condition of the rule
+func (r rule) matches(e event.Event) bool {
+	if len(r.kinds) > 0 && !ContainsFold(r.kinds, e.Kind) {
+		return false
+	}
+	if len(r.namespaces) > 0 && !MatchesAnyGlob(r.namespaces, e.Namespace) {
+		return false
+	}
+	if r.name != nil && !r.name.MatchString(e.Name) {
+		return false
+	}
+	if len(r.reasons) > 0 && !ContainsFold(r.reasons, e.Reason) {
+		return false
+	}
+
+	if r.labelSelector != nil || len(r.annotations) > 0 {
+		accessor, err := meta.Accessor(e.Obj)
+		if err != nil {
+			return false
+		}
+		if r.labelSelector != nil && !r.labelSelector.Matches(labels.Set(accessor.GetLabels())) {
+			return false
+		}
+		annotations := accessor.GetAnnotations()
+		for key, pattern := range r.annotations {
+			value, ok := annotations[key]
+			if !ok {
+				return false
+			}
+			if matched, _ := path.Match(pattern, value); !matched {
+				return false
+			}
+		}
+	}
+
+	if len(r.eventTypes) > 0 || len(r.eventReasons) > 0 {
+		eventType, eventReason, ok := kubernetesEvent(e)
+		if !ok {
+			return false
+		}
+		if len(r.eventTypes) > 0 && !ContainsFold(r.eventTypes, eventType) {
+			return false
+		}
+		if len(r.eventReasons) > 0 && !ContainsFold(r.eventReasons, eventReason) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// kubernetesEvent returns the type and reason of the Kubernetes Event object
+// carried by e, if it carries one
+func kubernetesEvent(e event.Event) (eventType, reason string, ok bool) {
+	switch obj := e.Obj.(type) {
+	case *api_v1.Event:
+		return obj.Type, obj.Reason, true
+	case *events_v1.Event:
+		return obj.Type, obj.Reason, true
+	}
+	return "", "", false
+}
+
+// MatchesAnyGlob reports whether s matches any of the glob patterns, e.g.
+// kube-*.
+func MatchesAnyGlob(patterns []string, s string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, s); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ContainsFold reports whether values holds s, ignoring case.
+func ContainsFold(values []string, s string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filter
+
+import (
+	"testing"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestConfiguredRules(t *testing.T) {
+	f, err := New(config.Filter{Rules: []config.FilterRule{
+		{Action: "include", Kinds: []string{"Pod"}, Annotations: map[string]string{"kubewatch/notify": "always"}},
+		{Action: "exclude", Namespaces: []string{"kube-*", "*-sandbox"}},
+		{Action: "exclude", Kinds: []string{"pod"}, Name: "^canary-"},
+		{Action: "exclude", LabelSelector: "tier in (cache,batch)"},
+		{Action: "exclude", Kinds: []string{"Event"}, EventTypes: []string{"Normal"}},
+		{Action: "exclude", Kinds: []string{"Deployment"}, Reasons: []string{"Updated"}},
+		{Action: "include", EventReasons: []string{"BackOff"}},
+	}})
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+
+	pod := func(name string, labels, annotations map[string]string) *api_v1.Pod {
+		return &api_v1.Pod{ObjectMeta: meta_v1.ObjectMeta{Name: name, Labels: labels, Annotations: annotations}}
+	}
+
+	tests := []struct {
+		name     string
+		event    event.Event
+		expected bool
+	}{
+		{
+			name:     "annotation include wins over later excludes",
+			event:    event.Event{Kind: "Pod", Namespace: "kube-system", Name: "dns", Obj: pod("dns", nil, map[string]string{"kubewatch/notify": "always"})},
+			expected: true,
+		},
+		{
+			name:     "namespace glob excludes",
+			event:    event.Event{Kind: "Pod", Namespace: "team-sandbox", Name: "api", Obj: pod("api", nil, nil)},
+			expected: false,
+		},
+		{
+			name:     "name regex excludes",
+			event:    event.Event{Kind: "Pod", Namespace: "prod", Name: "canary-api", Obj: pod("canary-api", nil, nil)},
+			expected: false,
+		},
+		{
+			name:     "name regex does not match",
+			event:    event.Event{Kind: "Pod", Namespace: "prod", Name: "api-canary", Obj: pod("api-canary", nil, nil)},
+			expected: true,
+		},
+		{
+			name:     "label selector excludes",
+			event:    event.Event{Kind: "Pod", Namespace: "prod", Name: "redis", Obj: pod("redis", map[string]string{"tier": "cache"}, nil)},
+			expected: false,
+		},
+		{
+			name:     "event type excludes",
+			event:    event.Event{Kind: "Event", Namespace: "prod", Obj: &api_v1.Event{Type: api_v1.EventTypeNormal}},
+			expected: false,
+		},
+		{
+			name:     "reason excludes",
+			event:    event.Event{Kind: "Deployment", Namespace: "prod", Reason: "Updated"},
+			expected: false,
+		},
+		{
+			name:     "event reason includes",
+			event:    event.Event{Kind: "Event", Namespace: "prod", Obj: &api_v1.Event{Type: api_v1.EventTypeWarning, Reason: "BackOff"}},
+			expected: true,
+		},
+		{
+			name:     "no rule matches",
+			event:    event.Event{Kind: "Deployment", Namespace: "prod", Reason: "Created"},
+			expected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := f.ShouldSendEvent(tt.event); result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestRulesOverrideAdvancedPreset(t *testing.T) {
+	f, err := New(config.Filter{
+		Presets: []string{AdvancedPreset},
+		Rules: []config.FilterRule{
+			{Action: "include", Kinds: []string{"Event"}, Namespaces: []string{"prod"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+
+	normal := &api_v1.Event{Type: api_v1.EventTypeNormal}
+	if !f.ShouldSendEvent(event.Event{Kind: "Event", Namespace: "prod", Reason: "Created", Obj: normal}) {
+		t.Error("rule including prod events did not override the preset")
+	}
+	if f.ShouldSendEvent(event.Event{Kind: "Event", Namespace: "dev", Reason: "Created", Obj: normal}) {
+		t.Error("advanced preset did not filter a Normal event")
+	}
+}
+
+func TestNewRejectsInvalidConfig(t *testing.T) {
+	tests := []config.Filter{
+		{Presets: []string{"quiet"}},
+		{Rules: []config.FilterRule{{Action: "drop"}}},
+		{Rules: []config.FilterRule{{Action: "exclude", Name: "("}}},
+		{Rules: []config.FilterRule{{Action: "exclude", Namespaces: []string{"[kube"}}}},
+		{Rules: []config.FilterRule{{Action: "exclude", LabelSelector: "tier in (cache"}}},
+		{Rules: []config.FilterRule{{Action: "exclude", Annotations: map[string]string{"a": "[b"}}}},
+	}
+
+	for _, c := range tests {
+		if _, err := New(c); err == nil {
+			t.Errorf("New(%+v) succeeded, want an error", c)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/digest"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/outbox"
+	"github.com/sirupsen/logrus"
+)
+
+// batchingHandler is a handler able to send digests
+type batchingHandler interface {
+	Handler
+	digest.Handler
+}
+
+// Batcher handler implements Handler interface,
+// collect the events of a window and send them to one destination as a digest
+//
+// When the configuration sets an outbox directory, the events of the window
+// are kept on disk too, until the digest is handed to next, so that a restart
+// does not lose them.
+type Batcher struct {
+	name     string
+	next     batchingHandler
+	window   time.Duration
+	topNames int
+	// afterFunc runs f once d has elapsed; tests replace it to control time
+	afterFunc func(d time.Duration, f func())
+
+	mutex sync.Mutex
+	// pending holds the events of the current window, nil when none is open
+	pending []event.Event
+	// log holds the pending events on disk, in order
+	log *outbox.Log
+}
+
+// NewBatcher returns a handler sending the events of each window to next,
+// named name, as a digest, or next itself when the settings disable
+// batching.
+func NewBatcher(name string, next Handler, c config.Digest) (Handler, error) {
+	if c.Window == 0 {
+		return next, nil
+	}
+	if c.Window < 0 {
+		return nil, fmt.Errorf("digest window must not be negative, got %s", c.Window)
+	}
+	batching, ok := next.(batchingHandler)
+	if !ok || !sendsDigests(next) {
+		return nil, fmt.Errorf("%T handlers cannot send digests", innermost(next))
+	}
+	return &Batcher{
+		name:      name,
+		next:      batching,
+		window:    c.Window,
+		topNames:  c.TopNames,
+		afterFunc: func(d time.Duration, f func()) { time.AfterFunc(d, f) },
+	}, nil
+}
+
+// Init initializes the handler behind the batcher, then opens the log of
+// pending events when the configuration sets an outbox directory, and opens
+// a window with the events a previous run left in it.
+func (b *Batcher) Init(c *config.Config) error {
+	if err := b.next.Init(c); err != nil {
+		return err
+	}
+	if c.Outbox.Dir == "" {
+		return nil
+	}
+
+	log, err := outbox.Open(filepath.Join(c.Outbox.Dir, b.name+".digest"), 0)
+	if err != nil {
+		return err
+	}
+	records, err := log.Records()
+	if err != nil {
+		return err
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.log = log
+	for _, data := range records {
+		var e event.Event
+		if err := json.Unmarshal(data, &e); err != nil {
+			return fmt.Errorf("handler %s: invalid digest record: %v", b.name, err)
+		}
+		b.add(e)
+	}
+	if len(records) > 0 {
+		logrus.Infof("handler %s: sending %d events left in a digest window", b.name, len(records))
+	}
+	return nil
+}
+
+// Handle adds the event to the current window, opening one if needed. The
+// event is sent when the window closes, so errors are only logged then.
+func (b *Batcher) Handle(e event.Event) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.log != nil {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		if err := b.log.Append(data); err != nil {
+			return fmt.Errorf("cannot write to the digest log: %v", err)
+		}
+	}
+	b.add(e)
+	return nil
+}
+
+// add adds the event to the current window, opening one if needed. The
+// caller holds the mutex.
+func (b *Batcher) add(e event.Event) {
+	if b.pending == nil {
+		b.afterFunc(b.window, b.flush)
+	}
+	b.pending = append(b.pending, e)
+}
+
+// flush closes the current window. A lone event is sent as it is. The events
+// are removed from the log once next accepted them; otherwise they are kept
+// for the next window.
+func (b *Batcher) flush() {
+	b.mutex.Lock()
+	pending := b.pending
+	b.pending = nil
+	b.mutex.Unlock()
+
+	var err error
+	if len(pending) == 1 {
+		err = b.next.Handle(pending[0])
+	} else {
+		builder := digest.NewBuilder(b.topNames)
+		for _, e := range pending {
+			builder.Add(e)
+		}
+		err = b.next.HandleDigest(builder.Digest(b.window))
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if err != nil {
+		if b.log == nil {
+			logrus.Errorf("Error sending the digest of %d events: %v", len(pending), err)
+			return
+		}
+		logrus.Errorf("handler %s: error sending the digest of %d events, keeping them for the next window: %v", b.name, len(pending), err)
+		if b.pending == nil {
+			b.afterFunc(b.window, b.flush)
+		}
+		b.pending = append(pending, b.pending...)
+		return
+	}
+	if b.log != nil {
+		if err := b.log.Discard(len(pending)); err != nil {
+			logrus.Errorf("handler %s: cannot update the digest log: %v", b.name, err)
+		}
+	}
+}
+
+// innermost returns the handler behind any decorators wrapping h
+func innermost(h Handler) Handler {
+	for {
+		u, ok := h.(unwrapper)
+		if !ok {
+			return h
+		}
+		h = u.Unwrap()
+	}
+}
+
+// sendsDigests reports whether the handler behind any decorators wrapping h
+// can send digests. Decorators such as Retrier forward digests whether or not
+// the handler they wrap can send them.
+func sendsDigests(h Handler) bool {
+	_, ok := innermost(h).(digest.Handler)
+	return ok
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/digest"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+)
+
+// digestHandler records the events and digests it is given.
+type digestHandler struct {
+	recordingHandler
+	digests []*digest.Digest
+}
+
+func (h *digestHandler) HandleDigest(d *digest.Digest) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.digests = append(h.digests, d)
+	return nil
+}
+
+func TestBatcherSendsOneDigestPerWindow(t *testing.T) {
+	next := &digestHandler{}
+	h, err := NewBatcher("webhook", next, config.Digest{Window: time.Minute})
+	if err != nil {
+		t.Fatalf("NewBatcher(): %v", err)
+	}
+	b := h.(*Batcher)
+	var flush func()
+	b.afterFunc = func(_ time.Duration, f func()) { flush = f }
+
+	for i := 0; i < 200; i++ {
+		b.Handle(event.Event{Namespace: "prod", Kind: "Pod", Reason: "Created", Name: fmt.Sprintf("api-%d", i)})
+	}
+	if len(next.recorded()) != 0 || len(next.digests) != 0 {
+		t.Fatal("Batcher sent something before the window closed")
+	}
+	flush()
+
+	if len(next.digests) != 1 || next.digests[0].Total != 200 || len(next.recorded()) != 0 {
+		t.Fatalf("got digests %+v and events %v, want one digest of 200 events", next.digests, next.recorded())
+	}
+
+	// A window holding a single event sends it as it is.
+	b.Handle(event.Event{Kind: "Node", Name: "node-1", Reason: "Updated"})
+	flush()
+	if events := next.recorded(); len(events) != 1 || events[0].Name != "node-1" || len(next.digests) != 1 {
+		t.Errorf("got digests %+v and events %v, want the lone event", next.digests, events)
+	}
+}
+
+func TestNewBatcher(t *testing.T) {
+	plain := &recordingHandler{}
+	if h, err := NewBatcher("webhook", plain, config.Digest{}); err != nil || h != Handler(plain) {
+		t.Errorf("NewBatcher() without a window = %v, %v, want the handler itself", h, err)
+	}
+	if _, err := NewBatcher("webhook", plain, config.Digest{Window: time.Minute}); err == nil {
+		t.Error("NewBatcher() accepted a handler that cannot send digests")
+	}
+	if _, err := NewBatcher("webhook", &digestHandler{}, config.Digest{Window: -time.Minute}); err == nil {
+		t.Error("NewBatcher() accepted a negative window")
+	}
+}
+
+// newTestBatcher returns a batcher sending to an outbox in dir, along with
+// the function closing its window once opened.
+func newTestBatcher(t *testing.T, dir string, next *digestHandler) (*Batcher, *func()) {
+	t.Helper()
+
+	h, err := NewBatcher("webhook", NewOutbox("webhook", next, nil), config.Digest{Window: time.Minute})
+	if err != nil {
+		t.Fatalf("NewBatcher(): %v", err)
+	}
+	b := h.(*Batcher)
+	flush := new(func())
+	b.afterFunc = func(_ time.Duration, f func()) { *flush = f }
+	if err := b.Init(&config.Config{Outbox: config.Outbox{Dir: dir}}); err != nil {
+		t.Fatalf("Init(): %v", err)
+	}
+	return b, flush
+}
+
+func TestBatcherWindowSurvivesRestarts(t *testing.T) {
+	dir := t.TempDir()
+
+	b, _ := newTestBatcher(t, dir, &digestHandler{})
+	for i := 0; i < 3; i++ {
+		if err := b.Handle(event.Event{Namespace: "prod", Kind: "Pod", Reason: "Created", Name: fmt.Sprintf("api-%d", i)}); err != nil {
+			t.Fatalf("Handle(): %v", err)
+		}
+	}
+	// kubewatch restarts before the window closes.
+
+	next := &digestHandler{}
+	_, flush := newTestBatcher(t, dir, next)
+	if *flush == nil {
+		t.Fatal("Init() did not open a window for the events left behind")
+	}
+	(*flush)()
+
+	var digests []*digest.Digest
+	for deadline := time.Now().Add(5 * time.Second); len(digests) == 0 && time.Now().Before(deadline); {
+		time.Sleep(5 * time.Millisecond)
+		next.mutex.Lock()
+		digests = next.digests
+		next.mutex.Unlock()
+	}
+	if len(digests) != 1 || digests[0].Total != 3 {
+		t.Fatalf("got digests %+v, want one digest of the 3 events", digests)
+	}
+
+	// Once the digest is in the outbox, the events are not sent again.
+	if _, flush := newTestBatcher(t, dir, &digestHandler{}); *flush != nil {
+		t.Error("Init() opened a window for events already sent")
+	}
+}
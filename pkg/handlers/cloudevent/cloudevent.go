@@ -27,10 +27,11 @@ import (
 	"time"
 
 	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/diff"
 	"github.com/bitnami-labs/kubewatch/pkg/event"
-	"github.com/bitnami-labs/kubewatch/pkg/filter"
-	"github.com/bitnami-labs/kubewatch/pkg/metrics"
+	"github.com/bitnami-labs/kubewatch/pkg/message"
 	"github.com/bitnami-labs/kubewatch/pkg/redact"
+	"github.com/bitnami-labs/kubewatch/pkg/retry"
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -52,7 +53,8 @@ type CloudEvent struct {
 	Url       string
 	StartTime uint64
 	Counter   uint64
-	Filter    *filter.Filter
+	client    *http.Client
+	template  *message.Template
 }
 
 type CloudEventMessage struct {
@@ -75,13 +77,14 @@ type CloudEventMessageData struct {
 	ApiVersion  string         `json:"apiVersion"`
 	Obj         runtime.Object `json:"obj"`
 	OldObj      runtime.Object `json:"oldObj"`
+	Changes     []diff.Change  `json:"changes,omitempty"`
 }
 
 func (m *CloudEvent) Init(c *config.Config) error {
 	m.Url = c.Handler.CloudEvent.Url
 	m.StartTime = uint64(time.Now().Unix())
 	m.Counter = 0
-	m.Filter = filter.NewFilter()
+	m.client = &http.Client{Timeout: c.Limits.Timeout}
 
 	if m.Url == "" {
 		m.Url = os.Getenv("KW_CLOUDEVENT_URL")
@@ -91,42 +94,26 @@ func (m *CloudEvent) Init(c *config.Config) error {
 		return fmt.Errorf(cloudEventErrMsg, "Missing cloudevent url")
 	}
 
-	return nil
-}
-
-func (m *CloudEvent) Handle(e event.Event) {
-	// Apply filtering if enabled
-	if !m.Filter.ShouldSendEvent(e) {
-		logrus.Debugf("Event filtered out - Kind: %s, Reason: %s, Name: %s", e.Kind, e.Reason, e.Name)
-		return
-	}
-
-	// Increment the sent metrics counter
-	// Map event.Reason to eventType for consistency with the total metrics
-	eventType := "unknown"
-	switch e.Reason {
-	case "Created":
-		eventType = "create"
-	case "Updated":
-		eventType = "update"
-	case "Deleted":
-		eventType = "delete"
+	template, err := message.Parse(c.Handler.CloudEvent.Template)
+	if err != nil {
+		return err
 	}
+	m.template = template
 
-	if metrics.EventsSentTotal != nil {
-		metrics.EventsSentTotal.WithLabelValues(e.Kind, eventType).Inc()
-	}
+	return nil
+}
 
+func (m *CloudEvent) Handle(e event.Event) error {
 	m.Counter++ // TODO: do we have to worry about threadsafety here?
 	message := m.prepareMessage(e)
 
 	err := m.postMessage(message)
 	if err != nil {
-		logrus.Printf("%s\n", err)
-		return
+		return err
 	}
 
 	logrus.Printf("Message successfully sent to %s at %s ", m.Url, time.Now())
+	return nil
 }
 
 func (m *CloudEvent) prepareMessage(e event.Event) *CloudEventMessage {
@@ -142,12 +129,13 @@ func (m *CloudEvent) prepareMessage(e event.Event) *CloudEventMessage {
 			Kind:        e.Kind,
 			ApiVersion:  e.ApiVersion,
 			ClusterUid:  "TODO",
-			Description: e.Message(),
+			Description: m.template.Text(e),
 			// The controller already redacts these, but this handler serializes
 			// whole objects to an off-cluster receiver, so it redacts again
 			// rather than trusting its caller.
-			Obj:    redact.Object(e.Obj),
-			OldObj: redact.Object(e.OldObj),
+			Obj:     redact.Object(e.Obj),
+			OldObj:  redact.Object(e.OldObj),
+			Changes: e.Changes,
 		},
 	}
 }
@@ -168,7 +156,7 @@ func (m *CloudEvent) formatReason(e event.Event) string {
 func (m *CloudEvent) postMessage(webhookMessage *CloudEventMessage) error {
 	message, err := json.Marshal(webhookMessage)
 	if err != nil {
-		return err
+		return retry.Permanent(err)
 	}
 
 	// Defensive last pass over the actual wire bytes: catches Secrets the typed
@@ -176,21 +164,24 @@ func (m *CloudEvent) postMessage(webhookMessage *CloudEventMessage) error {
 	// reach through `customresources` without enabling `resource.secret`.
 	message, err = redact.JSON(message)
 	if err != nil {
-		return fmt.Errorf("failed to redact outbound message, not sending it: %v", err)
+		return retry.Permanent(fmt.Errorf("failed to redact outbound message, not sending it: %v", err))
 	}
 
 	req, err := http.NewRequest("POST", m.Url, bytes.NewBuffer(message))
 	if err != nil {
-		return err
+		return retry.Permanent(err)
 	}
 	req.Header.Add("Content-Type", "application/json")
 
-	client := &http.Client{}
+	client := m.client
+	if client == nil {
+		client = &http.Client{}
+	}
 	resp, err := client.Do(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
-	return nil
+	return retry.CheckResponse(resp)
 }
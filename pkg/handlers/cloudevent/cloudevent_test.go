@@ -29,7 +29,6 @@ import (
 
 	"github.com/bitnami-labs/kubewatch/config"
 	"github.com/bitnami-labs/kubewatch/pkg/event"
-	"github.com/bitnami-labs/kubewatch/pkg/filter"
 	api_v1 "k8s.io/api/core/v1"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -76,7 +75,7 @@ func captureCloudEvents(t *testing.T) (*CloudEvent, *[]string) {
 	}))
 	t.Cleanup(server.Close)
 
-	return &CloudEvent{Url: server.URL, Filter: filter.NewFilter()}, &bodies
+	return &CloudEvent{Url: server.URL}, &bodies
 }
 
 func sentinelSecret(suffix string) *api_v1.Secret {
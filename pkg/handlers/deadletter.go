@@ -0,0 +1,126 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/deadletter"
+	"github.com/bitnami-labs/kubewatch/pkg/digest"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/retry"
+	"github.com/sirupsen/logrus"
+)
+
+// DeadLetter receives the messages handlers give up on, and appends them to
+// a file or hands them to another handler. A nil DeadLetter drops them.
+type DeadLetter struct {
+	file *deadletter.File
+	// name is the name of the handler receiving the messages
+	name    string
+	handler Handler
+}
+
+// NewDeadLetter returns the dead-letter destination configured by c, or nil
+// when there is none. A handler destination is looked up by Resolve once all
+// the handlers exist.
+func NewDeadLetter(c config.DeadLetter) (*DeadLetter, error) {
+	switch {
+	case c.File != "" && c.Handler != "":
+		return nil, fmt.Errorf("dead letters go to a file or to a handler, not both")
+	case c.File != "":
+		file, err := deadletter.NewFile(c.File)
+		if err != nil {
+			return nil, fmt.Errorf("dead-letter file: %v", err)
+		}
+		return &DeadLetter{file: file}, nil
+	case c.Handler != "":
+		return &DeadLetter{name: c.Handler}, nil
+	}
+	return nil, nil
+}
+
+// Resolve looks up the handler receiving the messages among the members of
+// group.
+func (d *DeadLetter) Resolve(group *Group) error {
+	if d == nil || d.name == "" {
+		return nil
+	}
+	handler, ok := group.Get(d.name)
+	if ok {
+		d.handler = handler
+		return nil
+	}
+	return fmt.Errorf("dead letters go to unknown handler %q, configured handlers are: %s", d.name, strings.Join(group.Names(), ", "))
+}
+
+// send hands a message the handler named handler gave up on to the
+// dead-letter destination, or logs it when there is none.
+func (d *DeadLetter) send(handler string, record outboxRecord, err error) {
+	letter := deadletter.Letter{
+		Time:     time.Now(),
+		Handler:  handler,
+		Event:    record.Event,
+		Digest:   record.Digest,
+		Error:    err.Error(),
+		Attempts: retry.Attempts(err),
+	}
+
+	if d == nil {
+		logrus.Errorf("handler %s: dropping %s after %d attempts: %v", handler, letter.Subject(), letter.Attempts, err)
+		return
+	}
+
+	var sendErr error
+	switch {
+	case d.file != nil:
+		sendErr = d.file.Append(letter)
+	case handler == d.name:
+		// The dead-letter handler failing its own messages must not loop.
+		sendErr = fmt.Errorf("the dead-letter handler cannot take its own messages")
+	case d.handler == nil:
+		sendErr = fmt.Errorf("dead-letter handler %q is not resolved", d.name)
+	default:
+		sendErr = d.handOver(letter)
+	}
+	if sendErr != nil {
+		logrus.Errorf("handler %s: dropping %s after %d attempts: %v (dead letter: %v)", handler, letter.Subject(), letter.Attempts, err, sendErr)
+		return
+	}
+	logrus.Warnf("handler %s: gave up on %s after %d attempts, sent it to the dead letters: %v", handler, letter.Subject(), letter.Attempts, err)
+}
+
+// handOver sends a letter to the dead-letter handler, with the failure of
+// the handler that gave up on it, so that it is not taken for a live one
+func (d *DeadLetter) handOver(l deadletter.Letter) error {
+	failure := &event.Failure{Handler: l.Handler, Error: l.Error, Attempts: l.Attempts}
+	if l.Event != nil {
+		e := *l.Event
+		e.Failure = failure
+		return d.handler.Handle(e)
+	}
+	dh, ok := d.handler.(digest.Handler)
+	if !ok || !sendsDigests(d.handler) {
+		return fmt.Errorf("%T handlers cannot send digests", innermost(d.handler))
+	}
+	dg := *l.Digest
+	dg.Failure = failure
+	return dh.HandleDigest(&dg)
+}
@@ -28,6 +28,8 @@ import (
 
 	"github.com/bitnami-labs/kubewatch/config"
 	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/message"
+	"github.com/bitnami-labs/kubewatch/pkg/retry"
 )
 
 var flockColors = map[string]string{
@@ -51,7 +53,9 @@ Command line flags will override environment variables
 // Flock handler implements handler.Handler interface,
 // Notify event to Flock channel
 type Flock struct {
-	Url string
+	Url      string
+	client   *http.Client
+	template *message.Template
 }
 
 // FlockMessage struct
@@ -82,21 +86,28 @@ func (f *Flock) Init(c *config.Config) error {
 	}
 
 	f.Url = url
+	f.client = &http.Client{Timeout: c.Limits.Timeout}
+
+	template, err := message.Parse(c.Handler.Flock.Template)
+	if err != nil {
+		return err
+	}
+	f.template = template
 
 	return checkMissingFlockVars(f)
 }
 
 // Handle handles an event.
-func (f *Flock) Handle(e event.Event) {
+func (f *Flock) Handle(e event.Event) error {
 	flockMessage := prepareFlockMessage(e, f)
 
-	err := postMessage(f.Url, flockMessage)
+	err := postMessage(f.client, f.Url, flockMessage)
 	if err != nil {
-		logrus.Printf("%s\n", err)
-		return
+		return err
 	}
 
 	logrus.Printf("Message successfully sent to channel %s at %s", f.Url, time.Now())
+	return nil
 }
 
 func checkMissingFlockVars(s *Flock) error {
@@ -113,30 +124,30 @@ func prepareFlockMessage(e event.Event, f *Flock) *FlockMessage {
 		Notification: "Kubewatch Alert",
 		Attachements: []FlockMessageAttachement{
 			{
-				Title: e.Message(),
+				Title: f.template.Text(e),
 				Color: flockColors[e.Status],
 			},
 		},
 	}
 }
 
-func postMessage(url string, flockMessage *FlockMessage) error {
+func postMessage(client *http.Client, url string, flockMessage *FlockMessage) error {
 	message, err := json.Marshal(flockMessage)
 	if err != nil {
-		return err
+		return retry.Permanent(err)
 	}
 
 	req, err := http.NewRequest("POST", url, bytes.NewBuffer(message))
 	if err != nil {
-		return err
+		return retry.Permanent(err)
 	}
 	req.Header.Add("Content-Type", "application/json")
 
-	client := &http.Client{}
-	_, err = client.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		return err
 	}
+	defer resp.Body.Close()
 
-	return nil
+	return retry.CheckResponse(resp)
 }
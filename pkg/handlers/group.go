@@ -0,0 +1,135 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/retry"
+)
+
+// Group handler implements Handler interface,
+// fan out each event to every member handler
+//
+// Members are called concurrently and each one is isolated from the others: a
+// member that panics is logged and skipped, so one broken destination never
+// keeps the event from reaching the rest.
+type Group struct {
+	members []member
+}
+
+type member struct {
+	name    string
+	handler Handler
+}
+
+// NewGroup returns an empty Group.
+func NewGroup() *Group {
+	return &Group{}
+}
+
+// Add appends a handler to the group under the given name. The name is only
+// used to tell members apart in logs.
+func (g *Group) Add(name string, h Handler) {
+	g.members = append(g.members, member{name: name, handler: h})
+}
+
+// Len returns the number of handlers in the group.
+func (g *Group) Len() int {
+	return len(g.members)
+}
+
+// Members returns the handlers in the group, in the order they were added.
+func (g *Group) Members() []Handler {
+	members := make([]Handler, 0, len(g.members))
+	for _, m := range g.members {
+		members = append(members, m.handler)
+	}
+	return members
+}
+
+// Init initializes every member handler and reports all failures at once.
+func (g *Group) Init(c *config.Config) error {
+	var errs []error
+	for _, m := range g.members {
+		if err := m.handler.Init(c); err != nil {
+			errs = append(errs, fmt.Errorf("handler %s: %v", m.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Handle hands the event to every member handler, and returns the errors of
+// the members that failed.
+func (g *Group) Handle(e event.Event) error {
+	return g.handleSelected(e, func(string) bool { return true })
+}
+
+// Has reports whether the group has a member with the given name.
+func (g *Group) Has(name string) bool {
+	_, ok := g.Get(name)
+	return ok
+}
+
+// Get returns the member with the given name.
+func (g *Group) Get(name string) (Handler, bool) {
+	for _, m := range g.members {
+		if m.name == name {
+			return m.handler, true
+		}
+	}
+	return nil, false
+}
+
+// Names returns the names of the handlers in the group.
+func (g *Group) Names() []string {
+	names := make([]string, 0, len(g.members))
+	for _, m := range g.members {
+		names = append(names, m.name)
+	}
+	return names
+}
+
+// handleSelected hands the event to the members selected by name. A member
+// failing or panicking does not keep the others from getting the event.
+func (g *Group) handleSelected(e event.Event, selected func(name string) bool) error {
+	var wg sync.WaitGroup
+	errs := make([]error, len(g.members))
+	for i, m := range g.members {
+		if !selected(m.name) {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, m member) {
+			defer wg.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					errs[i] = retry.Permanent(fmt.Errorf("handler %s panicked: %v", m.name, r))
+				}
+			}()
+			if err := m.handler.Handle(e); err != nil {
+				errs[i] = fmt.Errorf("handler %s: %w", m.name, err)
+			}
+		}(i, m)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+)
+
+// recordingHandler keeps the events it was given.
+type recordingHandler struct {
+	mutex   sync.Mutex
+	events  []event.Event
+	initErr error
+}
+
+func (h *recordingHandler) Init(*config.Config) error { return h.initErr }
+
+func (h *recordingHandler) Handle(e event.Event) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.events = append(h.events, e)
+	return nil
+}
+
+func (h *recordingHandler) recorded() []event.Event {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return append([]event.Event(nil), h.events...)
+}
+
+// panickingHandler stands in for a broken destination.
+type panickingHandler struct{}
+
+func (panickingHandler) Init(*config.Config) error { return nil }
+func (panickingHandler) Handle(event.Event) error  { panic("destination is broken") }
+
+func TestGroupFansOutToEveryMember(t *testing.T) {
+	slack, cloudevent := &recordingHandler{}, &recordingHandler{}
+
+	group := NewGroup()
+	group.Add("slack", slack)
+	group.Add("broken", panickingHandler{})
+	group.Add("cloudevent", cloudevent)
+
+	err := group.Handle(event.Event{Kind: "Pod", Name: "api", Reason: "Created"})
+	if err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Errorf("Handle() = %v, want the failure of the broken handler", err)
+	}
+
+	for name, h := range map[string]*recordingHandler{"slack": slack, "cloudevent": cloudevent} {
+		if got := h.recorded(); len(got) != 1 || got[0].Name != "api" {
+			t.Errorf("%s received %v, want the single api event", name, got)
+		}
+	}
+}
+
+func TestGroupInitReportsEveryFailure(t *testing.T) {
+	group := NewGroup()
+	group.Add("slack", &recordingHandler{initErr: errors.New("missing token")})
+	group.Add("webhook", &recordingHandler{})
+	group.Add("smtp", &recordingHandler{initErr: errors.New("missing smarthost")})
+
+	err := group.Init(&config.Config{})
+	if err == nil {
+		t.Fatal("Init() succeeded, want an error")
+	}
+	for _, want := range []string{"handler slack: missing token", "handler smtp: missing smarthost"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Init() error %q does not mention %q", err, want)
+		}
+	}
+
+	if err := NewGroup().Init(&config.Config{}); err != nil {
+		t.Errorf("empty group Init() = %v, want nil", err)
+	}
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/digest"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/metrics"
+	"github.com/bitnami-labs/kubewatch/pkg/retry"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+)
+
+// ErrBreakerOpen is returned without trying to deliver while a destination
+// is deemed unhealthy. It is not worth retrying before the breaker cooldown.
+var ErrBreakerOpen = errors.New("destination unhealthy, not sending")
+
+// Guard handler implements Handler interface,
+// rate limit the messages to one destination and stop sending to it for a
+// while after consecutive failures, as a circuit breaker
+type Guard struct {
+	name    string
+	next    Handler
+	limits  config.Limits
+	limiter *rate.Limiter
+	// now returns the current time; tests replace it to control time
+	now func() time.Time
+
+	mutex sync.Mutex
+	// failures counts the consecutive failed deliveries
+	failures int
+	// openedAt is when the breaker opened, zero while it is closed
+	openedAt time.Time
+	// probing is set while a delivery probes an unhealthy destination
+	probing bool
+}
+
+// NewGuard returns a handler limiting the deliveries of next, named name in
+// logs and metrics. The limits are read from the configuration by Init.
+func NewGuard(name string, next Handler) *Guard {
+	return &Guard{name: name, next: next, now: time.Now}
+}
+
+// Init reads the limits of the handler and initializes the handler behind
+// the guard with them, so that it applies the request timeout.
+func (g *Guard) Init(c *config.Config) error {
+	limits, err := c.Limits.For(g.name)
+	if err != nil {
+		return err
+	}
+	g.limits = limits
+	g.limiter = rate.NewLimiter(rate.Inf, limits.Burst)
+	if limits.Rate > 0 {
+		g.limiter.SetLimit(rate.Limit(limits.Rate))
+	}
+	metrics.DestinationHealthy.WithLabelValues(g.name).Set(1)
+
+	conf := *c
+	conf.Limits = limits
+	return g.next.Init(&conf)
+}
+
+// Handle sends the event once the rate limit allows it, unless the
+// destination is deemed unhealthy.
+func (g *Guard) Handle(e event.Event) error {
+	return g.do(func() error { return g.next.Handle(e) })
+}
+
+// HandleDigest sends the digest once the rate limit allows it, unless the
+// destination is deemed unhealthy.
+func (g *Guard) HandleDigest(d *digest.Digest) error {
+	next, ok := g.next.(digest.Handler)
+	if !ok {
+		return retry.Permanent(fmt.Errorf("%T handlers cannot send digests", g.next))
+	}
+	return g.do(func() error { return next.HandleDigest(d) })
+}
+
+// Unwrap returns the handler behind the guard.
+func (g *Guard) Unwrap() Handler {
+	return g.next
+}
+
+func (g *Guard) do(send func() error) error {
+	probe, err := g.allow()
+	if err != nil {
+		return err
+	}
+	if err = g.limiter.Wait(context.Background()); err == nil {
+		err = send()
+	}
+	g.record(err, probe)
+	return err
+}
+
+// allow returns ErrBreakerOpen while the breaker is open. Once the cooldown
+// is over, it lets a single delivery through to probe the destination.
+func (g *Guard) allow() (probe bool, err error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if g.openedAt.IsZero() {
+		return false, nil
+	}
+	if g.probing || g.now().Sub(g.openedAt) < g.limits.BreakerCooldown {
+		return false, ErrBreakerOpen
+	}
+	g.probing = true
+	return true, nil
+}
+
+// record counts the consecutive failed deliveries and opens or closes the
+// breaker. Permanent failures are answers of the destination, so they count
+// as successes here.
+func (g *Guard) record(err error, probe bool) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if probe {
+		g.probing = false
+	}
+	if err == nil || retry.IsPermanent(err) {
+		if !g.openedAt.IsZero() {
+			logrus.Infof("handler %s: destination healthy again, resuming deliveries", g.name)
+			metrics.DestinationHealthy.WithLabelValues(g.name).Set(1)
+			g.openedAt = time.Time{}
+		}
+		g.failures = 0
+		return
+	}
+
+	g.failures++
+	switch {
+	case probe:
+		// Wait for another cooldown, quietly.
+		g.openedAt = g.now()
+	case g.openedAt.IsZero() && g.failures >= g.limits.BreakerFailures:
+		logrus.Errorf("handler %s: destination unhealthy after %d consecutive failures, pausing deliveries for %s: %v", g.name, g.failures, g.limits.BreakerCooldown, err)
+		metrics.DestinationHealthy.WithLabelValues(g.name).Set(0)
+		g.openedAt = g.now()
+	}
+}
@@ -0,0 +1,242 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/deadletter"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/metrics"
+	"github.com/bitnami-labs/kubewatch/pkg/retry"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func newTestGuard(t *testing.T, name string, next Handler, limits config.Limits) (*Guard, *time.Time) {
+	t.Helper()
+
+	g := NewGuard(name, next)
+	if err := g.Init(&config.Config{Limits: limits}); err != nil {
+		t.Fatalf("Init(): %v", err)
+	}
+	now := time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC)
+	g.now = func() time.Time { return now }
+	return g, &now
+}
+
+func TestGuardOpensAfterConsecutiveFailures(t *testing.T) {
+	unavailable := errors.New("503 Service Unavailable")
+	next := &flakyHandler{errs: []error{unavailable, unavailable, unavailable, unavailable}}
+	g, now := newTestGuard(t, "unhealthy", next, config.Limits{BreakerFailures: 3, BreakerCooldown: time.Minute})
+	e := event.Event{Kind: "Pod", Name: "api"}
+	healthy := func() float64 { return testutil.ToFloat64(metrics.DestinationHealthy.WithLabelValues("unhealthy")) }
+
+	for i := 0; i < 3; i++ {
+		if err := g.Handle(e); err != unavailable {
+			t.Fatalf("Handle() = %v, want %v", err, unavailable)
+		}
+	}
+	if healthy() != 0 {
+		t.Errorf("destination healthy = %v after 3 failures, want 0", healthy())
+	}
+
+	// While the breaker is open, deliveries fail without reaching the
+	// destination.
+	if err := g.Handle(e); !errors.Is(err, ErrBreakerOpen) {
+		t.Errorf("Handle() with an open breaker = %v, want %v", err, ErrBreakerOpen)
+	}
+	if next.calls != 3 {
+		t.Fatalf("destination called %d times, want 3", next.calls)
+	}
+
+	// After the cooldown a failed probe opens the breaker again.
+	*now = now.Add(time.Minute)
+	if err := g.Handle(e); err != unavailable {
+		t.Fatalf("probe Handle() = %v, want %v", err, unavailable)
+	}
+	if err := g.Handle(e); !errors.Is(err, ErrBreakerOpen) || next.calls != 4 {
+		t.Fatalf("Handle() after a failed probe = %v with %d calls, want an error without a call", err, next.calls)
+	}
+
+	// A successful probe closes it.
+	*now = now.Add(time.Minute)
+	if err := g.Handle(e); err != nil {
+		t.Fatalf("probe Handle() = %v, want the destination to recover", err)
+	}
+	if err := g.Handle(e); err != nil || next.calls != 6 {
+		t.Errorf("Handle() after recovery = %v with %d calls, want 6 calls", err, next.calls)
+	}
+	if healthy() != 1 {
+		t.Errorf("destination healthy = %v after recovery, want 1", healthy())
+	}
+}
+
+func TestOpenBreakerIsWaitedOutRatherThanRetried(t *testing.T) {
+	next := &downHandler{failure: errors.New("503 Service Unavailable")}
+	path := filepath.Join(t.TempDir(), "dead-letters.jsonl")
+	deadLetter, err := NewDeadLetter(config.DeadLetter{File: path})
+	if err != nil {
+		t.Fatalf("NewDeadLetter(): %v", err)
+	}
+	g := NewGuard("breaker", next)
+	r := NewRetrier("breaker", g)
+	o := NewOutbox("breaker", r, deadLetter)
+	c := &config.Config{
+		Retry:  config.Retry{Attempts: 5},
+		Limits: config.Limits{BreakerFailures: 2, BreakerCooldown: time.Minute},
+		Outbox: config.Outbox{Dir: t.TempDir()},
+	}
+	if err := o.Init(c); err != nil {
+		t.Fatalf("Init(): %v", err)
+	}
+
+	// The outbox delivers from its own goroutine, which alone reads the
+	// clock and waits.
+	var mutex sync.Mutex
+	now := time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC)
+	var retries, waits int
+	g.now = func() time.Time {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return now
+	}
+	r.sleep = func(time.Duration) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		retries++
+	}
+	o.sleep = func(d time.Duration) {
+		mutex.Lock()
+		if d != time.Minute {
+			t.Errorf("outbox waited %s, want the cooldown", d)
+		}
+		waits++
+		now = now.Add(d)
+		mutex.Unlock()
+		time.Sleep(time.Millisecond)
+	}
+
+	// Two failures open the breaker; the event waits in the outbox, probing
+	// the destination after each cooldown, until it recovers.
+	if err := o.Handle(event.Event{Kind: "Pod", Name: "api"}); err != nil {
+		t.Fatalf("Handle(): %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	next.bringUp()
+	waitForDelivery(t, &next.recordingHandler, 1)
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if waits == 0 {
+		t.Error("outbox never waited for the cooldown")
+	}
+	// Each cooldown ends with a failed probe, which is retried once before
+	// the breaker opens again.
+	if retries > 2*waits+1 {
+		t.Errorf("got %d retries for %d cooldowns, want the open breaker not to be retried", retries, waits)
+	}
+	letters, err := deadletter.Read(path)
+	if err != nil || len(letters) != 0 {
+		t.Errorf("dead letters = %v, %v, want none", letters, err)
+	}
+}
+
+func TestOpenBreakerDoesNotHoldUpTheOtherHandlers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dead-letters.jsonl")
+	deadLetter, err := NewDeadLetter(config.DeadLetter{File: path})
+	if err != nil {
+		t.Fatalf("NewDeadLetter(): %v", err)
+	}
+	down := &downHandler{failure: errors.New("503 Service Unavailable")}
+	up := &recordingHandler{}
+	group := NewGroup()
+	for name, h := range map[string]Handler{"down": down, "up": up} {
+		group.Add(name, NewQueue(name, NewOutbox(name, NewRetrier(name, NewGuard(name, h)), deadLetter)))
+	}
+	c := &config.Config{
+		Queue:  config.Queue{Size: 1},
+		Retry:  config.Retry{Attempts: 1},
+		Limits: config.Limits{BreakerFailures: 1, BreakerCooldown: time.Hour},
+	}
+	if err := group.Init(c); err != nil {
+		t.Fatalf("Init(): %v", err)
+	}
+
+	// Without an outbox, the events for the unhealthy destination are
+	// dead-lettered rather than left to fill its queue, which would block
+	// the events of the healthy one.
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 20; i++ {
+			group.Handle(event.Event{Kind: "Pod", Name: fmt.Sprintf("api-%d", i)})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Handle() blocked on the unhealthy destination")
+	}
+	waitForDelivery(t, up, 20)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		letters, err := deadletter.Read(path)
+		if err == nil && len(letters) == 20 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d dead letters, %v, want the 20 events of the unhealthy destination", len(letters), err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestGuardIgnoresPermanentFailures(t *testing.T) {
+	rejected := retry.Permanent(errors.New("400 Bad Request"))
+	next := &flakyHandler{errs: []error{rejected, rejected, rejected}}
+	g, _ := newTestGuard(t, "rejecting", next, config.Limits{BreakerFailures: 2})
+
+	for i := 0; i < 4; i++ {
+		g.Handle(event.Event{Kind: "Pod", Name: "api"})
+	}
+	if next.calls != 4 {
+		t.Errorf("destination called %d times, want permanent failures to leave the breaker closed", next.calls)
+	}
+}
+
+func TestGuardRateLimits(t *testing.T) {
+	next := &flakyHandler{}
+	g, _ := newTestGuard(t, "limited", next, config.Limits{Rate: 20, Burst: 2})
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if err := g.Handle(event.Event{Kind: "Pod", Name: "api"}); err != nil {
+			t.Fatalf("Handle(): %v", err)
+		}
+	}
+	// Two messages go at once, the next two wait 50ms each.
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("4 messages sent in %s, want the rate limit to space them", elapsed)
+	}
+}
@@ -19,6 +19,7 @@ package handlers
 import (
 	"github.com/bitnami-labs/kubewatch/config"
 	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers/cloudevent"
 	"github.com/bitnami-labs/kubewatch/pkg/handlers/flock"
 	"github.com/bitnami-labs/kubewatch/pkg/handlers/hipchat"
 	"github.com/bitnami-labs/kubewatch/pkg/handlers/lark"
@@ -31,10 +32,12 @@ import (
 )
 
 // Handler is implemented by any handler.
-// The Handle method is used to process event
+// The Handle method is used to process event. It returns an error when the
+// event could not be delivered; errors marked with retry.Permanent are not
+// worth sending the event again.
 type Handler interface {
 	Init(c *config.Config) error
-	Handle(e event.Event)
+	Handle(e event.Event) error
 }
 
 // Map maps each event handler function to a name for easily lookup
@@ -46,6 +49,7 @@ var Map = map[string]interface{}{
 	"mattermost":   &mattermost.Mattermost{},
 	"flock":        &flock.Flock{},
 	"webhook":      &webhook.Webhook{},
+	"cloudevent":   &cloudevent.CloudEvent{},
 	"ms-teams":     &msteam.MSTeams{},
 	"smtp":         &smtp.SMTP{},
 	"lark":         &lark.Webhook{},
@@ -63,5 +67,6 @@ func (d *Default) Init(c *config.Config) error {
 }
 
 // Handle handles an event.
-func (d *Default) Handle(e event.Event) {
+func (d *Default) Handle(e event.Event) error {
+	return nil
 }
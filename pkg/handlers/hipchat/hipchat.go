@@ -19,6 +19,7 @@ package hipchat
 import (
 	"fmt"
 	"github.com/sirupsen/logrus"
+	"net/http"
 	"os"
 
 	hipchat "github.com/tbruyelle/hipchat-go/hipchat"
@@ -27,6 +28,8 @@ import (
 
 	"github.com/bitnami-labs/kubewatch/config"
 	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/message"
+	"github.com/bitnami-labs/kubewatch/pkg/retry"
 )
 
 var hipchatColors = map[string]hipchat.Color{
@@ -52,9 +55,11 @@ Command line flags will override environment variables
 // Hipchat handler implements handler.Handler interface,
 // Notify event to hipchat room
 type Hipchat struct {
-	Token string
-	Room  string
-	Url   string
+	Token    string
+	Room     string
+	Url      string
+	client   *http.Client
+	template *message.Template
 }
 
 // Init prepares hipchat configuration
@@ -78,30 +83,41 @@ func (s *Hipchat) Init(c *config.Config) error {
 	s.Token = token
 	s.Room = room
 	s.Url = url
+	s.client = &http.Client{Timeout: c.Limits.Timeout}
+
+	template, err := message.Parse(c.Handler.Hipchat.Template)
+	if err != nil {
+		return err
+	}
+	s.template = template
 
 	return checkMissingHipchatVars(s)
 }
 
 // Handle handles the notification.
-func (s *Hipchat) Handle(e event.Event) {
+func (s *Hipchat) Handle(e event.Event) error {
 	client := hipchat.NewClient(s.Token)
+	client.SetHTTPClient(s.client)
 	if s.Url != "" {
 		baseUrl, err := url.Parse(s.Url)
 		if err != nil {
-			panic(err)
+			return retry.Permanent(err)
 		}
 		client.BaseURL = baseUrl
 	}
 
-	notificationRequest := prepareHipchatNotification(e)
-	_, err := client.Room.Notification(s.Room, &notificationRequest)
+	notificationRequest := prepareHipchatNotification(e, s)
+	resp, err := client.Room.Notification(s.Room, &notificationRequest)
 
 	if err != nil {
-		logrus.Printf("%s\n", err)
-		return
+		if resp != nil && !retry.TransientStatus(resp.StatusCode) {
+			return retry.Permanent(err)
+		}
+		return err
 	}
 
 	logrus.Printf("Message successfully sent to room %s", s.Room)
+	return nil
 }
 
 func checkMissingHipchatVars(s *Hipchat) error {
@@ -112,9 +128,9 @@ func checkMissingHipchatVars(s *Hipchat) error {
 	return nil
 }
 
-func prepareHipchatNotification(e event.Event) hipchat.NotificationRequest {
+func prepareHipchatNotification(e event.Event, s *Hipchat) hipchat.NotificationRequest {
 	notification := hipchat.NotificationRequest{
-		Message: e.Message(),
+		Message: s.template.Text(e),
 		Notify:  true,
 		From:    "kubewatch",
 	}
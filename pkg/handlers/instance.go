@@ -0,0 +1,81 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/bitnami-labs/kubewatch/config"
+)
+
+// New returns a new, uninitialized handler of the given type. Every call
+// returns a distinct handler, so several instances of a type can be
+// initialized with different settings.
+func New(handlerType string) (Handler, error) {
+	proto, ok := Map[handlerType]
+	if !ok || handlerType == "default" {
+		return nil, fmt.Errorf("unknown handler type %q, supported types are: %s", handlerType, strings.Join(Types(), ", "))
+	}
+	return reflect.New(reflect.TypeOf(proto).Elem()).Interface().(Handler), nil
+}
+
+// Types returns the supported handler types, sorted.
+func Types() []string {
+	var types []string
+	for name := range Map {
+		if name != "default" {
+			types = append(types, name)
+		}
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Instance handler implements Handler interface,
+// wrap a handler configured by a named handler instance
+type Instance struct {
+	Handler
+	settings config.Handler
+}
+
+// NewInstance returns the handler described by a named handler instance.
+// The messages it gives up on go to deadLetter, which may be nil.
+func NewInstance(i config.HandlerInstance, deadLetter *DeadLetter) (*Instance, error) {
+	if err := config.CheckHandlerName(i.Name); err != nil {
+		return nil, fmt.Errorf("handler of type %q: %v", i.Type, err)
+	}
+	h, err := New(i.Type)
+	if err != nil {
+		return nil, fmt.Errorf("handler %q: %v", i.Name, err)
+	}
+	h, err = NewBatcher(i.Name, NewOutbox(i.Name, NewRetrier(i.Name, NewGuard(i.Name, h)), deadLetter), i.Handler.DigestFor(i.Type))
+	if err != nil {
+		return nil, fmt.Errorf("handler %q: %v", i.Name, err)
+	}
+	return &Instance{Handler: NewQueue(i.Name, h), settings: i.Handler}, nil
+}
+
+// Init initializes the wrapped handler with the instance's own handler
+// settings in place of the global ones.
+func (i *Instance) Init(c *config.Config) error {
+	conf := *c
+	conf.Handler = i.settings
+	return i.Handler.Init(&conf)
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"testing"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers/slack"
+)
+
+func TestNewReturnsDistinctHandlers(t *testing.T) {
+	a, err := New("slack")
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+	b, err := New("slack")
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+	if a == b {
+		t.Error("New() returned the same handler twice")
+	}
+
+	if _, err := New("pagerduty"); err == nil {
+		t.Error("New() accepted an unknown handler type")
+	}
+}
+
+func TestInstancesUseTheirOwnSettings(t *testing.T) {
+	global := &config.Config{}
+	global.Handler.Slack = config.Slack{Token: "global-token", Channel: "global"}
+
+	var instances []*Instance
+	for _, channel := range []string{"team-a", "team-b"} {
+		i := config.HandlerInstance{Name: channel + "-slack", Type: "slack"}
+		i.Handler.Slack = config.Slack{Token: channel + "-token", Channel: channel}
+
+		instance, err := NewInstance(i, nil)
+		if err != nil {
+			t.Fatalf("NewInstance(): %v", err)
+		}
+		if err := instance.Init(global); err != nil {
+			t.Fatalf("Init(): %v", err)
+		}
+		instances = append(instances, instance)
+	}
+
+	for i, channel := range []string{"team-a", "team-b"} {
+		s := innermost(instances[i].Handler).(*slack.Slack)
+		if s.Channel != channel || s.Token != channel+"-token" {
+			t.Errorf("instance %d posts to %q with %q, want %q with %q", i, s.Channel, s.Token, channel, channel+"-token")
+		}
+	}
+	if global.Handler.Slack.Channel != "global" {
+		t.Errorf("instance Init changed the global config: %+v", global.Handler.Slack)
+	}
+}
+
+func TestNewInstanceRequiresName(t *testing.T) {
+	for _, name := range []string{"", "../x", "a/b"} {
+		if _, err := NewInstance(config.HandlerInstance{Name: name, Type: "slack"}, nil); err == nil {
+			t.Errorf("NewInstance() accepted an instance named %q", name)
+		}
+	}
+}
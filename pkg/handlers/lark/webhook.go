@@ -27,7 +27,10 @@ import (
 	"time"
 
 	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/digest"
 	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/message"
+	"github.com/bitnami-labs/kubewatch/pkg/retry"
 )
 
 var webhookErrMsg = `
@@ -45,7 +48,9 @@ Command line flags will override environment variables
 // Webhook handler implements handler.Handler interface,
 // Notify event to Webhook channel
 type Webhook struct {
-	Url string
+	Url      string
+	client   *http.Client
+	template *message.Template
 }
 
 // TextMessage for messages
@@ -65,19 +70,36 @@ func (m *Webhook) Init(c *config.Config) error {
 		url = os.Getenv("KW_LARK_WEBHOOK_URL")
 	}
 	m.Url = url
+	m.client = &http.Client{Timeout: c.Limits.Timeout}
+	template, err := message.Parse(c.Handler.Lark.Template)
+	if err != nil {
+		return err
+	}
+	m.template = template
+
 	return checkMissingWebhookVars(m)
 }
 
 // Handle handles an event.
-func (m *Webhook) Handle(e event.Event) {
-	webhookMessage := prepareWebhookMessage(e, m)
+func (m *Webhook) Handle(e event.Event) error {
+	return m.post(prepareWebhookMessage(e, m))
+}
 
-	err := postMessage(m.Url, webhookMessage)
+// HandleDigest sends a digest of several events as one message.
+func (m *Webhook) HandleDigest(d *digest.Digest) error {
+	return m.post(&TextMessage{
+		MsgType: "text",
+		Content: &TextContent{Text: d.Title() + "\n" + d.Text()},
+	})
+}
+
+func (m *Webhook) post(webhookMessage *TextMessage) error {
+	err := postMessage(m.client, m.Url, webhookMessage)
 	if err != nil {
-		logrus.Printf("%s\n", err)
-		return
+		return err
 	}
 	logrus.Printf("Message successfully sent to lark webhook: %s at %s ", m.Url, time.Now())
+	return nil
 }
 
 func checkMissingWebhookVars(s *Webhook) error {
@@ -90,25 +112,25 @@ func checkMissingWebhookVars(s *Webhook) error {
 func prepareWebhookMessage(e event.Event, m *Webhook) *TextMessage {
 	return &TextMessage{
 		MsgType: "text",
-		Content: &TextContent{Text: e.Message()},
+		Content: &TextContent{Text: m.template.Text(e)},
 	}
 }
 
-func postMessage(url string, textMessage *TextMessage) error {
+func postMessage(client *http.Client, url string, textMessage *TextMessage) error {
 	message, err := json.Marshal(textMessage)
 	if err != nil {
-		return err
+		return retry.Permanent(err)
 	}
 	req, err := http.NewRequest("POST", url, bytes.NewBuffer(message))
 	if err != nil {
-		return err
+		return retry.Permanent(err)
 	}
 	req.Header.Add("Content-Type", "application/json")
 
-	client := &http.Client{}
-	_, err = client.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		return err
 	}
-	return nil
+	defer resp.Body.Close()
+	return retry.CheckResponse(resp)
 }
@@ -27,7 +27,10 @@ import (
 	"time"
 
 	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/digest"
 	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/message"
+	"github.com/bitnami-labs/kubewatch/pkg/retry"
 )
 
 var mattermostColors = map[string]string{
@@ -56,6 +59,8 @@ type Mattermost struct {
 	Channel  string
 	Url      string
 	Username string
+	client   *http.Client
+	template *message.Template
 }
 
 // MattermostMessage struct for messages
@@ -94,21 +99,35 @@ func (m *Mattermost) Init(c *config.Config) error {
 	m.Channel = channel
 	m.Url = url
 	m.Username = username
+	m.client = &http.Client{Timeout: c.Limits.Timeout}
+
+	template, err := message.Parse(c.Handler.Mattermost.Template)
+	if err != nil {
+		return err
+	}
+	m.template = template
 
 	return checkMissingMattermostVars(m)
 }
 
 // Handle handles an event.
-func (m *Mattermost) Handle(e event.Event) {
-	mattermostMessage := prepareMattermostMessage(e, m)
+func (m *Mattermost) Handle(e event.Event) error {
+	return m.post(prepareMattermostMessage(e, m))
+}
+
+// HandleDigest sends a digest of several events as one message.
+func (m *Mattermost) HandleDigest(d *digest.Digest) error {
+	return m.post(prepareMattermostDigestMessage(d, m))
+}
 
-	err := postMessage(m.Url, mattermostMessage)
+func (m *Mattermost) post(mattermostMessage *MattermostMessage) error {
+	err := postMessage(m.client, m.Url, mattermostMessage)
 	if err != nil {
-		logrus.Printf("%s\n", err)
-		return
+		return err
 	}
 
 	logrus.Printf("Message successfully sent to channel %s at %s", m.Channel, time.Now())
+	return nil
 }
 
 func checkMissingMattermostVars(s *Mattermost) error {
@@ -126,30 +145,45 @@ func prepareMattermostMessage(e event.Event, m *Mattermost) *MattermostMessage {
 		IconUrl:  "https://raw.githubusercontent.com/kubernetes/kubernetes/master/logo/logo_with_border.png",
 		Attachements: []MattermostMessageAttachement{
 			{
-				Title: e.Message(),
+				Title: m.template.Text(e),
 				Color: mattermostColors[e.Status],
 			},
 		},
 	}
 }
 
-func postMessage(url string, mattermostMessage *MattermostMessage) error {
+func prepareMattermostDigestMessage(d *digest.Digest, m *Mattermost) *MattermostMessage {
+	return &MattermostMessage{
+		Channel:  m.Channel,
+		Username: m.Username,
+		IconUrl:  "https://raw.githubusercontent.com/kubernetes/kubernetes/master/logo/logo_with_border.png",
+		Text:     d.Title(),
+		Attachements: []MattermostMessageAttachement{
+			{
+				Title: d.Text(),
+				Color: mattermostColors[d.Status],
+			},
+		},
+	}
+}
+
+func postMessage(client *http.Client, url string, mattermostMessage *MattermostMessage) error {
 	message, err := json.Marshal(mattermostMessage)
 	if err != nil {
-		return err
+		return retry.Permanent(err)
 	}
 
 	req, err := http.NewRequest("POST", url, bytes.NewBuffer(message))
 	if err != nil {
-		return err
+		return retry.Permanent(err)
 	}
 	req.Header.Add("Content-Type", "application/json")
 
-	client := &http.Client{}
-	_, err = client.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		return err
 	}
+	defer resp.Body.Close()
 
-	return nil
+	return retry.CheckResponse(resp)
 }
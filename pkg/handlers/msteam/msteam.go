@@ -24,9 +24,13 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"strings"
 
 	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/digest"
 	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/message"
+	"github.com/bitnami-labs/kubewatch/pkg/retry"
 )
 
 var msteamsErrMsg = `
@@ -69,6 +73,7 @@ type TeamsMessageCard struct {
 // Each element of AlertWebHook.Alerts will the number of elements of TeamsMessageCard.Sections to create
 type TeamsMessageCardSection struct {
 	ActivityTitle string                         `json:"activityTitle"`
+	Text          string                         `json:"text,omitempty"`
 	Facts         []TeamsMessageCardSectionFacts `json:"facts"`
 	Markdown      bool                           `json:"markdown"`
 }
@@ -84,15 +89,21 @@ type TeamsMessageCardSectionFacts struct {
 type MSTeams struct {
 	// TeamsWebhookURL is the webhook url of the Teams connector
 	TeamsWebhookURL string
+	client          *http.Client
+	template        *message.Template
 }
 
 // sendCard sends the JSON Encoded TeamsMessageCard to the webhook URL
 func sendCard(ms *MSTeams, card *TeamsMessageCard) (*http.Response, error) {
 	buffer := new(bytes.Buffer)
 	if err := json.NewEncoder(buffer).Encode(card); err != nil {
-		return nil, fmt.Errorf("Failed encoding message card: %v", err)
+		return nil, retry.Permanent(fmt.Errorf("Failed encoding message card: %v", err))
 	}
-	res, err := http.Post(ms.TeamsWebhookURL, "application/json", buffer)
+	client := ms.client
+	if client == nil {
+		client = &http.Client{}
+	}
+	res, err := client.Post(ms.TeamsWebhookURL, "application/json", buffer)
 	if err != nil {
 		return nil, fmt.Errorf("Failed sending to webhook url %s. Got the error: %v",
 			ms.TeamsWebhookURL, err)
@@ -102,8 +113,12 @@ func sendCard(ms *MSTeams, card *TeamsMessageCard) (*http.Response, error) {
 		if err != nil {
 			return nil, fmt.Errorf("Failed reading Teams http response: %v", err)
 		}
-		return nil, fmt.Errorf("Failed sending to the Teams Channel. Teams http response: %s, %s",
+		err = fmt.Errorf("Failed sending to the Teams Channel. Teams http response: %s, %s",
 			res.Status, string(resMessage))
+		if !retry.TransientStatus(res.StatusCode) {
+			return nil, retry.Permanent(err)
+		}
+		return nil, err
 	}
 	if err := res.Body.Close(); err != nil {
 		return nil, err
@@ -123,12 +138,19 @@ func (ms *MSTeams) Init(c *config.Config) error {
 		return fmt.Errorf(msteamsErrMsg, "Missing MS teams webhook URL")
 	}
 
+	template, err := message.Parse(c.Handler.MSTeams.Template)
+	if err != nil {
+		return err
+	}
+
 	ms.TeamsWebhookURL = webhookURL
+	ms.template = template
+	ms.client = &http.Client{Timeout: c.Limits.Timeout}
 	return nil
 }
 
 // Handle handles notification.
-func (ms *MSTeams) Handle(e event.Event) {
+func (ms *MSTeams) Handle(e event.Event) error {
 	card := &TeamsMessageCard{
 		Type:    messageType,
 		Context: context,
@@ -140,14 +162,44 @@ func (ms *MSTeams) Handle(e event.Event) {
 	card.ThemeColor = msTeamsColors[e.Status]
 
 	var s TeamsMessageCardSection
-	s.ActivityTitle = e.Message()
+	s.ActivityTitle = ms.template.Text(e)
+	if !ms.template.Custom() {
+		// Teams markdown needs a blank line to break lines
+		s.Text = strings.ReplaceAll(e.ChangesMessage(), "\n", "\n\n")
+	}
 	s.Markdown = true
 	card.Sections = append(card.Sections, s)
 
+	return ms.send(card)
+}
+
+// HandleDigest sends a digest of several events as one card, with a section
+// per group of events.
+func (ms *MSTeams) HandleDigest(d *digest.Digest) error {
+	card := &TeamsMessageCard{
+		Type:       messageType,
+		Context:    context,
+		Title:      "kubewatch",
+		Summary:    d.Title(),
+		Text:       d.Title(),
+		ThemeColor: msTeamsColors[d.Status],
+	}
+
+	// Teams markdown needs a blank line to break lines
+	card.Sections = append(card.Sections, TeamsMessageCardSection{
+		ActivityTitle: "Events",
+		Text:          strings.ReplaceAll(d.Text(), "\n", "\n\n"),
+		Markdown:      true,
+	})
+
+	return ms.send(card)
+}
+
+func (ms *MSTeams) send(card *TeamsMessageCard) error {
 	if _, err := sendCard(ms, card); err != nil {
-		logrus.Printf("%s\n", err)
-		return
+		return err
 	}
 
 	logrus.Printf("Message successfully sent to MS Teams")
+	return nil
 }
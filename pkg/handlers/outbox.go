@@ -0,0 +1,221 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/deadletter"
+	"github.com/bitnami-labs/kubewatch/pkg/digest"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/metrics"
+	"github.com/bitnami-labs/kubewatch/pkg/outbox"
+	"github.com/bitnami-labs/kubewatch/pkg/retry"
+	"github.com/sirupsen/logrus"
+)
+
+// outboxRecord is a message waiting in an outbox: an event or a digest
+type outboxRecord struct {
+	Event  *event.Event   `json:"event,omitempty"`
+	Digest *digest.Digest `json:"digest,omitempty"`
+}
+
+// subject describes the message in logs, e.g. "Pod prod/api Created"
+func (r outboxRecord) subject() string {
+	letter := deadletter.Letter{Event: r.Event, Digest: r.Digest}
+	return letter.Subject()
+}
+
+// Outbox handler implements Handler interface,
+// keep the messages for one destination on disk until they are delivered,
+// and hand the ones given up on to the dead letters
+type Outbox struct {
+	name       string
+	next       Handler
+	deadLetter *DeadLetter
+	policy     retry.Policy
+	// cooldown is how long the messages of the outbox wait for an unhealthy
+	// destination
+	cooldown time.Duration
+	// sleep waits for d; tests replace it to control time
+	sleep func(d time.Duration)
+
+	log *outbox.Log
+	// wake tells the drain loop that a message was appended
+	wake chan struct{}
+}
+
+// NewOutbox returns a handler keeping the messages for next, named name, in
+// an outbox on disk when the configuration sets an outbox directory. The
+// messages next gives up on go to deadLetter, which may be nil.
+func NewOutbox(name string, next Handler, deadLetter *DeadLetter) *Outbox {
+	return &Outbox{name: name, next: next, deadLetter: deadLetter, sleep: time.Sleep, wake: make(chan struct{}, 1)}
+}
+
+// Init initializes the handler behind the outbox, then opens the outbox and
+// starts delivering the messages left in it by a previous run.
+func (o *Outbox) Init(c *config.Config) error {
+	if err := o.next.Init(c); err != nil {
+		return err
+	}
+	limits, err := c.Limits.For(o.name)
+	if err != nil {
+		return err
+	}
+	o.cooldown = limits.BreakerCooldown
+	if c.Outbox.Dir == "" {
+		return nil
+	}
+
+	policy, err := retry.NewPolicy(c.Retry)
+	if err != nil {
+		return err
+	}
+	o.policy = policy
+
+	log, err := outbox.Open(filepath.Join(c.Outbox.Dir, o.name), 0)
+	if err != nil {
+		return err
+	}
+	o.log = log
+	if pending := log.Len(); pending > 0 {
+		logrus.Infof("handler %s: delivering %d messages left in the outbox", o.name, pending)
+	}
+	metrics.OutboxPending.WithLabelValues(o.name).Set(float64(log.Len()))
+	go o.drain()
+	return nil
+}
+
+// Handle appends the event to the outbox, or sends it right away when there
+// is no outbox.
+func (o *Outbox) Handle(e event.Event) error {
+	return o.handle(outboxRecord{Event: &e})
+}
+
+// HandleDigest appends the digest to the outbox, or sends it right away when
+// there is no outbox.
+func (o *Outbox) HandleDigest(d *digest.Digest) error {
+	return o.handle(outboxRecord{Digest: d})
+}
+
+func (o *Outbox) handle(record outboxRecord) error {
+	if o.log != nil {
+		return o.append(record)
+	}
+	// The error goes back to the caller, so it is not logged here when there
+	// are no dead letters. Without an outbox to keep it in, a message for a
+	// destination whose breaker is open is given up on at once rather than
+	// holding up the queue of the handler, and the events of the others.
+	err := o.send(record)
+	if err != nil && o.deadLetter != nil {
+		o.deadLetter.send(o.name, record, err)
+	}
+	return err
+}
+
+// Unwrap returns the handler behind the outbox.
+func (o *Outbox) Unwrap() Handler {
+	return o.next
+}
+
+func (o *Outbox) append(record outboxRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return retry.Permanent(err)
+	}
+	if err := o.log.Append(data); err != nil {
+		return fmt.Errorf("cannot write to the outbox: %v", err)
+	}
+	metrics.OutboxPending.WithLabelValues(o.name).Inc()
+
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// drain sends the messages of the outbox in order. A message is removed once
+// delivered or failed permanently; otherwise it is tried again, and the ones
+// after it wait, until the destination recovers.
+func (o *Outbox) drain() {
+	failures, attempts := 0, 0
+	for {
+		data, ok, err := o.log.Peek()
+		if err != nil {
+			logrus.Errorf("handler %s: cannot read the outbox: %v", o.name, err)
+			o.sleep(o.policy.MaxBackoff)
+			continue
+		}
+		if !ok {
+			<-o.wake
+			continue
+		}
+
+		var record outboxRecord
+		if err = json.Unmarshal(data, &record); err != nil {
+			err = retry.Permanent(fmt.Errorf("invalid outbox record: %v", err))
+		} else {
+			err = o.send(record)
+		}
+		// The message stays in the outbox until the destination is deemed
+		// healthy again, without counting as a failure.
+		if errors.Is(err, ErrBreakerOpen) {
+			o.sleep(o.cooldown)
+			continue
+		}
+		attempts += retry.Attempts(err)
+		if err != nil && !retry.IsPermanent(err) {
+			failures++
+			backoff := o.policy.Backoff(failures)
+			logrus.Errorf("handler %s: delivery failed, keeping %d messages in the outbox and trying again in %s: %v", o.name, o.log.Len(), backoff.Round(time.Millisecond), err)
+			o.sleep(backoff)
+			continue
+		}
+		if err != nil {
+			o.deadLetter.send(o.name, record, &retry.GiveUpError{Attempts: attempts, Err: err})
+		}
+		failures, attempts = 0, 0
+
+		if err := o.log.Commit(); err != nil {
+			logrus.Errorf("handler %s: cannot update the outbox: %v", o.name, err)
+			o.sleep(o.policy.MaxBackoff)
+			continue
+		}
+		metrics.OutboxPending.WithLabelValues(o.name).Dec()
+	}
+}
+
+// send delivers a message to the handler behind the outbox
+func (o *Outbox) send(record outboxRecord) error {
+	switch {
+	case record.Event != nil:
+		return o.next.Handle(*record.Event)
+	case record.Digest != nil:
+		next, ok := o.next.(digest.Handler)
+		if !ok {
+			return retry.Permanent(fmt.Errorf("%T handlers cannot send digests", o.next))
+		}
+		return next.HandleDigest(record.Digest)
+	}
+	return retry.Permanent(fmt.Errorf("empty outbox record"))
+}
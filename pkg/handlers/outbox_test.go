@@ -0,0 +1,209 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/deadletter"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/outbox"
+	"github.com/bitnami-labs/kubewatch/pkg/retry"
+)
+
+// downHandler fails until it is brought up, and records what it delivers.
+type downHandler struct {
+	recordingHandler
+	up bool
+	// failure is the error returned while down
+	failure error
+}
+
+func (h *downHandler) Handle(e event.Event) error {
+	h.mutex.Lock()
+	up := h.up
+	h.mutex.Unlock()
+	if !up {
+		return h.failure
+	}
+	return h.recordingHandler.Handle(e)
+}
+
+func (h *downHandler) bringUp() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.up = true
+}
+
+func newTestOutbox(t *testing.T, dir string, next Handler, deadLetter *DeadLetter) *Outbox {
+	t.Helper()
+
+	o := NewOutbox("webhook", next, deadLetter)
+	o.sleep = func(time.Duration) { time.Sleep(time.Millisecond) }
+	if err := o.Init(&config.Config{Outbox: config.Outbox{Dir: dir}}); err != nil {
+		t.Fatalf("Init(): %v", err)
+	}
+	return o
+}
+
+func waitForDelivery(t *testing.T, h *recordingHandler, want int) []event.Event {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if got := h.recorded(); len(got) >= want {
+			return got
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("got %d events, want %d", len(h.recorded()), want)
+	return nil
+}
+
+func TestOutboxDeliversInOrderOnceTheDestinationRecovers(t *testing.T) {
+	next := &downHandler{failure: errors.New("connection refused")}
+	o := newTestOutbox(t, t.TempDir(), next, nil)
+
+	for i := 0; i < 5; i++ {
+		if err := o.Handle(event.Event{Kind: "Pod", Name: fmt.Sprintf("api-%d", i)}); err != nil {
+			t.Fatalf("Handle(): %v", err)
+		}
+	}
+	time.Sleep(20 * time.Millisecond)
+	if got := next.recorded(); len(got) != 0 {
+		t.Fatalf("delivered %v while the destination was down", got)
+	}
+
+	next.bringUp()
+	got := waitForDelivery(t, &next.recordingHandler, 5)
+	for i, e := range got {
+		if want := fmt.Sprintf("api-%d", i); e.Name != want {
+			t.Errorf("event %d is %s, want %s", i, e.Name, want)
+		}
+	}
+}
+
+func TestOutboxSurvivesRestarts(t *testing.T) {
+	dir := t.TempDir()
+
+	// A previous run left two events behind.
+	log, err := outbox.Open(filepath.Join(dir, "webhook"), 0)
+	if err != nil {
+		t.Fatalf("Open(): %v", err)
+	}
+	for _, reason := range []string{"Created", "Deleted"} {
+		data, _ := json.Marshal(outboxRecord{Event: &event.Event{Kind: "Pod", Name: "api", Reason: reason}})
+		if err := log.Append(data); err != nil {
+			t.Fatalf("Append(): %v", err)
+		}
+	}
+	log.Close()
+
+	next := &downHandler{up: true}
+	newTestOutbox(t, dir, next, nil)
+	got := waitForDelivery(t, &next.recordingHandler, 2)
+	if got[0].Reason != "Created" || got[1].Reason != "Deleted" {
+		t.Errorf("delivered %+v after a restart, want the two events in order", got)
+	}
+}
+
+func TestOutboxSendsPermanentFailuresToTheDeadLetters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "deadletter.jsonl")
+	deadLetter, err := NewDeadLetter(config.DeadLetter{File: path})
+	if err != nil {
+		t.Fatalf("NewDeadLetter(): %v", err)
+	}
+
+	rejected := &retry.GiveUpError{Attempts: 2, Err: retry.Permanent(errors.New("400 Bad Request"))}
+	o := newTestOutbox(t, t.TempDir(), &downHandler{failure: rejected}, deadLetter)
+	o.Handle(event.Event{Kind: "Pod", Namespace: "prod", Name: "rejected", Reason: "Created"})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for o.log.Len() > 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if o.log.Len() != 0 {
+		t.Fatalf("outbox holds %d messages, want the rejected one removed", o.log.Len())
+	}
+
+	letters, err := deadletter.Read(path)
+	if err != nil {
+		t.Fatalf("Read(): %v", err)
+	}
+	if len(letters) != 1 {
+		t.Fatalf("got %d dead letters, want 1", len(letters))
+	}
+	l := letters[0]
+	if l.Handler != "webhook" || l.Event == nil || l.Event.Name != "rejected" || l.Attempts != 2 || !strings.Contains(l.Error, "400 Bad Request") {
+		t.Errorf("dead letter %+v, want the rejected event with its error and attempts", l)
+	}
+}
+
+func TestDeadLetterHandler(t *testing.T) {
+	if _, err := NewDeadLetter(config.DeadLetter{File: "dead.jsonl", Handler: "archive"}); err == nil {
+		t.Error("NewDeadLetter() accepted both a file and a handler")
+	}
+
+	archive := &recordingHandler{}
+	group := NewGroup()
+	group.Add("archive", archive)
+	deadLetter, _ := NewDeadLetter(config.DeadLetter{Handler: "missing"})
+	if err := deadLetter.Resolve(group); err == nil {
+		t.Error("Resolve() accepted an unknown handler")
+	}
+	deadLetter, _ = NewDeadLetter(config.DeadLetter{Handler: "archive"})
+	if err := deadLetter.Resolve(group); err != nil {
+		t.Fatalf("Resolve(): %v", err)
+	}
+
+	o := NewOutbox("webhook", &downHandler{failure: errors.New("timeout")}, deadLetter)
+	if err := o.Init(&config.Config{}); err != nil {
+		t.Fatalf("Init(): %v", err)
+	}
+	if err := o.Handle(event.Event{Kind: "Pod", Name: "api"}); err == nil {
+		t.Error("Handle() hid the failure from its caller")
+	}
+	got := archive.recorded()
+	if len(got) != 1 || got[0].Name != "api" {
+		t.Fatalf("dead-letter handler received %v, want the failed event", got)
+	}
+	if f := got[0].Failure; f == nil || f.Handler != "webhook" || f.Attempts != 1 || !strings.Contains(f.Error, "timeout") {
+		t.Errorf("dead-letter handler received failure %+v, want the error and attempts of the webhook handler", f)
+	}
+	if msg := got[0].Message(); !strings.Contains(msg, "handler webhook gave up after 1 attempts: timeout") {
+		t.Errorf("dead-letter message = %q, want it to tell the failure", msg)
+	}
+}
+
+func TestOutboxWithoutDirectorySendsRightAway(t *testing.T) {
+	next := &recordingHandler{}
+	o := NewOutbox("webhook", next, nil)
+	if err := o.Init(&config.Config{}); err != nil {
+		t.Fatalf("Init(): %v", err)
+	}
+	o.Handle(event.Event{Kind: "Pod", Name: "api"})
+	if got := next.recorded(); len(got) != 1 {
+		t.Errorf("delivered %v, want the event sent right away", got)
+	}
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/digest"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/metrics"
+	"github.com/sirupsen/logrus"
+)
+
+// Queue handler implements Handler interface,
+// hand the messages for one destination over to workers sending them in the
+// background, so that a slow destination does not hold back the callers
+type Queue struct {
+	name     string
+	next     Handler
+	settings config.Queue
+	items    chan outboxRecord
+
+	mutex sync.Mutex
+	// dropped counts the messages dropped since the queue was last full
+	dropped int
+}
+
+// NewQueue returns a handler queueing the messages for next, named name in
+// logs and metrics. The queue settings are read from the configuration by
+// Init.
+func NewQueue(name string, next Handler) *Queue {
+	return &Queue{name: name, next: next}
+}
+
+// Init reads the queue settings, initializes the handler behind the queue
+// and starts the workers.
+func (q *Queue) Init(c *config.Config) error {
+	settings, err := c.Queue.For(q.name)
+	if err != nil {
+		return err
+	}
+	if err := q.next.Init(c); err != nil {
+		return err
+	}
+	q.settings = settings
+	if settings.Disabled {
+		return nil
+	}
+
+	q.items = make(chan outboxRecord, settings.Size)
+	metrics.QueueDepth.WithLabelValues(q.name).Set(0)
+	for i := 0; i < settings.Workers; i++ {
+		go q.work()
+	}
+	return nil
+}
+
+// Handle queues the event, or sends it right away when queues are disabled.
+func (q *Queue) Handle(e event.Event) error {
+	return q.handle(outboxRecord{Event: &e})
+}
+
+// HandleDigest queues the digest, or sends it right away when queues are
+// disabled.
+func (q *Queue) HandleDigest(d *digest.Digest) error {
+	return q.handle(outboxRecord{Digest: d})
+}
+
+// Unwrap returns the handler behind the queue.
+func (q *Queue) Unwrap() Handler {
+	return q.next
+}
+
+func (q *Queue) handle(record outboxRecord) error {
+	if q.items == nil {
+		return q.send(record)
+	}
+
+	switch q.settings.Policy {
+	case config.QueueDropNewest:
+		select {
+		case q.items <- record:
+			q.admitted()
+		default:
+			q.drop(record)
+		}
+	case config.QueueDropOldest:
+		for {
+			select {
+			case q.items <- record:
+				q.admitted()
+				metrics.QueueDepth.WithLabelValues(q.name).Set(float64(len(q.items)))
+				return nil
+			default:
+			}
+			select {
+			case oldest := <-q.items:
+				q.drop(oldest)
+			default:
+			}
+		}
+	default:
+		q.items <- record
+	}
+	metrics.QueueDepth.WithLabelValues(q.name).Set(float64(len(q.items)))
+	return nil
+}
+
+// drop counts a message dropped from a full queue, logging only the first
+// one until the queue has room again.
+func (q *Queue) drop(record outboxRecord) {
+	metrics.QueueDroppedTotal.WithLabelValues(q.name).Inc()
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.dropped++
+	if q.dropped == 1 {
+		logrus.Warnf("handler %s: queue full with %d messages, dropping %s (policy %s)", q.name, q.settings.Size, record.subject(), q.settings.Policy)
+	}
+}
+
+// admitted notes that the queue has room again
+func (q *Queue) admitted() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if q.dropped > 0 {
+		logrus.Warnf("handler %s: queue has room again after dropping %d messages", q.name, q.dropped)
+		q.dropped = 0
+	}
+}
+
+// work sends the queued messages until the queue is closed
+func (q *Queue) work() {
+	for record := range q.items {
+		metrics.QueueDepth.WithLabelValues(q.name).Set(float64(len(q.items)))
+		if err := q.safeSend(record); err != nil {
+			logrus.Errorf("handler %s: cannot send %s: %v", q.name, record.subject(), err)
+		}
+	}
+}
+
+// safeSend sends a message, turning a panic of the handler into an error so
+// that the worker keeps going.
+func (q *Queue) safeSend(record outboxRecord) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler panicked: %v", r)
+		}
+	}()
+	return q.send(record)
+}
+
+// send delivers a message to the handler behind the queue
+func (q *Queue) send(record outboxRecord) error {
+	if record.Digest != nil {
+		next, ok := q.next.(digest.Handler)
+		if !ok {
+			return fmt.Errorf("%T handlers cannot send digests", q.next)
+		}
+		return next.HandleDigest(record.Digest)
+	}
+	return q.next.Handle(*record.Event)
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+)
+
+// gatedHandler records the events it gets once the gate is open.
+type gatedHandler struct {
+	recordingHandler
+	started chan struct{}
+	gate    chan struct{}
+}
+
+func newGatedHandler() *gatedHandler {
+	return &gatedHandler{started: make(chan struct{}, 100), gate: make(chan struct{})}
+}
+
+func (h *gatedHandler) Handle(e event.Event) error {
+	h.started <- struct{}{}
+	<-h.gate
+	return h.recordingHandler.Handle(e)
+}
+
+func newTestQueue(t *testing.T, next Handler, c config.Queue) *Queue {
+	t.Helper()
+
+	q := NewQueue("queued", next)
+	if err := q.Init(&config.Config{Queue: c}); err != nil {
+		t.Fatalf("Init(): %v", err)
+	}
+	return q
+}
+
+func names(events []event.Event) []string {
+	var names []string
+	for _, e := range events {
+		names = append(names, e.Name)
+	}
+	return names
+}
+
+func TestQueueDoesNotWaitForDelivery(t *testing.T) {
+	next := newGatedHandler()
+	q := newTestQueue(t, next, config.Queue{})
+
+	done := make(chan error)
+	go func() { done <- q.Handle(event.Event{Kind: "Pod", Name: "api"}) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Handle(): %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Handle() waited for the destination")
+	}
+
+	close(next.gate)
+	waitForDelivery(t, &next.recordingHandler, 1)
+}
+
+func TestQueuePolicies(t *testing.T) {
+	var Tests = []struct {
+		policy string
+		want   []string
+	}{
+		{config.QueueDropNewest, []string{"api-0", "api-1"}},
+		{config.QueueDropOldest, []string{"api-0", "api-2"}},
+	}
+
+	for _, tt := range Tests {
+		next := newGatedHandler()
+		q := newTestQueue(t, next, config.Queue{Size: 1, Policy: tt.policy})
+
+		// The worker holds api-0 while api-1 fills the queue, so api-2
+		// finds it full.
+		q.Handle(event.Event{Kind: "Pod", Name: "api-0"})
+		<-next.started
+		q.Handle(event.Event{Kind: "Pod", Name: "api-1"})
+		q.Handle(event.Event{Kind: "Pod", Name: "api-2"})
+
+		close(next.gate)
+		if got := names(waitForDelivery(t, &next.recordingHandler, 2)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("policy %s delivered %v, want %v", tt.policy, got, tt.want)
+		}
+	}
+}
+
+func TestQueueKeepsOrderWithOneWorker(t *testing.T) {
+	next := &recordingHandler{}
+	q := newTestQueue(t, next, config.Queue{Size: 100})
+
+	var want []string
+	for i := 0; i < 50; i++ {
+		name := fmt.Sprintf("api-%d", i)
+		want = append(want, name)
+		q.Handle(event.Event{Kind: "Pod", Name: name})
+	}
+	if got := names(waitForDelivery(t, next, 50)); !reflect.DeepEqual(got, want) {
+		t.Errorf("delivered %v, want %v", got, want)
+	}
+}
+
+func TestQueueDisabledSendsRightAway(t *testing.T) {
+	failure := errors.New("connection refused")
+	q := newTestQueue(t, &flakyHandler{errs: []error{failure}}, config.Queue{Disabled: true})
+
+	if err := q.Handle(event.Event{Kind: "Pod", Name: "api"}); err != failure {
+		t.Errorf("Handle() = %v, want %v", err, failure)
+	}
+}
+
+func TestQueueRejectsUnknownPolicy(t *testing.T) {
+	q := NewQueue("queued", &recordingHandler{})
+	if err := q.Init(&config.Config{Queue: config.Queue{Policy: "drop-all"}}); err == nil {
+		t.Errorf("Init() with an unknown policy succeeded")
+	}
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/digest"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/metrics"
+	"github.com/bitnami-labs/kubewatch/pkg/retry"
+	"github.com/sirupsen/logrus"
+)
+
+// unwrapper is implemented by handlers decorating a single other handler
+type unwrapper interface {
+	Unwrap() Handler
+}
+
+// Retrier handler implements Handler interface,
+// send events to one destination again after transient failures
+type Retrier struct {
+	name   string
+	next   Handler
+	policy retry.Policy
+	// sleep waits for d; tests replace it to control time
+	sleep func(d time.Duration)
+}
+
+// NewRetrier returns a handler retrying the deliveries of next, named name in
+// logs and metrics. The retry policy is read from the configuration by Init.
+func NewRetrier(name string, next Handler) *Retrier {
+	return &Retrier{name: name, next: next, sleep: time.Sleep}
+}
+
+// Init reads the retry policy and initializes the handler behind the retrier.
+func (r *Retrier) Init(c *config.Config) error {
+	policy, err := retry.NewPolicy(c.Retry)
+	if err != nil {
+		return err
+	}
+	r.policy = policy
+	return r.next.Init(c)
+}
+
+// Handle sends the event, retrying transient failures.
+func (r *Retrier) Handle(e event.Event) error {
+	return r.do(fmt.Sprintf("%s %s", e.Kind, e.Name), func() error { return r.next.Handle(e) })
+}
+
+// HandleDigest sends the digest, retrying transient failures.
+func (r *Retrier) HandleDigest(d *digest.Digest) error {
+	next, ok := r.next.(digest.Handler)
+	if !ok {
+		return retry.Permanent(fmt.Errorf("%T handlers cannot send digests", r.next))
+	}
+	return r.do("digest", func() error { return next.HandleDigest(d) })
+}
+
+// Unwrap returns the handler behind the retrier.
+func (r *Retrier) Unwrap() Handler {
+	return r.next
+}
+
+// do calls send until it succeeds, fails permanently or runs out of
+// attempts, waiting longer and longer between attempts. An open breaker is
+// returned right away: it is no attempt, and the guard already reported it.
+func (r *Retrier) do(what string, send func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := send()
+		if err == nil || errors.Is(err, ErrBreakerOpen) {
+			return err
+		}
+		if retry.IsPermanent(err) {
+			metrics.DeliveryFailuresTotal.WithLabelValues(r.name, "permanent").Inc()
+			return &retry.GiveUpError{Attempts: attempt, Err: err}
+		}
+		if attempt >= r.policy.Attempts {
+			metrics.DeliveryFailuresTotal.WithLabelValues(r.name, "exhausted").Inc()
+			return &retry.GiveUpError{Attempts: attempt, Err: err}
+		}
+
+		backoff := r.policy.Backoff(attempt)
+		logrus.Warnf("handler %s: sending %s failed (attempt %d of %d), retrying in %s: %v", r.name, what, attempt, r.policy.Attempts, backoff.Round(time.Millisecond), err)
+		metrics.DeliveryRetriesTotal.WithLabelValues(r.name).Inc()
+		r.sleep(backoff)
+	}
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/retry"
+)
+
+// flakyHandler fails with the given errors, one per call, then succeeds.
+type flakyHandler struct {
+	errs  []error
+	calls int
+}
+
+func (h *flakyHandler) Init(*config.Config) error { return nil }
+
+func (h *flakyHandler) Handle(event.Event) error {
+	h.calls++
+	if len(h.errs) == 0 {
+		return nil
+	}
+	err := h.errs[0]
+	h.errs = h.errs[1:]
+	return err
+}
+
+func newTestRetrier(t *testing.T, next Handler, c config.Retry) (*Retrier, *[]time.Duration) {
+	t.Helper()
+
+	r := NewRetrier("flaky", next)
+	if err := r.Init(&config.Config{Retry: c}); err != nil {
+		t.Fatalf("Init(): %v", err)
+	}
+	var waits []time.Duration
+	r.sleep = func(d time.Duration) { waits = append(waits, d) }
+	return r, &waits
+}
+
+func TestRetrierRetriesTransientFailures(t *testing.T) {
+	unavailable := errors.New("503 Service Unavailable")
+	next := &flakyHandler{errs: []error{unavailable, unavailable}}
+	r, waits := newTestRetrier(t, next, config.Retry{InitialBackoff: time.Second})
+
+	if err := r.Handle(event.Event{Kind: "Pod", Name: "api"}); err != nil {
+		t.Fatalf("Handle() = %v, want the third attempt to succeed", err)
+	}
+	if next.calls != 3 || len(*waits) != 2 {
+		t.Fatalf("got %d attempts and waits %v, want 3 attempts and 2 waits", next.calls, *waits)
+	}
+	if (*waits)[1] < time.Second || (*waits)[1] > 2*time.Second {
+		t.Errorf("second wait = %s, want the backoff to double", (*waits)[1])
+	}
+}
+
+func TestRetrierGivesUp(t *testing.T) {
+	var Tests = []struct {
+		name      string
+		errs      []error
+		wantCalls int
+	}{
+		{"permanent failure", []error{retry.Permanent(errors.New("invalid_auth"))}, 1},
+		{"no attempt left", []error{errors.New("timeout"), errors.New("timeout"), errors.New("timeout")}, 3},
+	}
+
+	for _, tt := range Tests {
+		next := &flakyHandler{errs: tt.errs}
+		r, _ := newTestRetrier(t, next, config.Retry{Attempts: 3})
+
+		if err := r.Handle(event.Event{Kind: "Pod", Name: "api"}); err == nil {
+			t.Errorf("%s: Handle() succeeded, want an error", tt.name)
+		}
+		if next.calls != tt.wantCalls {
+			t.Errorf("%s: got %d attempts, want %d", tt.name, next.calls, tt.wantCalls)
+		}
+	}
+}
+
+func TestBatcherSeesThroughRetrier(t *testing.T) {
+	if _, err := NewBatcher("slack", NewRetrier("slack", &digestHandler{}), config.Digest{Window: time.Minute}); err != nil {
+		t.Errorf("NewBatcher() = %v for a handler able to send digests", err)
+	}
+	if _, err := NewBatcher("webhook", NewRetrier("webhook", &recordingHandler{}), config.Digest{Window: time.Minute}); err == nil {
+		t.Error("NewBatcher() accepted a retrier around a handler that cannot send digests")
+	}
+}
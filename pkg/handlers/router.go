@@ -0,0 +1,152 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/filter"
+	"k8s.io/apimachinery/pkg/api/meta"
+)
+
+// Router handler implements Handler interface,
+// send each event to the handlers its routing rules select
+//
+// Rules are evaluated in order, like Alertmanager routes: the first rule that
+// matches decides the destinations, unless it sets continue, in which case the
+// following rules are evaluated too and their handlers added. Events no rule
+// matched go to the default handlers.
+type Router struct {
+	group *Group
+	rules []config.Route
+	dflt  []string
+}
+
+// NewRouter returns a Router dispatching to the members of group according to
+// routes. Every handler name referenced by routes must be a member of group.
+func NewRouter(group *Group, routes config.Routes) (*Router, error) {
+	r := &Router{
+		group: group,
+		rules: routes.Rules,
+		dflt:  routes.Default,
+	}
+
+	checkNames := func(where string, names []string) error {
+		for _, name := range names {
+			if !group.Has(name) {
+				return fmt.Errorf("%s references unknown handler %q, configured handlers are: %s", where, name, strings.Join(group.Names(), ", "))
+			}
+		}
+		return nil
+	}
+	for i, rule := range routes.Rules {
+		if len(rule.Handlers) == 0 {
+			return nil, fmt.Errorf("route %d has no handlers", i+1)
+		}
+		if err := checkNames(fmt.Sprintf("route %d", i+1), rule.Handlers); err != nil {
+			return nil, err
+		}
+		for _, pattern := range rule.Match.Namespaces {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("route %d: invalid namespace pattern %q: %v", i+1, pattern, err)
+			}
+		}
+	}
+	if err := checkNames("default route", routes.Default); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Init initializes the handlers the router dispatches to.
+func (r *Router) Init(c *config.Config) error {
+	return r.group.Init(c)
+}
+
+// Handle sends the event to the handlers selected by the routing rules.
+func (r *Router) Handle(e event.Event) error {
+	targets := r.route(e)
+	if targets == nil {
+		return r.group.Handle(e)
+	}
+	return r.group.handleSelected(e, func(name string) bool { return targets[name] })
+}
+
+// route returns the names of the handlers the event goes to, or nil when it
+// goes to every handler.
+func (r *Router) route(e event.Event) map[string]bool {
+	var targets map[string]bool
+	for _, rule := range r.rules {
+		if !routeMatches(rule.Match, e) {
+			continue
+		}
+		if targets == nil {
+			targets = map[string]bool{}
+		}
+		for _, name := range rule.Handlers {
+			targets[name] = true
+		}
+		if !rule.Continue {
+			break
+		}
+	}
+
+	if targets != nil {
+		return targets
+	}
+	if len(r.dflt) == 0 {
+		return nil
+	}
+	targets = map[string]bool{}
+	for _, name := range r.dflt {
+		targets[name] = true
+	}
+	return targets
+}
+
+func routeMatches(m config.RouteMatch, e event.Event) bool {
+	if len(m.Namespaces) > 0 && !filter.MatchesAnyGlob(m.Namespaces, e.Namespace) {
+		return false
+	}
+	if len(m.Kinds) > 0 && !filter.ContainsFold(m.Kinds, e.Kind) {
+		return false
+	}
+	if len(m.Reasons) > 0 && !filter.ContainsFold(m.Reasons, e.Reason) {
+		return false
+	}
+	if len(m.Statuses) > 0 && !filter.ContainsFold(m.Statuses, e.Status) {
+		return false
+	}
+	if len(m.Labels) > 0 {
+		accessor, err := meta.Accessor(e.Obj)
+		if err != nil {
+			return false
+		}
+		labels := accessor.GetLabels()
+		for key, value := range m.Labels {
+			if got, ok := labels[key]; !ok || got != value {
+				return false
+			}
+		}
+	}
+	return true
+}
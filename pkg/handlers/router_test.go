@@ -0,0 +1,147 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"testing"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRouterRoutes(t *testing.T) {
+	routes := config.Routes{
+		Default: []string{"slack"},
+		Rules: []config.Route{
+			{
+				Match:    config.RouteMatch{Namespaces: []string{"prod*"}, Statuses: []string{"Danger"}},
+				Handlers: []string{"ms-teams"},
+				Continue: true,
+			},
+			{
+				Match:    config.RouteMatch{Labels: map[string]string{"team": "payments"}},
+				Handlers: []string{"webhook"},
+			},
+			{
+				Match:    config.RouteMatch{Kinds: []string{"deployment"}},
+				Handlers: []string{"cloudevent"},
+			},
+		},
+	}
+
+	pod := func(labels map[string]string) *api_v1.Pod {
+		return &api_v1.Pod{ObjectMeta: meta_v1.ObjectMeta{Labels: labels}}
+	}
+
+	var Tests = []struct {
+		name  string
+		event event.Event
+		want  []string
+	}{
+		{
+			"prod danger goes on call and continues",
+			event.Event{Namespace: "prod-eu", Kind: "Pod", Status: "Danger", Obj: pod(map[string]string{"team": "payments"})},
+			[]string{"ms-teams", "webhook"},
+		},
+		{
+			"prod danger without further match",
+			event.Event{Namespace: "prod", Kind: "Pod", Status: "Danger", Obj: pod(nil)},
+			[]string{"ms-teams"},
+		},
+		{
+			"first match without continue stops",
+			event.Event{Namespace: "dev", Kind: "Deployment", Status: "Normal", Obj: pod(map[string]string{"team": "payments"})},
+			[]string{"webhook"},
+		},
+		{
+			"kinds match case insensitively",
+			event.Event{Namespace: "dev", Kind: "Deployment", Status: "Normal"},
+			[]string{"cloudevent"},
+		},
+		{
+			"everything else goes to the default route",
+			event.Event{Namespace: "dev", Kind: "Pod", Status: "Warning", Obj: pod(nil)},
+			[]string{"slack"},
+		},
+	}
+
+	for _, tt := range Tests {
+		t.Run(tt.name, func(t *testing.T) {
+			group := NewGroup()
+			recorders := map[string]*recordingHandler{}
+			for _, name := range []string{"slack", "ms-teams", "webhook", "cloudevent"} {
+				recorders[name] = &recordingHandler{}
+				group.Add(name, recorders[name])
+			}
+			router, err := NewRouter(group, routes)
+			if err != nil {
+				t.Fatalf("NewRouter(): %v", err)
+			}
+
+			router.Handle(tt.event)
+
+			want := map[string]bool{}
+			for _, name := range tt.want {
+				want[name] = true
+			}
+			for name, h := range recorders {
+				if received := len(h.recorded()) > 0; received != want[name] {
+					t.Errorf("%s received the event: %v, want %v", name, received, want[name])
+				}
+			}
+		})
+	}
+}
+
+func TestRouterWithoutDefaultSendsUnmatchedEverywhere(t *testing.T) {
+	group := NewGroup()
+	slack, webhook := &recordingHandler{}, &recordingHandler{}
+	group.Add("slack", slack)
+	group.Add("webhook", webhook)
+
+	router, err := NewRouter(group, config.Routes{Rules: []config.Route{
+		{Match: config.RouteMatch{Kinds: []string{"Node"}}, Handlers: []string{"webhook"}},
+	}})
+	if err != nil {
+		t.Fatalf("NewRouter(): %v", err)
+	}
+
+	router.Handle(event.Event{Kind: "Pod"})
+	if len(slack.recorded()) != 1 || len(webhook.recorded()) != 1 {
+		t.Errorf("unmatched event reached slack %d and webhook %d times, want once each", len(slack.recorded()), len(webhook.recorded()))
+	}
+}
+
+func TestNewRouterRejectsInvalidRoutes(t *testing.T) {
+	group := NewGroup()
+	group.Add("slack", &recordingHandler{})
+
+	var Tests = []config.Routes{
+		{Default: []string{"teams"}},
+		{Rules: []config.Route{{Handlers: []string{"teams"}}}},
+		{Rules: []config.Route{{Match: config.RouteMatch{Kinds: []string{"Pod"}}}}},
+		{Rules: []config.Route{{Match: config.RouteMatch{Namespaces: []string{"[prod"}}, Handlers: []string{"slack"}}}},
+	}
+
+	for _, routes := range Tests {
+		if _, err := NewRouter(group, routes); err == nil {
+			t.Errorf("NewRouter(%+v) succeeded, want an error", routes)
+		}
+	}
+}
@@ -17,14 +17,19 @@ limitations under the License.
 package slack
 
 import (
+	"errors"
 	"fmt"
 	"github.com/sirupsen/logrus"
+	"net/http"
 	"os"
 
 	"github.com/slack-go/slack"
 
 	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/digest"
 	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/message"
+	"github.com/bitnami-labs/kubewatch/pkg/retry"
 )
 
 var slackColors = map[string]string{
@@ -49,9 +54,11 @@ Command line flags will override environment variables
 // Slack handler implements handler.Handler interface,
 // Notify event to slack channel
 type Slack struct {
-	Token   string
-	Channel string
-	Title   string
+	Token    string
+	Channel  string
+	Title    string
+	client   *http.Client
+	template *message.Template
 }
 
 // Init prepares slack configuration
@@ -78,24 +85,47 @@ func (s *Slack) Init(c *config.Config) error {
 	s.Token = token
 	s.Channel = channel
 	s.Title = title
+	s.client = &http.Client{Timeout: c.Limits.Timeout}
+
+	template, err := message.Parse(c.Handler.Slack.Template)
+	if err != nil {
+		return err
+	}
+	s.template = template
 
 	return checkMissingSlackVars(s)
 }
 
 // Handle handles the notification.
-func (s *Slack) Handle(e event.Event) {
-	api := slack.New(s.Token)
-	attachment := prepareSlackAttachment(e, s)
+func (s *Slack) Handle(e event.Event) error {
+	return s.post(prepareSlackAttachment(e, s))
+}
 
+// HandleDigest sends a digest of several events as one message.
+func (s *Slack) HandleDigest(d *digest.Digest) error {
+	return s.post(prepareSlackDigestAttachment(d, s))
+}
+
+func (s *Slack) post(attachment slack.Attachment) error {
+	api := slack.New(s.Token, slack.OptionHTTPClient(s.client))
 	channelID, timestamp, err := api.PostMessage(s.Channel,
 		slack.MsgOptionAttachments(attachment),
 		slack.MsgOptionAsUser(true))
 	if err != nil {
-		logrus.Printf("%s\n", err)
-		return
+		return classifySlackError(err)
 	}
 
 	logrus.Printf("Message successfully sent to channel %s at %s", channelID, timestamp)
+	return nil
+}
+
+// classifySlackError marks as permanent the errors Slack does not deem
+// retryable, and the API errors such as an unknown channel.
+func classifySlackError(err error) error {
+	if errors.As(err, new(slack.SlackErrorResponse)) {
+		return retry.Permanent(err)
+	}
+	return retry.Classify(err)
 }
 
 func checkMissingSlackVars(s *Slack) error {
@@ -112,11 +142,18 @@ func prepareSlackAttachment(e event.Event, s *Slack) slack.Attachment {
 		Fields: []slack.AttachmentField{
 			{
 				Title: s.Title,
-				Value: e.Message(),
+				Value: s.template.Text(e),
 			},
 		},
 	}
 
+	if changes := e.ChangesMessage(); changes != "" && !s.template.Custom() {
+		attachment.Fields = append(attachment.Fields, slack.AttachmentField{
+			Title: "Changes",
+			Value: changes,
+		})
+	}
+
 	if color, ok := slackColors[e.Status]; ok {
 		attachment.Color = color
 	}
@@ -125,3 +162,21 @@ func prepareSlackAttachment(e event.Event, s *Slack) slack.Attachment {
 
 	return attachment
 }
+
+func prepareSlackDigestAttachment(d *digest.Digest, s *Slack) slack.Attachment {
+	attachment := slack.Attachment{
+		Fields: []slack.AttachmentField{
+			{
+				Title: fmt.Sprintf("%s: %s", s.Title, d.Title()),
+				Value: d.Text(),
+			},
+		},
+		MarkdownIn: []string{"fields"},
+	}
+
+	if color, ok := slackColors[d.Status]; ok {
+		attachment.Color = color
+	}
+
+	return attachment
+}
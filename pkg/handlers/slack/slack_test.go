@@ -46,3 +46,11 @@ func TestSlackInit(t *testing.T) {
 		}
 	}
 }
+
+func TestSlackInitRejectsInvalidTemplate(t *testing.T) {
+	c := &config.Config{}
+	c.Handler.Slack = config.Slack{Token: "foo", Channel: "bar", Template: "{{ .Event.Name"}
+	if err := (&Slack{}).Init(c); err == nil {
+		t.Fatal("Init() accepted an invalid template")
+	}
+}
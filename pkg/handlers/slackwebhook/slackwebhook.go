@@ -40,13 +40,17 @@ package slackwebhook
 import (
 	"fmt"
 	"github.com/sirupsen/logrus"
+	"net/http"
 	"os"
 	"time"
 
 	"github.com/slack-go/slack"
 
 	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/digest"
 	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/message"
+	"github.com/bitnami-labs/kubewatch/pkg/retry"
 )
 
 var webhookErrMsg = `
@@ -71,6 +75,8 @@ type SlackWebhook struct {
 	Username        string
 	Emoji           string
 	Slackwebhookurl string
+	client          *http.Client
+	template        *message.Template
 }
 
 // Init prepares Webhook configuration
@@ -97,30 +103,45 @@ func (m *SlackWebhook) Init(c *config.Config) error {
 	m.Username = username
 	m.Emoji = emoji
 	m.Slackwebhookurl = slackwebhookurl
+	m.client = &http.Client{Timeout: c.Limits.Timeout}
+
+	template, err := message.Parse(c.Handler.SlackWebhook.Template)
+	if err != nil {
+		return err
+	}
+	m.template = template
 
 	return checkMissingWebhookVars(m)
 }
 
 // Handle handles an event.
-func (m *SlackWebhook) Handle(e event.Event) {
+func (m *SlackWebhook) Handle(e event.Event) error {
+	return m.post(m.template.Text(e))
+}
 
+// HandleDigest sends a digest of several events as one message.
+func (m *SlackWebhook) HandleDigest(d *digest.Digest) error {
+	return m.post(d.Title() + "\n" + d.Text())
+}
+
+func (m *SlackWebhook) post(text string) error {
 	webhookMessage := slack.WebhookMessage{
 		Channel:   m.Channel,
 		Username:  m.Username,
-		Text:      e.Message(),
+		Text:      text,
 		IconEmoji: m.Emoji,
 	}
 
 	logrus.Printf("slackwebhook-handle():Slackwebhook WebHookMessage: %s", webhookMessage.Text)
 
-	err := slack.PostWebhook(m.Slackwebhookurl, &webhookMessage)
+	err := slack.PostWebhookCustomHTTP(m.Slackwebhookurl, m.client, &webhookMessage)
 
 	if err != nil {
-		logrus.Printf("slackwebhook-handle() Error: %s\n", err)
-		return
+		return retry.Classify(err)
 	}
 
 	logrus.Printf("Message successfully sent to %s at %s. Message: %s", m.Slackwebhookurl, time.Now(), webhookMessage.Text)
+	return nil
 }
 
 func checkMissingWebhookVars(s *SlackWebhook) error {
@@ -42,8 +42,13 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-func sendEmail(conf config.SMTP, msg string) error {
+func sendEmail(conf config.SMTP, msg string, timeout time.Duration) error {
 	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
 
 	host, port, err := net.SplitHostPort(conf.Smarthost)
 	if err != nil {
@@ -63,7 +68,8 @@ func sendEmail(conf config.SMTP, msg string) error {
 			tlsConfig.ServerName = host
 		}
 
-		conn, err = tls.Dial("tcp", conf.Smarthost, tlsConfig)
+		d := tls.Dialer{Config: tlsConfig}
+		conn, err = d.DialContext(ctx, "tcp", conf.Smarthost)
 		if err != nil {
 			return fmt.Errorf("establish TLS connection to server: %w", err)
 		}
@@ -77,6 +83,9 @@ func sendEmail(conf config.SMTP, msg string) error {
 			return fmt.Errorf("establish connection to server: %w", err)
 		}
 	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
 	c, err = smtp.NewClient(conn, host)
 	if err != nil {
 		conn.Close()
@@ -22,11 +22,16 @@ See example configuration in the ConfigExample constant.
 package smtp
 
 import (
+	"errors"
 	"fmt"
+	"net/textproto"
 	"time"
 
 	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/digest"
 	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/message"
+	"github.com/bitnami-labs/kubewatch/pkg/retry"
 	"github.com/sirupsen/logrus"
 )
 
@@ -50,12 +55,15 @@ const (
 // SMTP handler implements handler.Handler interface,
 // Notify event via email.
 type SMTP struct {
-	cfg config.SMTP
+	cfg      config.SMTP
+	timeout  time.Duration
+	template *message.Template
 }
 
 // Init prepares Webhook configuration
 func (s *SMTP) Init(c *config.Config) error {
 	s.cfg = c.Handler.SMTP
+	s.timeout = c.Limits.Timeout
 
 	if s.cfg.To == "" {
 		return fmt.Errorf("smtp `to` conf field is required")
@@ -66,21 +74,53 @@ func (s *SMTP) Init(c *config.Config) error {
 	if s.cfg.Smarthost == "" {
 		return fmt.Errorf("smtp `smarthost` conf field is required")
 	}
+
+	template, err := message.Parse(s.cfg.Template)
+	if err != nil {
+		return err
+	}
+	s.template = template
 	return nil
 }
 
 // Handle handles the notification.
-func (s *SMTP) Handle(e event.Event) {
-	send(s.cfg, e.Message())
+func (s *SMTP) Handle(e event.Event) error {
+	msg, _ := formatEmail(e, s.template)
+	if err := send(s.cfg, msg, s.timeout); err != nil {
+		return err
+	}
 	logrus.Printf("Message successfully sent to %s at %s ", s.cfg.To, time.Now())
+	return nil
+}
+
+// HandleDigest sends a digest of several events as one e-mail.
+func (s *SMTP) HandleDigest(d *digest.Digest) error {
+	if err := send(s.cfg, d.Title()+"\n\n"+d.Text(), s.timeout); err != nil {
+		return err
+	}
+	logrus.Printf("Digest successfully sent to %s at %s ", s.cfg.To, time.Now())
+	return nil
 }
 
-func formatEmail(e event.Event) (string, error) {
+func formatEmail(e event.Event, template *message.Template) (string, error) {
+	if template.Custom() {
+		return template.Text(e), nil
+	}
+	if changes := e.ChangesMessage(); changes != "" {
+		return e.Message() + "\n\nChanges:\n" + changes, nil
+	}
 	return e.Message(), nil
 }
 
-func send(conf config.SMTP, msg string) {
-	if err := sendEmail(conf, msg); err != nil {
-		logrus.Error(err)
+// send sends an e-mail. Permanent SMTP replies (5xx) make permanent
+// errors; connection failures and temporary replies (4xx) may be retried.
+// The whole conversation with the server must end within timeout, unless it
+// is zero.
+func send(conf config.SMTP, msg string, timeout time.Duration) error {
+	err := sendEmail(conf, msg, timeout)
+	var reply *textproto.Error
+	if errors.As(err, &reply) && reply.Code >= 500 {
+		return retry.Permanent(err)
 	}
+	return err
 }
@@ -29,7 +29,10 @@ import (
 	"time"
 
 	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/diff"
 	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/message"
+	"github.com/bitnami-labs/kubewatch/pkg/retry"
 )
 
 var webhookErrMsg = `
@@ -48,14 +51,17 @@ Command line flags will override environment variables
 // Webhook handler implements handler.Handler interface,
 // Notify event to Webhook channel
 type Webhook struct {
-	Url string
+	Url      string
+	client   *http.Client
+	template *message.Template
 }
 
 // WebhookMessage for messages
 type WebhookMessage struct {
-	EventMeta EventMeta `json:"eventmeta"`
-	Text      string    `json:"text"`
-	Time      time.Time `json:"time"`
+	EventMeta EventMeta     `json:"eventmeta"`
+	Text      string        `json:"text"`
+	Time      time.Time     `json:"time"`
+	Changes   []diff.Change `json:"changes,omitempty"`
 }
 
 // EventMeta containes the meta data about the event occurred
@@ -81,8 +87,13 @@ func (m *Webhook) Init(c *config.Config) error {
 
 	m.Url = url
 
+	// Every webhook gets its own transport, so instances trusting different
+	// certificates do not overwrite each other's TLS settings.
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	m.client = &http.Client{Transport: transport, Timeout: c.Limits.Timeout}
+
 	if tlsSkip {
-		http.DefaultTransport.(*http.Transport).TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
 	} else {
 		if cert == "" {
 			logrus.Printf("No webhook cert is given")
@@ -94,25 +105,31 @@ func (m *Webhook) Init(c *config.Config) error {
 			}
 			caCertPool := x509.NewCertPool()
 			caCertPool.AppendCertsFromPEM(caCert)
-			http.DefaultTransport.(*http.Transport).TLSClientConfig = &tls.Config{RootCAs: caCertPool}
+			transport.TLSClientConfig = &tls.Config{RootCAs: caCertPool}
 		}
 
 	}
 
+	template, err := message.Parse(c.Handler.Webhook.Template)
+	if err != nil {
+		return err
+	}
+	m.template = template
+
 	return checkMissingWebhookVars(m)
 }
 
 // Handle handles an event.
-func (m *Webhook) Handle(e event.Event) {
+func (m *Webhook) Handle(e event.Event) error {
 	webhookMessage := prepareWebhookMessage(e, m)
 
-	err := postMessage(m.Url, webhookMessage)
+	err := postMessage(m.client, m.Url, webhookMessage)
 	if err != nil {
-		logrus.Printf("%s\n", err)
-		return
+		return err
 	}
 
 	logrus.Printf("Message successfully sent to %s at %s ", m.Url, time.Now())
+	return nil
 }
 
 func checkMissingWebhookVars(s *Webhook) error {
@@ -131,28 +148,32 @@ func prepareWebhookMessage(e event.Event, m *Webhook) *WebhookMessage {
 			Namespace: e.Namespace,
 			Reason:    e.Reason,
 		},
-		Text: e.Message(),
-		Time: time.Now(),
+		Text:    m.template.Text(e),
+		Time:    time.Now(),
+		Changes: e.Changes,
 	}
 }
 
-func postMessage(url string, webhookMessage *WebhookMessage) error {
+func postMessage(client *http.Client, url string, webhookMessage *WebhookMessage) error {
 	message, err := json.Marshal(webhookMessage)
 	if err != nil {
-		return err
+		return retry.Permanent(err)
 	}
 
 	req, err := http.NewRequest("POST", url, bytes.NewBuffer(message))
 	if err != nil {
-		return err
+		return retry.Permanent(err)
 	}
 	req.Header.Add("Content-Type", "application/json")
 
-	client := &http.Client{}
-	_, err = client.Do(req)
+	if client == nil {
+		client = &http.Client{}
+	}
+	resp, err := client.Do(req)
 	if err != nil {
 		return err
 	}
+	defer resp.Body.Close()
 
-	return nil
+	return retry.CheckResponse(resp)
 }
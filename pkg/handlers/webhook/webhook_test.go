@@ -17,11 +17,19 @@ limitations under the License.
 package webhook
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/diff"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/retry"
 )
 
 func TestWebhookInit(t *testing.T) {
@@ -44,3 +52,81 @@ func TestWebhookInit(t *testing.T) {
 		}
 	}
 }
+
+func TestWebhookMessageCarriesChanges(t *testing.T) {
+	e := event.Event{
+		Kind:    "deployment",
+		Name:    "web",
+		Reason:  "Updated",
+		Changes: []diff.Change{{Path: "spec.replicas", Old: 1, New: 3}},
+	}
+
+	payload, err := json.Marshal(prepareWebhookMessage(e, &Webhook{}))
+	if err != nil {
+		t.Fatalf("Marshal(): %v", err)
+	}
+	if want := `"changes":[{"path":"spec.replicas","old":1,"new":3}]`; !strings.Contains(string(payload), want) {
+		t.Errorf("payload %s does not contain %s", payload, want)
+	}
+
+	e.Changes = nil
+	payload, err = json.Marshal(prepareWebhookMessage(e, &Webhook{}))
+	if err != nil {
+		t.Fatalf("Marshal(): %v", err)
+	}
+	if strings.Contains(string(payload), "changes") {
+		t.Errorf("payload %s has changes for an event without any", payload)
+	}
+}
+
+func TestWebhookHandleReportsFailures(t *testing.T) {
+	var Tests = []struct {
+		status    int
+		wantErr   bool
+		permanent bool
+	}{
+		{http.StatusOK, false, false},
+		{http.StatusServiceUnavailable, true, false},
+		{http.StatusTooManyRequests, true, false},
+		{http.StatusUnauthorized, true, true},
+	}
+
+	for _, tt := range Tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+		}))
+
+		c := &config.Config{}
+		c.Handler.Webhook.Url = server.URL
+		w := &Webhook{}
+		if err := w.Init(c); err != nil {
+			t.Fatalf("Init(): %v", err)
+		}
+		err := w.Handle(event.Event{Kind: "pod", Name: "web", Reason: "Created"})
+		if (err != nil) != tt.wantErr || retry.IsPermanent(err) != tt.permanent {
+			t.Errorf("Handle() with a %d response = %v, want error %t, permanent %t", tt.status, err, tt.wantErr, tt.permanent)
+		}
+		server.Close()
+	}
+}
+
+func TestWebhookHandleTimesOut(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	c := &config.Config{}
+	c.Handler.Webhook.Url = server.URL
+	c.Limits.Timeout = 50 * time.Millisecond
+	w := &Webhook{}
+	if err := w.Init(c); err != nil {
+		t.Fatalf("Init(): %v", err)
+	}
+	err := w.Handle(event.Event{Kind: "pod", Name: "web", Reason: "Created"})
+	if err == nil || retry.IsPermanent(err) {
+		t.Errorf("Handle() with a stuck receiver = %v, want a transient error", err)
+	}
+}
@@ -0,0 +1,176 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package message
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template"
+)
+
+// funcs are the helpers available to templates. Names and argument order
+// follow Sprig, so that the last argument can be piped in, e.g.
+// {{ .Event.Name | upper }} or {{ .Object.spec.replicas | default 1 }}.
+var funcs = template.FuncMap{
+	"lower":      strings.ToLower,
+	"upper":      strings.ToUpper,
+	"title":      title,
+	"trim":       strings.TrimSpace,
+	"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+	"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+	"replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+	"contains":   func(substr, s string) bool { return strings.Contains(s, substr) },
+	"hasPrefix":  func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+	"hasSuffix":  func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+	"trunc":      trunc,
+	"quote":      func(v interface{}) string { return fmt.Sprintf("%q", toString(v)) },
+	"join":       join,
+	"toString":   toString,
+	"default":    defaultValue,
+	"empty":      empty,
+	"coalesce":   coalesce,
+	"ternary":    ternary,
+	"dig":        dig,
+	"toJson":     toJSON,
+}
+
+func title(s string) string {
+	words := strings.Fields(s)
+	for i, w := range words {
+		runes := []rune(w)
+		words[i] = strings.ToUpper(string(runes[0])) + string(runes[1:])
+	}
+	return strings.Join(words, " ")
+}
+
+// trunc keeps the first n runes of s
+func trunc(n int, s string) string {
+	if runes := []rune(s); n >= 0 && len(runes) > n {
+		return string(runes[:n])
+	}
+	return s
+}
+
+func join(sep string, list interface{}) string {
+	value := reflect.ValueOf(list)
+	if value.Kind() != reflect.Slice && value.Kind() != reflect.Array {
+		return toString(list)
+	}
+	parts := make([]string, value.Len())
+	for i := range parts {
+		parts[i] = toString(value.Index(i).Interface())
+	}
+	return strings.Join(parts, sep)
+}
+
+func toString(v interface{}) string {
+	switch typed := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return typed
+	case fmt.Stringer:
+		return typed.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// defaultValue returns v, or d when v is empty
+func defaultValue(d interface{}, v ...interface{}) interface{} {
+	if len(v) == 0 || empty(v[0]) {
+		return d
+	}
+	return v[0]
+}
+
+// empty reports whether v is nil or the zero value of its type, or an empty
+// collection
+func empty(v interface{}) bool {
+	value := reflect.ValueOf(v)
+	if !value.IsValid() {
+		return true
+	}
+	switch value.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return value.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return value.IsNil()
+	default:
+		return value.IsZero()
+	}
+}
+
+// coalesce returns the first argument that is not empty
+func coalesce(values ...interface{}) interface{} {
+	for _, v := range values {
+		if !empty(v) {
+			return v
+		}
+	}
+	return nil
+}
+
+func ternary(whenTrue, whenFalse interface{}, condition bool) interface{} {
+	if condition {
+		return whenTrue
+	}
+	return whenFalse
+}
+
+// dig walks nested maps along keys and returns the value found, or d when a
+// key is missing. The last two arguments are d and the map:
+// {{ dig "spec" "replicas" 1 .Object }}.
+func dig(args ...interface{}) (interface{}, error) {
+	if len(args) < 3 {
+		return nil, fmt.Errorf("dig needs at least one key, a default and a map")
+	}
+	d := args[len(args)-2]
+	current, ok := args[len(args)-1].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("dig: last argument must be a map, got %T", args[len(args)-1])
+	}
+
+	keys := args[:len(args)-2]
+	for i, k := range keys {
+		key, ok := k.(string)
+		if !ok {
+			return nil, fmt.Errorf("dig: keys must be strings, got %T", k)
+		}
+		value, found := current[key]
+		if !found {
+			return d, nil
+		}
+		if i == len(keys)-1 {
+			return value, nil
+		}
+		if current, ok = value.(map[string]interface{}); !ok {
+			return d, nil
+		}
+	}
+	return d, nil
+}
+
+func toJSON(v interface{}) (string, error) {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
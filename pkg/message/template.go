@@ -0,0 +1,148 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package message renders notification texts from user supplied Go
+// templates.
+//
+// A template is executed against a Data value, so it can refer to
+//
+//	.Event      the event, e.g. .Event.Kind, .Event.Name or .Event.Reason
+//	.Object     the object, as it appears in YAML, e.g. .Object.spec.replicas
+//	.OldObject  the previous version of the object on updates
+//	.Changes    the fields an update changed, each with .Path, .Old and .New
+//	.Message    the message kubewatch would send without a template
+//
+// and call .Change "spec.replicas" for the change to a single field or
+// .Manager for the field manager behind the latest write, besides the
+// helpers listed in funcs.go.
+package message
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/pkg/diff"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/utils"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/meta"
+)
+
+// Template is a parsed message template
+type Template struct {
+	tmpl *template.Template
+}
+
+// Data is what a template is executed against
+type Data struct {
+	Event     *event.Event
+	Object    map[string]interface{}
+	OldObject map[string]interface{}
+	Changes   []diff.Change
+	Message   string
+}
+
+// Parse parses a template. An empty text yields a nil Template, which
+// renders the default message.
+func Parse(text string) (*Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+	tmpl, err := template.New("message").Funcs(funcs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid message template: %v", err)
+	}
+	return &Template{tmpl: tmpl}, nil
+}
+
+// Render executes the template against e.
+func (t *Template) Render(e event.Event) (string, error) {
+	if t == nil {
+		return e.Message(), nil
+	}
+
+	object, err := utils.ToUnstructured(e.Obj)
+	if err != nil {
+		return "", fmt.Errorf("converting %T for the message template: %v", e.Obj, err)
+	}
+	oldObject, err := utils.ToUnstructured(e.OldObj)
+	if err != nil {
+		return "", fmt.Errorf("converting %T for the message template: %v", e.OldObj, err)
+	}
+
+	var out bytes.Buffer
+	err = t.tmpl.Execute(&out, Data{
+		Event:     &e,
+		Object:    object,
+		OldObject: oldObject,
+		Changes:   e.Changes,
+		Message:   e.Message(),
+	})
+	if err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// Text renders e, falling back to the default message when the template
+// fails so that the event is not lost.
+func (t *Template) Text(e event.Event) string {
+	text, err := t.Render(e)
+	if err != nil {
+		logrus.Errorf("Cannot render message template for %s %s, sending the default message: %v", e.Kind, e.Name, err)
+		return e.Message()
+	}
+	return text
+}
+
+// Custom reports whether t replaces the default message
+func (t *Template) Custom() bool {
+	return t != nil
+}
+
+// Change returns the change to the field at path, or nil if the update did
+// not change it.
+func (d Data) Change(path string) *diff.Change {
+	for i := range d.Changes {
+		if d.Changes[i].Path == path {
+			return &d.Changes[i]
+		}
+	}
+	return nil
+}
+
+// Manager returns the field manager of the most recent write to the object,
+// e.g. kubectl-scale or a controller name, or an empty string if the object
+// does not track managed fields.
+func (d Data) Manager() string {
+	accessor, err := meta.Accessor(d.Event.Obj)
+	if err != nil {
+		return ""
+	}
+	var manager string
+	var latest time.Time
+	for _, entry := range accessor.GetManagedFields() {
+		if entry.Time == nil {
+			continue
+		}
+		if manager == "" || entry.Time.Time.After(latest) {
+			manager, latest = entry.Manager, entry.Time.Time
+		}
+	}
+	return manager
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package message
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/pkg/diff"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	apps_v1 "k8s.io/api/apps/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func deployment(replicas int32, manager string, at time.Time) *apps_v1.Deployment {
+	return &apps_v1.Deployment{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "api",
+			Namespace: "prod",
+			Labels:    map[string]string{"team": "payments"},
+			ManagedFields: []meta_v1.ManagedFieldsEntry{
+				{Manager: "kube-controller-manager", Time: &meta_v1.Time{Time: at.Add(-time.Hour)}},
+				{Manager: manager, Time: &meta_v1.Time{Time: at}},
+			},
+		},
+		Spec: apps_v1.DeploymentSpec{Replicas: &replicas},
+	}
+}
+
+func scaledEvent() event.Event {
+	now := time.Now()
+	return event.Event{
+		Kind:      "Deployment",
+		Name:      "api",
+		Namespace: "prod",
+		Reason:    "Updated",
+		Status:    "Warning",
+		Obj:       deployment(5, "kubectl-scale", now),
+		OldObj:    deployment(3, "kubectl-client-side-apply", now.Add(-time.Minute)),
+		Changes:   []diff.Change{{Path: "spec.replicas", Old: int64(3), New: int64(5)}},
+	}
+}
+
+func TestRender(t *testing.T) {
+	var Tests = []struct {
+		template string
+		want     string
+	}{
+		{
+			`{{ .Event.Kind }} {{ .Event.Name }} scaled {{ with .Change "spec.replicas" }}{{ .Old }}→{{ .New }}{{ end }} by {{ .Manager }}`,
+			"Deployment api scaled 3→5 by kubectl-scale",
+		},
+		{
+			`{{ .OldObject.spec.replicas }} {{ .Object.metadata.labels.team | upper }}`,
+			"3 PAYMENTS",
+		},
+		{
+			`{{ dig "spec" "paused" "running" .Object }} {{ dig "metadata" "labels" "team" "none" .Object }}`,
+			"running payments",
+		},
+		{
+			`{{ .Object.metadata.annotations | default "no annotations" }}`,
+			"no annotations",
+		},
+		{
+			`{{ range .Changes }}{{ .Path }}{{ end }} {{ .Change "spec.paused" | empty }}`,
+			"spec.replicas true",
+		},
+		{
+			`{{ .Event.Namespace | quote }} {{ trunc 3 .Event.Name | title }}`,
+			`"prod" Api`,
+		},
+		{
+			`[{{ .Message }}]`,
+			"[A `Deployment` in namespace `prod` has been `Updated`:\n`api`]",
+		},
+	}
+
+	for _, tt := range Tests {
+		tmpl, err := Parse(tt.template)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.template, err)
+		}
+		got, err := tmpl.Render(scaledEvent())
+		if err != nil {
+			t.Errorf("Render(%q): %v", tt.template, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Render(%q) = %q, want %q", tt.template, got, tt.want)
+		}
+	}
+}
+
+func TestParseRejectsInvalidTemplates(t *testing.T) {
+	for _, text := range []string{
+		"{{ .Event.Name",
+		"{{ .Event.Name | shout }}",
+	} {
+		if _, err := Parse(text); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", text)
+		}
+	}
+}
+
+func TestTextFallsBackToDefaultMessage(t *testing.T) {
+	e := scaledEvent()
+
+	var none *Template
+	if got := none.Text(e); got != e.Message() {
+		t.Errorf("nil Template rendered %q, want the default message", got)
+	}
+	if none.Custom() {
+		t.Error("nil Template reports a custom message")
+	}
+
+	failing, err := Parse(`{{ index .Event.Name 99 }}`)
+	if err != nil {
+		t.Fatalf("Parse(): %v", err)
+	}
+	if _, err := failing.Render(e); err == nil {
+		t.Fatal("Render() succeeded on an out of range index, want an error")
+	}
+	if got := failing.Text(e); !strings.Contains(got, "has been `Updated`") {
+		t.Errorf("Text() = %q, want the default message", got)
+	}
+}
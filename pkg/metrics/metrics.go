@@ -24,6 +24,22 @@ import (
 var (
 	// EventsSentTotal tracks events sent to handlers after filtering
 	EventsSentTotal *prometheus.CounterVec
+	// EventsSuppressedTotal tracks repeated events held back by deduplication
+	EventsSuppressedTotal *prometheus.CounterVec
+	// DeliveryRetriesTotal tracks deliveries retried after a transient failure
+	DeliveryRetriesTotal *prometheus.CounterVec
+	// DeliveryFailuresTotal tracks deliveries given up on
+	DeliveryFailuresTotal *prometheus.CounterVec
+	// OutboxPending tracks the messages waiting in the outbox of each handler
+	OutboxPending *prometheus.GaugeVec
+	// DestinationHealthy tracks whether the circuit breaker of each handler
+	// lets deliveries through
+	DestinationHealthy *prometheus.GaugeVec
+	// QueueDepth tracks the events waiting in the queue of each handler
+	QueueDepth *prometheus.GaugeVec
+	// QueueDroppedTotal tracks the events dropped because a handler queue
+	// was full
+	QueueDroppedTotal *prometheus.CounterVec
 )
 
 func init() {
@@ -35,4 +51,60 @@ func init() {
 		},
 		[]string{"resourceType", "eventType"},
 	)
+
+	EventsSuppressedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kubewatch_events_suppressed_total",
+			Help: "The total number of repeated Kubernetes events held back by deduplication, labeled by resource and event type",
+		},
+		[]string{"resourceType", "eventType"},
+	)
+
+	DeliveryRetriesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kubewatch_delivery_retries_total",
+			Help: "The total number of deliveries retried after a transient failure, labeled by handler",
+		},
+		[]string{"handler"},
+	)
+
+	DeliveryFailuresTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kubewatch_delivery_failures_total",
+			Help: "The total number of deliveries given up on, labeled by handler and by reason: permanent for failures retrying cannot fix, exhausted when no attempt is left",
+		},
+		[]string{"handler", "reason"},
+	)
+
+	OutboxPending = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "kubewatch_outbox_pending",
+			Help: "The number of messages waiting for delivery in the outbox, labeled by handler",
+		},
+		[]string{"handler"},
+	)
+
+	DestinationHealthy = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "kubewatch_destination_healthy",
+			Help: "Whether the destination of a handler is deemed healthy: 0 while its circuit breaker is open after consecutive failures, 1 otherwise, labeled by handler",
+		},
+		[]string{"handler"},
+	)
+
+	QueueDepth = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "kubewatch_queue_depth",
+			Help: "The number of events waiting in the dispatch queue, labeled by handler",
+		},
+		[]string{"handler"},
+	)
+
+	QueueDroppedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kubewatch_queue_dropped_total",
+			Help: "The total number of events dropped because the dispatch queue was full, labeled by handler",
+		},
+		[]string{"handler"},
+	)
 }
@@ -0,0 +1,391 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package outbox keeps the messages waiting for delivery in an append-only
+// log on disk, so they survive restarts and are delivered in order.
+//
+// A log is a directory of segment files holding one record per line, each
+// named after the sequence number of its first record, and a cursor file
+// holding the sequence number of the first record not delivered yet.
+// Segments whose records were all delivered are removed.
+package outbox
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultSegmentSize is the size past which a new segment is started
+const DefaultSegmentSize = 16 << 20
+
+const (
+	segmentSuffix = ".log"
+	cursorFile    = "cursor"
+)
+
+// segment is a file of consecutive records
+type segment struct {
+	first uint64
+	count uint64
+	size  int64
+}
+
+func (s segment) name() string {
+	return fmt.Sprintf("%020d%s", s.first, segmentSuffix)
+}
+
+// Log is an append-only log of records read in the order they were appended
+type Log struct {
+	dir         string
+	segmentSize int64
+
+	mutex    sync.Mutex
+	segments []segment
+	// cursor is the sequence number of the first record not committed
+	cursor uint64
+	// writer appends to the last segment
+	writer *os.File
+	// reader reads the segment starting at readerFirst, from readerSeq on
+	reader      *bufio.Reader
+	readerFile  *os.File
+	readerFirst uint64
+	readerSeq   uint64
+	// head is the record at the cursor once Peek read it
+	head []byte
+}
+
+// Open opens the log in dir, creating it if needed, and starts a new segment
+// when the last one grows past segmentSize bytes, or DefaultSegmentSize when
+// segmentSize is not positive. A record cut short by a crash is dropped.
+func Open(dir string, segmentSize int64) (*Log, error) {
+	if segmentSize <= 0 {
+		segmentSize = DefaultSegmentSize
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	l := &Log{dir: dir, segmentSize: segmentSize}
+	if err := l.load(); err != nil {
+		return nil, fmt.Errorf("outbox %s: %v", dir, err)
+	}
+	return l, nil
+}
+
+// load reads the cursor and scans the segments
+func (l *Log) load() error {
+	data, err := os.ReadFile(filepath.Join(l.dir, cursorFile))
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return err
+	default:
+		if l.cursor, err = strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64); err != nil {
+			return fmt.Errorf("invalid cursor: %v", err)
+		}
+	}
+
+	names, err := filepath.Glob(filepath.Join(l.dir, "*"+segmentSuffix))
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		first, err := strconv.ParseUint(strings.TrimSuffix(filepath.Base(name), segmentSuffix), 10, 64)
+		if err != nil {
+			return fmt.Errorf("unexpected file %s", name)
+		}
+		s, err := scan(name, first, i == len(names)-1)
+		if err != nil {
+			return err
+		}
+		l.segments = append(l.segments, s)
+	}
+
+	if len(l.segments) == 0 || l.next() < l.cursor {
+		// Nothing on disk to deliver: start afresh at the cursor.
+		for _, s := range l.segments {
+			if err := os.Remove(filepath.Join(l.dir, s.name())); err != nil {
+				return err
+			}
+		}
+		l.segments = []segment{{first: l.cursor}}
+	}
+	if l.cursor < l.segments[0].first {
+		l.cursor = l.segments[0].first
+	}
+	if err := l.removeDelivered(); err != nil {
+		return err
+	}
+
+	last := l.segments[len(l.segments)-1]
+	l.writer, err = os.OpenFile(filepath.Join(l.dir, last.name()), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	return err
+}
+
+// scan counts the records of a segment. The last segment is truncated after
+// its last complete record.
+func scan(name string, first uint64, last bool) (segment, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return segment{}, err
+	}
+	complete := bytes.LastIndexByte(data, '\n') + 1
+	if complete < len(data) {
+		if !last {
+			return segment{}, fmt.Errorf("segment %s ends with an incomplete record", name)
+		}
+		if err := os.Truncate(name, int64(complete)); err != nil {
+			return segment{}, err
+		}
+	}
+	return segment{
+		first: first,
+		count: uint64(bytes.Count(data[:complete], []byte{'\n'})),
+		size:  int64(complete),
+	}, nil
+}
+
+// next returns the sequence number of the next record appended
+func (l *Log) next() uint64 {
+	last := l.segments[len(l.segments)-1]
+	return last.first + last.count
+}
+
+// Len returns the number of records not committed
+func (l *Log) Len() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return int(l.next() - l.cursor)
+}
+
+// Append adds a record at the end of the log and syncs it to disk. Records
+// must not contain newlines, which JSON encoding guarantees.
+func (l *Log) Append(record []byte) error {
+	if bytes.IndexByte(record, '\n') >= 0 {
+		return fmt.Errorf("outbox records must not contain newlines")
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	last := &l.segments[len(l.segments)-1]
+	if last.size >= l.segmentSize {
+		if err := l.startSegment(); err != nil {
+			return err
+		}
+		last = &l.segments[len(l.segments)-1]
+	}
+
+	line := append(append(make([]byte, 0, len(record)+1), record...), '\n')
+	if _, err := l.writer.Write(line); err != nil {
+		return err
+	}
+	if err := l.writer.Sync(); err != nil {
+		return err
+	}
+	last.count++
+	last.size += int64(len(line))
+	return nil
+}
+
+// startSegment closes the last segment and starts a new one
+func (l *Log) startSegment() error {
+	s := segment{first: l.next()}
+	writer, err := os.OpenFile(filepath.Join(l.dir, s.name()), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	if err := l.writer.Close(); err != nil {
+		writer.Close()
+		return err
+	}
+	l.writer = writer
+	l.segments = append(l.segments, s)
+	return nil
+}
+
+// Peek returns the first record not committed, or false when every record
+// was committed. It returns the same record until Commit is called.
+func (l *Log) Peek() ([]byte, bool, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.head != nil {
+		return l.head, true, nil
+	}
+	if l.cursor == l.next() {
+		return nil, false, nil
+	}
+
+	i := sort.Search(len(l.segments), func(i int) bool {
+		return l.segments[i].first+l.segments[i].count > l.cursor
+	})
+	if l.reader == nil || l.readerFirst != l.segments[i].first || l.readerSeq != l.cursor {
+		if err := l.seek(l.segments[i]); err != nil {
+			return nil, false, err
+		}
+	}
+	// A record is counted once written in full, so reading it does not
+	// race with Append.
+	line, err := l.reader.ReadBytes('\n')
+	if err != nil {
+		l.closeReader()
+		return nil, false, err
+	}
+	l.readerSeq++
+	l.head = line[:len(line)-1]
+	return l.head, true, nil
+}
+
+// seek opens the segment holding the cursor and skips to it
+func (l *Log) seek(s segment) error {
+	l.closeReader()
+
+	f, err := os.Open(filepath.Join(l.dir, s.name()))
+	if err != nil {
+		return err
+	}
+	reader := bufio.NewReader(f)
+	for seq := s.first; seq < l.cursor; seq++ {
+		if _, err := reader.ReadBytes('\n'); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	l.reader, l.readerFile, l.readerFirst, l.readerSeq = reader, f, s.first, l.cursor
+	return nil
+}
+
+// Commit marks the record returned by Peek as delivered, and removes the
+// segments left without records to deliver.
+func (l *Log) Commit() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.head == nil {
+		return fmt.Errorf("nothing to commit")
+	}
+	if err := writeFile(filepath.Join(l.dir, cursorFile), []byte(strconv.FormatUint(l.cursor+1, 10))); err != nil {
+		return err
+	}
+	l.cursor++
+	l.head = nil
+	return l.removeDelivered()
+}
+
+// Records returns the records not committed, in order, without moving the
+// cursor.
+func (l *Log) Records() ([][]byte, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	var records [][]byte
+	for _, s := range l.segments {
+		if s.first+s.count <= l.cursor {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(l.dir, s.name()))
+		if err != nil {
+			return nil, err
+		}
+		lines := bytes.SplitAfter(data, []byte{'\n'})
+		for seq := s.first; seq < s.first+s.count; seq++ {
+			line := lines[seq-s.first]
+			if seq >= l.cursor {
+				records = append(records, line[:len(line)-1])
+			}
+		}
+	}
+	return records, nil
+}
+
+// Discard marks the first n records not committed as delivered, as n calls
+// to Peek and Commit would.
+func (l *Log) Discard(n int) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if n < 0 || uint64(n) > l.next()-l.cursor {
+		return fmt.Errorf("cannot discard %d of %d records", n, l.next()-l.cursor)
+	}
+	if err := writeFile(filepath.Join(l.dir, cursorFile), []byte(strconv.FormatUint(l.cursor+uint64(n), 10))); err != nil {
+		return err
+	}
+	l.cursor += uint64(n)
+	l.head = nil
+	l.closeReader()
+	return l.removeDelivered()
+}
+
+// removeDelivered removes the segments before the cursor, except the last
+// one which is still appended to
+func (l *Log) removeDelivered() error {
+	for len(l.segments) > 1 && l.segments[0].first+l.segments[0].count <= l.cursor {
+		if l.reader != nil && l.readerFirst == l.segments[0].first {
+			l.closeReader()
+		}
+		if err := os.Remove(filepath.Join(l.dir, l.segments[0].name())); err != nil {
+			return err
+		}
+		l.segments = l.segments[1:]
+	}
+	return nil
+}
+
+func (l *Log) closeReader() {
+	if l.readerFile != nil {
+		l.readerFile.Close()
+	}
+	l.reader, l.readerFile = nil, nil
+}
+
+// Close closes the files of the log
+func (l *Log) Close() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.closeReader()
+	return l.writer.Close()
+}
+
+// writeFile replaces the contents of a file atomically
+func writeFile(name string, data []byte) error {
+	tmp := name + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, name)
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package outbox
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func openLog(t *testing.T, dir string, segmentSize int64) *Log {
+	t.Helper()
+
+	l, err := Open(dir, segmentSize)
+	if err != nil {
+		t.Fatalf("Open(): %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+	return l
+}
+
+func appendRecords(t *testing.T, l *Log, from, to int) {
+	t.Helper()
+
+	for i := from; i < to; i++ {
+		if err := l.Append([]byte(fmt.Sprintf(`{"n":%d}`, i))); err != nil {
+			t.Fatalf("Append(): %v", err)
+		}
+	}
+}
+
+// deliver peeks and commits n records and returns them
+func deliver(t *testing.T, l *Log, n int) []string {
+	t.Helper()
+
+	var got []string
+	for i := 0; i < n; i++ {
+		record, ok, err := l.Peek()
+		if err != nil || !ok {
+			t.Fatalf("Peek() = %v, %v after %d records", ok, err, i)
+		}
+		got = append(got, string(record))
+		if err := l.Commit(); err != nil {
+			t.Fatalf("Commit(): %v", err)
+		}
+	}
+	return got
+}
+
+func TestLogDeliversInOrderAcrossRestarts(t *testing.T) {
+	dir := t.TempDir()
+
+	l := openLog(t, dir, 64)
+	appendRecords(t, l, 0, 10)
+	if got := deliver(t, l, 3); got[0] != `{"n":0}` || got[2] != `{"n":2}` {
+		t.Fatalf("delivered %v, want records 0 to 2", got)
+	}
+	// Peeking without committing does not move the cursor.
+	if record, _, _ := l.Peek(); string(record) != `{"n":3}` {
+		t.Fatalf("Peek() = %s, want record 3", record)
+	}
+	l.Close()
+
+	l = openLog(t, dir, 64)
+	if l.Len() != 7 {
+		t.Fatalf("Len() = %d after a restart, want 7", l.Len())
+	}
+	appendRecords(t, l, 10, 12)
+	got := deliver(t, l, 9)
+	for i, record := range got {
+		if want := fmt.Sprintf(`{"n":%d}`, i+3); record != want {
+			t.Fatalf("record %d = %s, want %s", i, record, want)
+		}
+	}
+	if _, ok, err := l.Peek(); ok || err != nil {
+		t.Errorf("Peek() = %v, %v on a drained log, want nothing", ok, err)
+	}
+}
+
+func TestLogRecordsAndDiscard(t *testing.T) {
+	dir := t.TempDir()
+
+	l := openLog(t, dir, 32)
+	appendRecords(t, l, 0, 10)
+	deliver(t, l, 2)
+	records, err := l.Records()
+	if err != nil || len(records) != 8 || string(records[0]) != `{"n":2}` || string(records[7]) != `{"n":9}` {
+		t.Fatalf("Records() = %q, %v, want records 2 to 9", records, err)
+	}
+	if err := l.Discard(5); err != nil {
+		t.Fatalf("Discard(): %v", err)
+	}
+	if err := l.Discard(4); err == nil {
+		t.Errorf("Discard() of more records than left succeeded")
+	}
+	l.Close()
+
+	l = openLog(t, dir, 32)
+	if got := deliver(t, l, 3); got[0] != `{"n":7}` || got[2] != `{"n":9}` {
+		t.Errorf("delivered %v after a restart, want records 7 to 9", got)
+	}
+}
+
+func TestLogRemovesDeliveredSegments(t *testing.T) {
+	dir := t.TempDir()
+
+	l := openLog(t, dir, 32)
+	appendRecords(t, l, 0, 20)
+	segments := func() int {
+		names, _ := filepath.Glob(filepath.Join(dir, "*"+segmentSuffix))
+		return len(names)
+	}
+	if segments() < 2 {
+		t.Fatalf("got %d segments, want the log to roll over", segments())
+	}
+
+	deliver(t, l, 20)
+	if segments() != 1 {
+		t.Errorf("got %d segments once everything was delivered, want only the last one", segments())
+	}
+}
+
+func TestLogDropsRecordCutShort(t *testing.T) {
+	dir := t.TempDir()
+
+	l := openLog(t, dir, 0)
+	appendRecords(t, l, 0, 2)
+	l.Close()
+
+	// A crash in the middle of an append leaves half a record behind.
+	f, err := os.OpenFile(filepath.Join(dir, segment{}.name()), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"n":`)
+	f.Close()
+
+	l = openLog(t, dir, 0)
+	if l.Len() != 2 {
+		t.Fatalf("Len() = %d, want the 2 complete records", l.Len())
+	}
+	appendRecords(t, l, 2, 3)
+	if got := deliver(t, l, 3); got[2] != `{"n":2}` {
+		t.Errorf("delivered %v, want the record appended after the restart last", got)
+	}
+}
+
+func TestLogRejectsNewlines(t *testing.T) {
+	l := openLog(t, t.TempDir(), 0)
+	if err := l.Append([]byte("two\nlines")); err == nil {
+		t.Error("Append() accepted a record with a newline")
+	}
+}
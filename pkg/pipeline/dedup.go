@@ -0,0 +1,163 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/diff"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/metrics"
+	"github.com/bitnami-labs/kubewatch/pkg/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// dedup sends the first of a series of identical events right away and
+// holds back its repeats. When the window started by the first one closes,
+// the latest repeat is sent with the number held back, and a new window
+// starts; a window without repeats ends the series.
+//
+// Events are identical when they share kind, namespace, name, reason and the
+// values of the configured fields.
+type dedup struct {
+	window time.Duration
+	fields [][]string
+	send   func(event.Event) error
+	// afterFunc runs f once d has elapsed; tests replace it to control time
+	afterFunc func(d time.Duration, f func())
+
+	mutex  sync.Mutex
+	series map[string]*series
+}
+
+// series tracks the repeats of an event within the current window
+type series struct {
+	latest   event.Event
+	repeated int
+}
+
+func newDedup(c config.Dedup, send func(event.Event) error) (*dedup, error) {
+	if c.Window < 0 {
+		return nil, fmt.Errorf("dedup window must not be negative, got %s", c.Window)
+	}
+
+	d := &dedup{
+		window:    c.Window,
+		send:      send,
+		afterFunc: func(d time.Duration, f func()) { time.AfterFunc(d, f) },
+		series:    map[string]*series{},
+	}
+	for _, field := range c.Fields {
+		keys, err := diff.SplitPath(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid dedup field: %v", err)
+		}
+		d.fields = append(d.fields, keys)
+	}
+	return d, nil
+}
+
+func (d *dedup) handle(e event.Event) error {
+	if d.window == 0 {
+		return d.send(e)
+	}
+
+	key := d.key(e)
+	d.mutex.Lock()
+	if s, ok := d.series[key]; ok {
+		s.latest = e
+		s.repeated++
+		d.mutex.Unlock()
+		logrus.Debugf("Repeated event held back - Kind: %s, Reason: %s, Name: %s", e.Kind, e.Reason, e.Name)
+		metrics.EventsSuppressedTotal.WithLabelValues(e.Kind, eventType(e)).Inc()
+		return nil
+	}
+	d.series[key] = &series{}
+	d.mutex.Unlock()
+
+	d.afterFunc(d.window, func() { d.closeWindow(key) })
+	return d.send(e)
+}
+
+// closeWindow sends the summary of the repeats seen during the window that
+// just closed, if any. Nobody waits for the summary, so errors are logged.
+func (d *dedup) closeWindow(key string) {
+	d.mutex.Lock()
+	s := d.series[key]
+	if s.repeated == 0 {
+		delete(d.series, key)
+		d.mutex.Unlock()
+		return
+	}
+	summary := s.latest
+	summary.Repeated = s.repeated
+	s.repeated = 0
+	d.mutex.Unlock()
+
+	d.afterFunc(d.window, func() { d.closeWindow(key) })
+	if err := d.send(summary); err != nil {
+		logrus.Errorf("Error sending the summary of %d repeats of %s %s: %v", summary.Repeated, summary.Kind, summary.Name, err)
+	}
+}
+
+// key identifies the series an event belongs to
+func (d *dedup) key(e event.Event) string {
+	parts := []string{e.Kind, e.Namespace, e.Name, e.Reason}
+	if len(d.fields) == 0 {
+		return strings.Join(parts, "\x00")
+	}
+
+	object, err := utils.ToUnstructured(e.Obj)
+	if err != nil {
+		logrus.Debugf("Cannot read dedup fields of %s %s: %v", e.Kind, e.Name, err)
+	}
+	for _, keys := range d.fields {
+		value, _ := json.Marshal(lookup(object, keys))
+		parts = append(parts, string(value))
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// lookup returns the value under keys in nested maps, or nil if there is
+// none. An empty key stands for every item of a list, whose values are
+// returned in a list.
+func lookup(value interface{}, keys []string) interface{} {
+	for i, key := range keys {
+		if key == "" {
+			list, ok := value.([]interface{})
+			if !ok {
+				return nil
+			}
+			values := make([]interface{}, len(list))
+			for j, item := range list {
+				values[j] = lookup(item, keys[i+1:])
+			}
+			return values
+		}
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = m[key]
+	}
+	return value
+}
@@ -0,0 +1,158 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipeline
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	apps_v1 "k8s.io/api/apps/v1"
+	api_v1 "k8s.io/api/core/v1"
+)
+
+// manualTimers collects the functions dedup schedules so a test can close
+// windows when it wants to.
+type manualTimers struct {
+	pending []func()
+}
+
+func (m *manualTimers) afterFunc(_ time.Duration, f func()) {
+	m.pending = append(m.pending, f)
+}
+
+// fire runs the functions scheduled so far.
+func (m *manualTimers) fire() {
+	pending := m.pending
+	m.pending = nil
+	for _, f := range pending {
+		f()
+	}
+}
+
+func newTestDedup(t *testing.T, c config.Dedup) (*dedup, *manualTimers, *[]event.Event) {
+	t.Helper()
+
+	var sent []event.Event
+	d, err := newDedup(c, func(e event.Event) error {
+		sent = append(sent, e)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("newDedup(): %v", err)
+	}
+	timers := &manualTimers{}
+	d.afterFunc = timers.afterFunc
+	return d, timers, &sent
+}
+
+func TestDedupSummarizesRepeats(t *testing.T) {
+	d, timers, sent := newTestDedup(t, config.Dedup{Window: time.Minute})
+
+	backoff := event.Event{Kind: "Pod", Namespace: "prod", Name: "api", Reason: "Updated"}
+	for i := 0; i < 5; i++ {
+		d.handle(backoff)
+	}
+	d.handle(event.Event{Kind: "Pod", Namespace: "prod", Name: "api", Reason: "Deleted"})
+
+	if len(*sent) != 2 || (*sent)[0].Repeated != 0 || (*sent)[1].Reason != "Deleted" {
+		t.Fatalf("sent %+v, want the first update and the delete", *sent)
+	}
+
+	// The window closes: the four held back repeats are summarized.
+	timers.fire()
+	if len(*sent) != 3 || (*sent)[2].Repeated != 4 {
+		t.Fatalf("sent %+v, want a summary of 4 repeats", *sent)
+	}
+	if msg := (*sent)[2].Message(); !strings.HasSuffix(msg, "(repeated 4 times)") {
+		t.Errorf("summary message = %q, want it to mention the repeats", msg)
+	}
+
+	// A quiet window ends the series, so the next repeat is sent right away.
+	timers.fire()
+	if len(*sent) != 3 {
+		t.Fatalf("sent %+v after a quiet window, want no more events", *sent)
+	}
+	timers.fire()
+	d.handle(backoff)
+	if len(*sent) != 4 || (*sent)[3].Repeated != 0 {
+		t.Errorf("sent %+v, want the update after the series ended", *sent)
+	}
+}
+
+func TestDedupFieldsTellEventsApart(t *testing.T) {
+	d, _, sent := newTestDedup(t, config.Dedup{Window: time.Minute, Fields: []string{"status.phase"}})
+
+	pod := func(phase api_v1.PodPhase) event.Event {
+		return event.Event{Kind: "Pod", Name: "api", Reason: "Updated", Obj: &api_v1.Pod{Status: api_v1.PodStatus{Phase: phase}}}
+	}
+	d.handle(pod(api_v1.PodPending))
+	d.handle(pod(api_v1.PodPending))
+	d.handle(pod(api_v1.PodRunning))
+
+	if len(*sent) != 2 || (*sent)[1].Obj.(*api_v1.Pod).Status.Phase != api_v1.PodRunning {
+		t.Errorf("sent %+v, want the Pending and the Running update", *sent)
+	}
+}
+
+func TestDedupFieldsWithDottedKeys(t *testing.T) {
+	d, _, sent := newTestDedup(t, config.Dedup{Window: time.Minute, Fields: []string{
+		`metadata.annotations["kubernetes.io/change-cause"]`,
+		"spec.template.spec.containers[].image",
+	}})
+
+	deployment := func(cause, image string) event.Event {
+		obj := &apps_v1.Deployment{}
+		obj.Annotations = map[string]string{"kubernetes.io/change-cause": cause}
+		obj.Spec.Template.Spec.Containers = []api_v1.Container{{Name: "api", Image: image}}
+		return event.Event{Kind: "Deployment", Name: "api", Reason: "Updated", Obj: obj}
+	}
+	d.handle(deployment("rollout 1", "api:1"))
+	d.handle(deployment("rollout 1", "api:1"))
+	d.handle(deployment("rollout 2", "api:1"))
+	d.handle(deployment("rollout 2", "api:2"))
+
+	if len(*sent) != 3 {
+		t.Errorf("sent %d events, want one per change cause and image", len(*sent))
+	}
+}
+
+func TestDedupWithoutWindowSendsEverything(t *testing.T) {
+	d, _, sent := newTestDedup(t, config.Dedup{})
+
+	e := event.Event{Kind: "Node", Name: "node-1", Reason: "Updated"}
+	d.handle(e)
+	d.handle(e)
+
+	if len(*sent) != 2 {
+		t.Errorf("sent %d events, want 2", len(*sent))
+	}
+}
+
+func TestNewDedupRejectsInvalidConfig(t *testing.T) {
+	for _, c := range []config.Dedup{
+		{Window: -time.Second},
+		{Window: time.Minute, Fields: []string{"status..phase"}},
+		{Window: time.Minute, Fields: []string{"metadata.annotations[kubernetes.io/change-cause]"}},
+	} {
+		if _, err := newDedup(c, func(event.Event) error { return nil }); err == nil {
+			t.Errorf("newDedup(%+v) succeeded, want an error", c)
+		}
+	}
+}
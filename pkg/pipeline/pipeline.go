@@ -0,0 +1,126 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package pipeline holds the processing shared by every handler. The
+// controller hands each event to a Pipeline, which runs it through the common
+// stages — filtering, CEL predicates, deduplication and sent-event accounting —
+// before passing it on to the configured handlers, so every destination gets
+// the same treatment.
+package pipeline
+
+import (
+	"fmt"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/filter"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers"
+	"github.com/bitnami-labs/kubewatch/pkg/metrics"
+	"github.com/bitnami-labs/kubewatch/pkg/predicate"
+	"github.com/sirupsen/logrus"
+)
+
+// Pipeline handler implements Handler interface,
+// run each event through the shared stages before handing it to the handlers
+type Pipeline struct {
+	next       handlers.Handler
+	filter     *filter.Filter
+	predicates []*predicate.Predicate
+	dedup      *dedup
+}
+
+// New returns a Pipeline feeding next.
+func New(next handlers.Handler) *Pipeline {
+	return &Pipeline{next: next}
+}
+
+// Init prepares the stages from the configuration and initializes the
+// handlers behind the pipeline.
+func (p *Pipeline) Init(c *config.Config) error {
+	f, err := filter.New(c.Filter)
+	if err != nil {
+		return err
+	}
+	p.filter = f
+
+	p.predicates = nil
+	for i, expression := range c.Filter.Expressions {
+		compiled, err := predicate.Compile(expression)
+		if err != nil {
+			return fmt.Errorf("filter expression %d: %v", i+1, err)
+		}
+		p.predicates = append(p.predicates, compiled)
+	}
+
+	d, err := newDedup(c.Dedup, p.send)
+	if err != nil {
+		return err
+	}
+	p.dedup = d
+
+	return p.next.Init(c)
+}
+
+// Handle runs the event through the stages and hands it to the handlers.
+func (p *Pipeline) Handle(e event.Event) error {
+	if !p.filter.ShouldSendEvent(e) {
+		logrus.Debugf("Event filtered out - Kind: %s, Reason: %s, Name: %s", e.Kind, e.Reason, e.Name)
+		return nil
+	}
+	if !p.matchesPredicates(e) {
+		return nil
+	}
+	return p.dedup.handle(e)
+}
+
+// send hands an event that made it through the stages to the handlers.
+func (p *Pipeline) send(e event.Event) error {
+	metrics.EventsSentTotal.WithLabelValues(e.Kind, eventType(e)).Inc()
+	return p.next.Handle(e)
+}
+
+// matchesPredicates reports whether every filter expression holds for the
+// event. An expression that fails to evaluate, e.g. because it selects a field
+// the object lacks, does not hold.
+func (p *Pipeline) matchesPredicates(e event.Event) bool {
+	for _, pred := range p.predicates {
+		ok, err := pred.Eval(e)
+		if err != nil {
+			logrus.Debugf("Event filtered out - Kind: %s, Reason: %s, Name: %s: evaluating %q: %v", e.Kind, e.Reason, e.Name, pred, err)
+			return false
+		}
+		if !ok {
+			logrus.Debugf("Event filtered out - Kind: %s, Reason: %s, Name: %s: %q is false", e.Kind, e.Reason, e.Name, pred)
+			return false
+		}
+	}
+	return true
+}
+
+// eventType maps event.Reason to the eventType label of the metrics, for
+// consistency with kubewatch_events_total.
+func eventType(e event.Event) string {
+	switch e.Reason {
+	case "Created":
+		return "create"
+	case "Updated":
+		return "update"
+	case "Deleted":
+		return "delete"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipeline
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	api_v1 "k8s.io/api/core/v1"
+)
+
+// recordingHandler stands in for the handlers behind the pipeline.
+type recordingHandler struct {
+	mutex       sync.Mutex
+	initialized bool
+	events      []event.Event
+}
+
+func (h *recordingHandler) Init(*config.Config) error {
+	h.initialized = true
+	return nil
+}
+
+func (h *recordingHandler) Handle(e event.Event) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.events = append(h.events, e)
+	return nil
+}
+
+func (h *recordingHandler) recorded() []event.Event {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return append([]event.Event(nil), h.events...)
+}
+
+func TestPipelineFiltersAndCountsForEveryHandler(t *testing.T) {
+	handler := &recordingHandler{}
+	p := New(handler)
+
+	c := &config.Config{}
+	c.Filter.Rules = []config.FilterRule{{Action: "exclude", Namespaces: []string{"kube-system"}}}
+	if err := p.Init(c); err != nil {
+		t.Fatalf("Init(): %v", err)
+	}
+	if !handler.initialized {
+		t.Error("Init() did not initialize the handlers behind the pipeline")
+	}
+
+	sent := metrics.EventsSentTotal.WithLabelValues("PipelineTestPod", "create")
+	before := testutil.ToFloat64(sent)
+
+	p.Handle(event.Event{Kind: "PipelineTestPod", Namespace: "kube-system", Name: "dns", Reason: "Created"})
+	p.Handle(event.Event{Kind: "PipelineTestPod", Namespace: "prod", Name: "api", Reason: "Created"})
+
+	events := handler.recorded()
+	if len(events) != 1 || events[0].Name != "api" {
+		t.Errorf("handler received %v, want only the api event", events)
+	}
+	if got := testutil.ToFloat64(sent) - before; got != 1 {
+		t.Errorf("kubewatch_events_sent_total grew by %v, want 1", got)
+	}
+}
+
+func TestPipelineInitRejectsInvalidFilter(t *testing.T) {
+	c := &config.Config{}
+	c.Filter.Presets = []string{"unknown"}
+	if err := New(&recordingHandler{}).Init(c); err == nil {
+		t.Error("Init() accepted an invalid filter")
+	}
+}
+
+func TestPipelineAppliesExpressions(t *testing.T) {
+	handler := &recordingHandler{}
+	p := New(handler)
+
+	c := &config.Config{}
+	c.Filter.Expressions = []string{"event.kind != 'Pod' || object.status.containerStatuses.exists(c, c.restartCount > 3)"}
+	if err := p.Init(c); err != nil {
+		t.Fatalf("Init(): %v", err)
+	}
+
+	restarts := func(n int32) *api_v1.Pod {
+		return &api_v1.Pod{Status: api_v1.PodStatus{ContainerStatuses: []api_v1.ContainerStatus{{RestartCount: n}}}}
+	}
+	p.Handle(event.Event{Kind: "Pod", Name: "stable", Obj: restarts(0)})
+	p.Handle(event.Event{Kind: "Pod", Name: "crashing", Obj: restarts(7)})
+	p.Handle(event.Event{Kind: "Pod", Name: "pending", Obj: &api_v1.Pod{}})
+	p.Handle(event.Event{Kind: "Service", Name: "api"})
+
+	var names []string
+	for _, e := range handler.recorded() {
+		names = append(names, e.Name)
+	}
+	if want := []string{"crashing", "api"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("handler received %v, want %v", names, want)
+	}
+}
+
+func TestPipelineInitRejectsInvalidExpression(t *testing.T) {
+	c := &config.Config{}
+	c.Filter.Expressions = []string{"object.spec.replicas >"}
+	err := New(&recordingHandler{}).Init(c)
+	if err == nil || !strings.Contains(err.Error(), "filter expression 1") {
+		t.Errorf("Init() error = %v, want an invalid expression error", err)
+	}
+}
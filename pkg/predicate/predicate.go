@@ -0,0 +1,119 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package predicate evaluates CEL expressions against events.
+//
+// An expression sees three variables:
+//
+//   - object is the object the event is about, as it appears in YAML or JSON,
+//     e.g. object.spec.replicas. It is an empty map on events without one.
+//   - oldObject is the previous version of the object on updates, and an empty
+//     map otherwise.
+//   - event carries the event itself: event.kind, event.name,
+//     event.namespace, event.reason, event.status and event.apiVersion.
+//
+// Typed objects and the unstructured objects produced by the customresources
+// informers are presented the same way, so one expression works for both.
+package predicate
+
+import (
+	"fmt"
+
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/utils"
+	"github.com/google/cel-go/cel"
+)
+
+// Predicate is a compiled CEL expression evaluating to a bool
+type Predicate struct {
+	expression string
+	program    cel.Program
+}
+
+var env *cel.Env
+
+func init() {
+	var err error
+	env, err = cel.NewEnv(
+		cel.Variable("object", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("oldObject", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("event", cel.MapType(cel.StringType, cel.StringType)),
+	)
+	if err != nil {
+		panic(fmt.Sprintf("creating CEL environment: %v", err))
+	}
+}
+
+// Compile parses and type-checks expression, which must evaluate to a bool.
+func Compile(expression string) (*Predicate, error) {
+	ast, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("invalid expression %q:\n%v", expression, issues.Err())
+	}
+	// Fields of object are only known at runtime, so dyn is accepted here
+	// and checked by Eval.
+	if out := ast.OutputType(); !out.IsExactType(cel.BoolType) && !out.IsExactType(cel.DynType) {
+		return nil, fmt.Errorf("expression %q evaluates to %s, want bool", expression, ast.OutputType())
+	}
+
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("expression %q: %v", expression, err)
+	}
+
+	return &Predicate{expression: expression, program: program}, nil
+}
+
+// String returns the source of the expression.
+func (p *Predicate) String() string {
+	return p.expression
+}
+
+// Eval evaluates the expression against an event. Errors, such as selecting a
+// field the object does not have, are returned rather than treated as false;
+// use has() to test for optional fields.
+func (p *Predicate) Eval(e event.Event) (bool, error) {
+	object, err := utils.ToUnstructured(e.Obj)
+	if err != nil {
+		return false, fmt.Errorf("converting %T for expression evaluation: %v", e.Obj, err)
+	}
+	oldObject, err := utils.ToUnstructured(e.OldObj)
+	if err != nil {
+		return false, fmt.Errorf("converting %T for expression evaluation: %v", e.OldObj, err)
+	}
+
+	out, _, err := p.program.Eval(map[string]interface{}{
+		"object":    object,
+		"oldObject": oldObject,
+		"event": map[string]string{
+			"kind":       e.Kind,
+			"name":       e.Name,
+			"namespace":  e.Namespace,
+			"reason":     e.Reason,
+			"status":     e.Status,
+			"apiVersion": e.ApiVersion,
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+
+	result, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("expression %q returned %v, want bool", p.expression, out.Value())
+	}
+	return result, nil
+}
@@ -0,0 +1,148 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package predicate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	apps_v1 "k8s.io/api/apps/v1"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func replicas(n int32) *int32 { return &n }
+
+func TestEval(t *testing.T) {
+	restarting := &api_v1.Pod{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "api", Namespace: "prod"},
+		Status: api_v1.PodStatus{ContainerStatuses: []api_v1.ContainerStatus{
+			{Name: "api", RestartCount: 5},
+		}},
+	}
+	scaled := event.Event{
+		Kind:   "Deployment",
+		Reason: "Updated",
+		Obj:    &apps_v1.Deployment{Spec: apps_v1.DeploymentSpec{Replicas: replicas(5)}},
+		OldObj: &apps_v1.Deployment{Spec: apps_v1.DeploymentSpec{Replicas: replicas(3)}},
+	}
+	certificate := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "cert-manager.io/v1",
+		"kind":       "Certificate",
+		"status": map[string]interface{}{
+			"conditions": []interface{}{
+				map[string]interface{}{"type": "Ready", "status": "False"},
+			},
+		},
+	}}
+
+	tests := []struct {
+		name       string
+		expression string
+		event      event.Event
+		expected   bool
+	}{
+		{
+			"typed object",
+			"object.status.containerStatuses.exists(c, c.restartCount > 3)",
+			event.Event{Kind: "Pod", Obj: restarting},
+			true,
+		},
+		{
+			"typed object below threshold",
+			"object.status.containerStatuses.exists(c, c.restartCount > 10)",
+			event.Event{Kind: "Pod", Obj: restarting},
+			false,
+		},
+		{
+			"old and new object",
+			"oldObject.spec.replicas != object.spec.replicas",
+			scaled,
+			true,
+		},
+		{
+			"unstructured object",
+			"object.status.conditions.exists(c, c.type == 'Ready' && c.status == 'False')",
+			event.Event{Kind: "Certificate", Obj: certificate},
+			true,
+		},
+		{
+			"event fields",
+			"event.kind == 'Pod' && event.namespace.startsWith('prod')",
+			event.Event{Kind: "Pod", Namespace: "prod-eu"},
+			true,
+		},
+		{
+			"dyn field",
+			"object.spec.paused",
+			event.Event{Kind: "Deployment", Obj: &apps_v1.Deployment{Spec: apps_v1.DeploymentSpec{Paused: true}}},
+			true,
+		},
+		{
+			"missing old object",
+			"has(oldObject.spec)",
+			event.Event{Kind: "Pod", Obj: restarting},
+			false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Compile(tt.expression)
+			if err != nil {
+				t.Fatalf("Compile(): %v", err)
+			}
+			result, err := p.Eval(tt.event)
+			if err != nil {
+				t.Fatalf("Eval(): %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestEvalMissingField(t *testing.T) {
+	p, err := Compile("object.spec.replicas > 1")
+	if err != nil {
+		t.Fatalf("Compile(): %v", err)
+	}
+	if _, err := p.Eval(event.Event{Kind: "Pod", Obj: &api_v1.Pod{}}); err == nil {
+		t.Error("Eval() selecting a missing field succeeded, want an error")
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+	}{
+		{"object.spec.replicas >", "invalid expression"},
+		{"event.name", "want bool"},
+		{"pod.spec.replicas > 1", "undeclared reference to 'pod'"},
+	}
+
+	for _, tt := range tests {
+		_, err := Compile(tt.expression)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Compile(%q) error = %v, want it to mention %q", tt.expression, err, tt.want)
+		}
+	}
+}
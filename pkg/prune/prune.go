@@ -0,0 +1,221 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package prune removes configured fields from the objects kubewatch
+// receives, before the informers cache them, so that large fields nobody
+// looks at, such as metadata.managedFields, cost no memory and never reach
+// the diffs, the redaction or the handlers. Pruned managed fields keep the
+// manager of the latest write, which message templates read as .Manager.
+package prune
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/diff"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Rules decides which fields are removed from each kind of object
+type Rules struct {
+	// all applies to every kind, byKind to the lowercased kinds it is keyed
+	// by; each path is split into its keys, an empty key standing for any
+	// list index
+	all    [][]string
+	byKind map[string][][]string
+}
+
+// NewRules validates the configured rules.
+func NewRules(c []config.PrunePathRule) (*Rules, error) {
+	r := &Rules{byKind: map[string][][]string{}}
+
+	for i, rule := range c {
+		if len(rule.Paths) == 0 {
+			return nil, fmt.Errorf("prune path rule %d: no paths", i)
+		}
+		paths := make([][]string, 0, len(rule.Paths))
+		for _, p := range rule.Paths {
+			keys, err := diff.SplitPath(p)
+			if err != nil {
+				return nil, fmt.Errorf("prune path rule %d: %v", i, err)
+			}
+			if keys[len(keys)-1] == "" {
+				return nil, fmt.Errorf("prune path rule %d: path %q must name a field, not the items of a list", i, p)
+			}
+			paths = append(paths, keys)
+		}
+
+		if len(rule.Kinds) == 0 {
+			r.all = append(r.all, paths...)
+			continue
+		}
+		for _, kind := range rule.Kinds {
+			kind = strings.ToLower(kind)
+			r.byKind[kind] = append(r.byKind[kind], paths...)
+		}
+	}
+
+	return r, nil
+}
+
+// For returns the paths removed from objects of the given kind, which is
+// matched case-insensitively.
+func (r *Rules) For(kind string) [][]string {
+	if r == nil {
+		return nil
+	}
+	return append(append([][]string(nil), r.all...), r.byKind[strings.ToLower(kind)]...)
+}
+
+// Transform returns the informer transform removing the paths of kind from
+// the objects, or nil when there is nothing to remove.
+func (r *Rules) Transform(kind string) cache.TransformFunc {
+	paths := r.For(kind)
+	if len(paths) == 0 {
+		return nil
+	}
+	return func(obj interface{}) (interface{}, error) {
+		switch o := obj.(type) {
+		case *unstructured.Unstructured:
+			removeAll(o.Object, paths)
+			return o, nil
+		case runtime.Object:
+			return pruneTyped(o, paths), nil
+		}
+		// e.g. cache.DeletedFinalStateUnknown, whose object was pruned
+		// when it was cached
+		return obj, nil
+	}
+}
+
+// pruneTyped removes the paths from a typed object through its unstructured
+// form, and returns a new object when anything was removed.
+func pruneTyped(obj runtime.Object, paths [][]string) runtime.Object {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		logrus.Warnf("Cannot prune %T: %v", obj, err)
+		return obj
+	}
+	if !removeAll(content, paths) {
+		return obj
+	}
+
+	pruned, ok := reflect.New(reflect.TypeOf(obj).Elem()).Interface().(runtime.Object)
+	if !ok {
+		return obj
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(content, pruned); err != nil {
+		logrus.Warnf("Cannot prune %T: %v", obj, err)
+		return obj
+	}
+	return pruned
+}
+
+// managedFields is trimmed down to its latest entry rather than removed
+var managedFields = []string{"metadata", "managedFields"}
+
+// removeAll removes the paths from content, and reports whether any was
+// there.
+func removeAll(content map[string]interface{}, paths [][]string) bool {
+	removed := false
+	for _, keys := range paths {
+		if reflect.DeepEqual(keys, managedFields) {
+			if trimManagedFields(content) {
+				removed = true
+			}
+			continue
+		}
+		if remove(content, keys) {
+			removed = true
+		}
+	}
+	return removed
+}
+
+// trimManagedFields replaces the managed fields of content with the manager,
+// operation and time of the latest write, dropping the fields each manager
+// owns, which make up most of their size. It reports whether there were
+// managed fields.
+func trimManagedFields(content map[string]interface{}) bool {
+	metadata, ok := content["metadata"].(map[string]interface{})
+	if !ok {
+		return false
+	}
+	entries, found := metadata["managedFields"].([]interface{})
+	if !found {
+		return remove(content, managedFields)
+	}
+
+	var latest map[string]interface{}
+	var latestTime time.Time
+	for _, e := range entries {
+		entry, ok := e.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		stamp, _ := entry["time"].(string)
+		t, err := time.Parse(time.RFC3339, stamp)
+		if err != nil {
+			continue
+		}
+		if latest == nil || t.After(latestTime) {
+			latest, latestTime = entry, t
+		}
+	}
+	if latest == nil {
+		delete(metadata, "managedFields")
+		return true
+	}
+	metadata["managedFields"] = []interface{}{map[string]interface{}{
+		"manager":   latest["manager"],
+		"operation": latest["operation"],
+		"time":      latest["time"],
+	}}
+	return true
+}
+
+func remove(value interface{}, keys []string) bool {
+	if keys[0] == "" {
+		list, ok := value.([]interface{})
+		if !ok {
+			return false
+		}
+		removed := false
+		for _, item := range list {
+			if remove(item, keys[1:]) {
+				removed = true
+			}
+		}
+		return removed
+	}
+
+	fields, ok := value.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	if len(keys) == 1 {
+		_, found := fields[keys[0]]
+		delete(fields, keys[0])
+		return found
+	}
+	return remove(fields[keys[0]], keys[1:])
+}
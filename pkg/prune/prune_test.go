@@ -0,0 +1,182 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package prune
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	apps_v1 "k8s.io/api/apps/v1"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/cache"
+)
+
+const lastApplied = "kubectl.kubernetes.io/last-applied-configuration"
+
+var sampleRules = []config.PrunePathRule{
+	{Paths: []string{"metadata.managedFields", `metadata.annotations["` + lastApplied + `"]`}},
+	{Kinds: []string{"configmap"}, Paths: []string{"data", "binaryData"}},
+	{Kinds: []string{"Pod"}, Paths: []string{"spec.containers[].env"}},
+}
+
+func TestTransformTypedObjects(t *testing.T) {
+	rules, err := NewRules(sampleRules)
+	if err != nil {
+		t.Fatalf("NewRules(): %v", err)
+	}
+
+	cm := &api_v1.ConfigMap{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:          "settings",
+			Namespace:     "default",
+			Labels:        map[string]string{"app": "api"},
+			Annotations:   map[string]string{lastApplied: "{}", "owner": "payments"},
+			ManagedFields: []meta_v1.ManagedFieldsEntry{{Manager: "kubectl"}},
+		},
+		Data:       map[string]string{"config.yaml": strings.Repeat("x", 1024)},
+		BinaryData: map[string][]byte{"blob": []byte("data")},
+	}
+	obj, err := rules.Transform("ConfigMap")(cm)
+	if err != nil {
+		t.Fatalf("Transform(): %v", err)
+	}
+	pruned, ok := obj.(*api_v1.ConfigMap)
+	if !ok {
+		t.Fatalf("Transform() returned a %T, want a *v1.ConfigMap", obj)
+	}
+	if pruned.Data != nil || pruned.BinaryData != nil || pruned.ManagedFields != nil {
+		t.Errorf("Transform() left data %v, binary data %v, managed fields %v", pruned.Data, pruned.BinaryData, pruned.ManagedFields)
+	}
+	if _, ok := pruned.Annotations[lastApplied]; ok || pruned.Annotations["owner"] != "payments" {
+		t.Errorf("Transform() annotations = %v, want only the owner", pruned.Annotations)
+	}
+	if pruned.Name != "settings" || pruned.Labels["app"] != "api" {
+		t.Errorf("Transform() = %+v, want the other fields kept", pruned.ObjectMeta)
+	}
+
+	pod := &api_v1.Pod{Spec: api_v1.PodSpec{Containers: []api_v1.Container{
+		{Name: "api", Env: []api_v1.EnvVar{{Name: "TOKEN", Value: "secret"}}},
+		{Name: "proxy"},
+	}}}
+	obj, _ = rules.Transform("pod")(pod)
+	if containers := obj.(*api_v1.Pod).Spec.Containers; len(containers) != 2 || containers[0].Env != nil || containers[0].Name != "api" {
+		t.Errorf("Transform() containers = %+v, want them without env", containers)
+	}
+
+	untouched := &api_v1.ConfigMap{ObjectMeta: meta_v1.ObjectMeta{Name: "empty"}}
+	if obj, _ := rules.Transform("ConfigMap")(untouched); obj != untouched {
+		t.Errorf("Transform() copied an object it removed nothing from")
+	}
+}
+
+func TestTransformUnstructuredObjects(t *testing.T) {
+	rules, err := NewRules(sampleRules)
+	if err != nil {
+		t.Fatalf("NewRules(): %v", err)
+	}
+
+	cert := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "cert-manager.io/v1",
+		"kind":       "Certificate",
+		"metadata": map[string]interface{}{
+			"name":          "web",
+			"managedFields": []interface{}{map[string]interface{}{"manager": "kubectl"}},
+		},
+		"data": "kept",
+	}}
+	obj, err := rules.Transform("Certificate")(cert)
+	if err != nil {
+		t.Fatalf("Transform(): %v", err)
+	}
+	pruned := obj.(*unstructured.Unstructured)
+	if _, found, _ := unstructured.NestedFieldNoCopy(pruned.Object, "metadata", "managedFields"); found {
+		t.Errorf("Transform() left the managed fields")
+	}
+	if pruned.GetName() != "web" || pruned.Object["data"] != "kept" {
+		t.Errorf("Transform() = %v, want the other fields kept", pruned.Object)
+	}
+
+	tombstone := cache.DeletedFinalStateUnknown{Key: "default/web", Obj: cert}
+	if obj, err := rules.Transform("Certificate")(tombstone); err != nil || obj != tombstone {
+		t.Errorf("Transform() of a tombstone = %v, %v, want it unchanged", obj, err)
+	}
+}
+
+func TestPrunedManagedFieldsKeepTheLatestManager(t *testing.T) {
+	rules, err := NewRules(sampleRules)
+	if err != nil {
+		t.Fatalf("NewRules(): %v", err)
+	}
+
+	earlier := meta_v1.NewTime(time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC))
+	later := meta_v1.NewTime(earlier.Add(time.Hour))
+	fields := &meta_v1.FieldsV1{Raw: []byte(`{"f:spec":{"f:replicas":{}}}`)}
+	deployment := &apps_v1.Deployment{ObjectMeta: meta_v1.ObjectMeta{
+		Name: "api",
+		ManagedFields: []meta_v1.ManagedFieldsEntry{
+			{Manager: "kubectl-client-side-apply", Operation: meta_v1.ManagedFieldsOperationUpdate, Time: &earlier, FieldsType: "FieldsV1", FieldsV1: fields},
+			{Manager: "kubectl-scale", Operation: meta_v1.ManagedFieldsOperationUpdate, Time: &later, FieldsType: "FieldsV1", FieldsV1: fields, Subresource: "scale"},
+			{Manager: "undated", FieldsV1: fields},
+		},
+	}}
+	obj, err := rules.Transform("Deployment")(deployment)
+	if err != nil {
+		t.Fatalf("Transform(): %v", err)
+	}
+	got := obj.(*apps_v1.Deployment).ManagedFields
+	if len(got) != 1 || got[0].Manager != "kubectl-scale" || got[0].Operation != meta_v1.ManagedFieldsOperationUpdate ||
+		got[0].Time == nil || !got[0].Time.Equal(&later) || got[0].FieldsV1 != nil || got[0].Subresource != "" {
+		t.Errorf("Transform() managed fields = %+v, want only the manager, operation and time of the kubectl-scale entry", got)
+	}
+}
+
+func TestNoTransformWithoutPaths(t *testing.T) {
+	rules, err := NewRules([]config.PrunePathRule{{Kinds: []string{"ConfigMap"}, Paths: []string{"data"}}})
+	if err != nil {
+		t.Fatalf("NewRules(): %v", err)
+	}
+	if rules.Transform("Secret") != nil {
+		t.Errorf("Transform(Secret) is set, want none")
+	}
+	var none *Rules
+	if none.Transform("ConfigMap") != nil {
+		t.Errorf("Transform() of nil rules is set, want none")
+	}
+}
+
+func TestNewRulesRejectsInvalidRules(t *testing.T) {
+	var Tests = []struct {
+		rule config.PrunePathRule
+		err  string
+	}{
+		{config.PrunePathRule{Kinds: []string{"Pod"}}, "no paths"},
+		{config.PrunePathRule{Paths: []string{"spec.containers[0]"}}, "only [] and"},
+		{config.PrunePathRule{Paths: []string{"spec.containers[]"}}, "not the items of a list"},
+		{config.PrunePathRule{Paths: []string{""}}, "empty path"},
+	}
+
+	for _, tt := range Tests {
+		_, err := NewRules([]config.PrunePathRule{tt.rule})
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("NewRules(%+v) error = %v, want one containing %q", tt.rule, err, tt.err)
+		}
+	}
+}
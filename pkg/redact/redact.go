@@ -38,7 +38,9 @@ import (
 	"bytes"
 	"encoding/base64"
 	"encoding/json"
+	"strings"
 
+	"github.com/bitnami-labs/kubewatch/pkg/diff"
 	api_v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -176,3 +178,47 @@ func redactUnstructuredData(object map[string]interface{}) bool {
 	}
 	return redacted
 }
+
+// Changes redacts the values of changes to a Secret's data fields, so an
+// update can say which keys changed without saying what they hold. obj is
+// either version of the object the changes were computed on; changes to
+// other kinds of objects are returned as they are.
+func Changes(obj runtime.Object, changes []diff.Change) []diff.Change {
+	if !isSecret(obj) {
+		return changes
+	}
+
+	redacted := make([]diff.Change, len(changes))
+	for i, change := range changes {
+		if isDataPath(change.Path) {
+			if change.Old != nil {
+				change.Old = Placeholder
+			}
+			if change.New != nil {
+				change.New = Placeholder
+			}
+		}
+		redacted[i] = change
+	}
+	return redacted
+}
+
+func isSecret(obj runtime.Object) bool {
+	switch typed := obj.(type) {
+	case *api_v1.Secret:
+		return true
+	case *unstructured.Unstructured:
+		return typed != nil && typed.GetKind() == secretKind
+	}
+	return false
+}
+
+// isDataPath reports whether a diff path lies within one of the dataFields
+func isDataPath(path string) bool {
+	for field := range dataFields {
+		if path == field || strings.HasPrefix(path, field+".") || strings.HasPrefix(path, field+"[") {
+			return true
+		}
+	}
+	return false
+}
@@ -19,9 +19,11 @@ package redact
 import (
 	"encoding/base64"
 	"encoding/json"
+	"reflect"
 	"strings"
 	"testing"
 
+	"github.com/bitnami-labs/kubewatch/pkg/diff"
 	api_v1 "k8s.io/api/core/v1"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -256,3 +258,37 @@ func TestJSONRejectsInvalidPayload(t *testing.T) {
 		t.Error("JSON() accepted invalid JSON, want error")
 	}
 }
+
+func TestChangesRedactsSecretData(t *testing.T) {
+	changes := []diff.Change{
+		{Path: "data.password", Old: sentinel, New: sentinel + "-ROTATED"},
+		{Path: `data["tls.key"]`, New: sentinel},
+		{Path: "stringData", Old: map[string]interface{}{"token": sentinel}},
+		{Path: "metadata.labels.team", Old: "a", New: "b"},
+	}
+
+	got := Changes(secret("creds"), changes)
+
+	assertNoSentinel(t, "changes", got)
+	want := []diff.Change{
+		{Path: "data.password", Old: Placeholder, New: Placeholder},
+		{Path: `data["tls.key"]`, New: Placeholder},
+		{Path: "stringData", Old: Placeholder},
+		{Path: "metadata.labels.team", Old: "a", New: "b"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Changes() = %v, want %v", got, want)
+	}
+	if changes[0].Old != sentinel {
+		t.Errorf("Changes() modified its input")
+	}
+}
+
+func TestChangesPassesThroughNonSecrets(t *testing.T) {
+	changes := []diff.Change{{Path: "data.password", Old: "a", New: "b"}}
+	configMap := &api_v1.ConfigMap{ObjectMeta: meta_v1.ObjectMeta{Name: "settings"}}
+
+	if got := Changes(configMap, changes); !reflect.DeepEqual(got, changes) {
+		t.Errorf("Changes() = %v, want %v", got, changes)
+	}
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func gvr(group, version, resource string) schema.GroupVersionResource {
+	return schema.GroupVersionResource{Group: group, Version: version, Resource: resource}
+}
+
+// Builtin lists the resources of Kubernetes itself kubewatch can watch.
+// Adding one here is enough for kubewatch to watch it when configured to.
+var Builtin = []Resource{
+	{Name: "pod", Aliases: []string{"po", "pods"}, Flag: "po", Env: "KW_POD", Description: "pods",
+		GVR: gvr("", "v1", "pods"), Kind: "Pod"},
+	{Name: "service", Aliases: []string{"svc", "services"}, Flag: "svc", Env: "KW_SERVICE", Description: "services",
+		GVR: gvr("", "v1", "services"), Kind: "Service"},
+	{Name: "namespace", Aliases: []string{"ns", "namespaces"}, Flag: "ns", Env: "KW_NAMESPACE", Description: "namespaces",
+		GVR: gvr("", "v1", "namespaces"), Kind: "Namespace", ClusterScoped: true},
+	{Name: "replicationcontroller", Aliases: []string{"rc", "replicationcontrollers"}, Flag: "rc", Env: "KW_REPLICATION_CONTROLLER", Description: "replication controllers",
+		GVR: gvr("", "v1", "replicationcontrollers"), Kind: "ReplicationController"},
+	{Name: "node", Aliases: []string{"no", "nodes"}, Flag: "node", Env: "KW_NODE", Description: "Nodes",
+		GVR: gvr("", "v1", "nodes"), Kind: "Node", ClusterScoped: true},
+	{Name: "serviceaccount", Aliases: []string{"sa", "serviceaccounts"}, Flag: "sa", Env: "KW_SERVICE_ACCOUNT", Description: "service accounts",
+		GVR: gvr("", "v1", "serviceaccounts"), Kind: "ServiceAccount"},
+	{Name: "persistentvolume", Aliases: []string{"pv", "persistentvolumes"}, Flag: "pv", Env: "KW_PERSISTENT_VOLUME", Description: "persistent volumes",
+		GVR: gvr("", "v1", "persistentvolumes"), Kind: "PersistentVolume", ClusterScoped: true},
+	{Name: "persistentvolumeclaim", Aliases: []string{"pvc", "persistentvolumeclaims"}, Flag: "pvc", Env: "KW_PERSISTENT_VOLUME_CLAIM", Description: "persistent volume claims",
+		GVR: gvr("", "v1", "persistentvolumeclaims"), Kind: "PersistentVolumeClaim"},
+	{Name: "secret", Aliases: []string{"secrets"}, Flag: "secret", Env: "KW_SECRET", Description: "plain secrets",
+		GVR: gvr("", "v1", "secrets"), Kind: "Secret"},
+	{Name: "configmap", Aliases: []string{"cm", "configmaps"}, Flag: "cm", Env: "KW_CONFIGMAP", Description: "plain configmaps",
+		GVR: gvr("", "v1", "configmaps"), Kind: "ConfigMap"},
+	{Name: "endpoints", Aliases: []string{"ep"}, Flag: "ep", Env: "KW_ENDPOINTS", Description: "endpoints",
+		GVR: gvr("", "v1", "endpoints"), Kind: "Endpoints"},
+	{Name: "coreevent", Flag: "coreevent", Description: "events (old events object)",
+		GVR: gvr("", "v1", "events"), Kind: "Event"},
+	{Name: "deployment", Aliases: []string{"deploy", "deployments"}, Flag: "deploy", Env: "KW_DEPLOYMENT", Description: "deployments",
+		GVR: gvr("apps", "v1", "deployments"), Kind: "Deployment"},
+	{Name: "replicaset", Aliases: []string{"rs", "replicasets"}, Flag: "rs", Env: "KW_REPLICASET", Description: "replicasets",
+		GVR: gvr("apps", "v1", "replicasets"), Kind: "ReplicaSet"},
+	{Name: "daemonset", Aliases: []string{"ds", "daemonsets"}, Flag: "ds", Env: "KW_DAEMONSET", Description: "daemonsets",
+		GVR: gvr("apps", "v1", "daemonsets"), Kind: "DaemonSet"},
+	{Name: "statefulset", Aliases: []string{"sts", "statefulsets"}, Flag: "statefulset", Description: "statefulsets",
+		GVR: gvr("apps", "v1", "statefulsets"), Kind: "StatefulSet"},
+	{Name: "job", Aliases: []string{"jobs"}, Flag: "job", Env: "KW_JOB", Description: "jobs",
+		GVR: gvr("batch", "v1", "jobs"), Kind: "Job"},
+	{Name: "cronjob", Aliases: []string{"cj", "cronjobs"}, Flag: "cj", Env: "KW_CRONJOB", Description: "cron jobs",
+		GVR: gvr("batch", "v1", "cronjobs"), Kind: "CronJob"},
+	{Name: "hpa", Aliases: []string{"horizontalpodautoscaler", "horizontalpodautoscalers"}, Description: "horizontal pod autoscalers",
+		GVR: gvr("autoscaling", "v1", "horizontalpodautoscalers"), Kind: "HorizontalPodAutoscaler"},
+	{Name: "ingress", Aliases: []string{"ing", "ingresses"}, Flag: "ing", Env: "KW_INGRESS", Description: "ingresses",
+		GVR: gvr("networking.k8s.io", "v1", "ingresses"), Kind: "Ingress"},
+	{Name: "networkpolicy", Aliases: []string{"netpol", "networkpolicies"}, Flag: "netpol", Env: "KW_NETWORK_POLICY", Description: "network policies",
+		GVR: gvr("networking.k8s.io", "v1", "networkpolicies"), Kind: "NetworkPolicy"},
+	{Name: "endpointslice", Aliases: []string{"endpointslices"}, Flag: "endpointslice", Env: "KW_ENDPOINT_SLICE", Description: "endpoint slices",
+		GVR: gvr("discovery.k8s.io", "v1", "endpointslices"), Kind: "EndpointSlice"},
+	{Name: "poddisruptionbudget", Aliases: []string{"pdb", "poddisruptionbudgets"}, Flag: "pdb", Env: "KW_POD_DISRUPTION_BUDGET", Description: "pod disruption budgets",
+		GVR: gvr("policy", "v1", "poddisruptionbudgets"), Kind: "PodDisruptionBudget"},
+	{Name: "storageclass", Aliases: []string{"sc", "storageclasses"}, Flag: "sc", Env: "KW_STORAGE_CLASS", Description: "storage classes",
+		GVR: gvr("storage.k8s.io", "v1", "storageclasses"), Kind: "StorageClass", ClusterScoped: true},
+	{Name: "priorityclass", Aliases: []string{"pc", "priorityclasses"}, Flag: "pc", Env: "KW_PRIORITY_CLASS", Description: "priority classes",
+		GVR: gvr("scheduling.k8s.io", "v1", "priorityclasses"), Kind: "PriorityClass", ClusterScoped: true},
+	{Name: "clusterrole", Aliases: []string{"clusterroles"}, Flag: "clusterrole", Env: "KW_CLUSTER_ROLE", Description: "cluster roles",
+		GVR: gvr("rbac.authorization.k8s.io", "v1", "clusterroles"), Kind: "ClusterRole", ClusterScoped: true},
+	{Name: "clusterrolebinding", Aliases: []string{"clusterrolebindings"}, Flag: "clusterrolebinding", Env: "KW_CLUSTER_ROLE_BINDING", Description: "cluster role bindings",
+		GVR: gvr("rbac.authorization.k8s.io", "v1", "clusterrolebindings"), Kind: "ClusterRoleBinding", ClusterScoped: true},
+	{Name: "role", Aliases: []string{"roles"}, Flag: "role", Env: "KW_ROLE", Description: "roles",
+		GVR: gvr("rbac.authorization.k8s.io", "v1", "roles"), Kind: "Role"},
+	{Name: "rolebinding", Aliases: []string{"rolebindings"}, Flag: "rolebinding", Env: "KW_ROLE_BINDING", Description: "role bindings",
+		GVR: gvr("rbac.authorization.k8s.io", "v1", "rolebindings"), Kind: "RoleBinding"},
+	{Name: "validatingwebhookconfiguration", Aliases: []string{"validatingwebhookconfigurations"}, Flag: "validatingwebhookconfiguration", Env: "KW_VALIDATING_WEBHOOK_CONFIGURATION", Description: "validating admission webhook configurations",
+		GVR: gvr("admissionregistration.k8s.io", "v1", "validatingwebhookconfigurations"), Kind: "ValidatingWebhookConfiguration", ClusterScoped: true},
+	{Name: "mutatingwebhookconfiguration", Aliases: []string{"mutatingwebhookconfigurations"}, Flag: "mutatingwebhookconfiguration", Env: "KW_MUTATING_WEBHOOK_CONFIGURATION", Description: "mutating admission webhook configurations",
+		GVR: gvr("admissionregistration.k8s.io", "v1", "mutatingwebhookconfigurations"), Kind: "MutatingWebhookConfiguration", ClusterScoped: true},
+	{Name: "event", Description: "events",
+		GVR: gvr("events.k8s.io", "v1", "events"), Kind: "Event"},
+	// The typed informers do not cover the API extensions, so CRDs are
+	// watched through the dynamic client.
+	{Name: "customresourcedefinition", Aliases: []string{"crd", "crds", "customresourcedefinitions"}, Flag: "crd", Env: "KW_CUSTOM_RESOURCE_DEFINITION", Description: "custom resource definitions",
+		GVR: gvr("apiextensions.k8s.io", "v1", "customresourcedefinitions"), Kind: "CustomResourceDefinition", ClusterScoped: true, Dynamic: true},
+}
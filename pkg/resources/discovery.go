@@ -0,0 +1,112 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/restmapper"
+)
+
+// Resolver finds resources by name: in a registry first, then among the
+// resources the cluster serves, through API discovery.
+type Resolver struct {
+	registry *Registry
+	mapper   meta.RESTMapper
+}
+
+// NewResolver returns a resolver looking names up in registry, then asking
+// the cluster behind client.
+func NewResolver(registry *Registry, client discovery.DiscoveryInterface) *Resolver {
+	cached := memory.NewMemCacheClient(client)
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(cached)
+	return &Resolver{
+		registry: registry,
+		mapper:   restmapper.NewShortcutExpander(mapper, cached, nil),
+	}
+}
+
+// Resolve returns the resource going by name, which is a registered name, or
+// a resource, short name or kind the cluster serves, optionally qualified by
+// its group, e.g. cronjobs, cj, Certificate or leases.coordination.k8s.io.
+// Resources served in several versions are watched in the preferred one.
+//
+// The error satisfies meta.IsNoMatchError when the cluster serves no such
+// resource, which may change once a CRD is installed.
+func (r *Resolver) Resolve(name string) (Resource, error) {
+	if res, err := r.registry.Lookup(name); err == nil {
+		return res, nil
+	}
+
+	gvrs, err := r.mapper.ResourcesFor(schema.ParseGroupResource(name).WithVersion(""))
+	if err != nil {
+		return Resource{}, fmt.Errorf("unknown resource %q: %w", name, err)
+	}
+	// Matches come in order of preference, so the first one of a group is
+	// in its preferred version.
+	var groups []string
+	seen := map[schema.GroupResource]bool{}
+	for _, gvr := range gvrs {
+		if !seen[gvr.GroupResource()] {
+			seen[gvr.GroupResource()] = true
+			groups = append(groups, gvr.GroupResource().String())
+		}
+	}
+	if len(groups) > 1 {
+		return Resource{}, fmt.Errorf("resource %q is ambiguous, name one of: %s", name, strings.Join(groups, ", "))
+	}
+
+	res, err := r.ResolveGVR(gvrs[0])
+	if err != nil {
+		return Resource{}, fmt.Errorf("resource %q: %w", name, err)
+	}
+	return res, nil
+}
+
+// ResolveGVR returns the resource gvr stands for: the registered one, or the
+// one the cluster serves, with its kind and scope.
+//
+// The error satisfies meta.IsNoMatchError when the cluster serves no such
+// resource, which may change once a CRD is installed.
+func (r *Resolver) ResolveGVR(gvr schema.GroupVersionResource) (Resource, error) {
+	if res, ok := r.registry.Get(gvr); ok {
+		return res, nil
+	}
+	gvk, err := r.mapper.KindFor(gvr)
+	if err != nil {
+		return Resource{}, err
+	}
+	mapping, err := r.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return Resource{}, err
+	}
+	res := Custom(gvr)
+	res.Kind = gvk.Kind
+	res.ClusterScoped = mapping.Scope.Name() == meta.RESTScopeNameRoot
+	return res, nil
+}
+
+// Reset forgets what the cluster served, so that resources installed since
+// are found.
+func (r *Resolver) Reset() {
+	meta.MaybeResetRESTMapper(r.mapper)
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+)
+
+func fakeDiscovery(lists ...*metav1.APIResourceList) *fakediscovery.FakeDiscovery {
+	client := fakeclientset.NewSimpleClientset().Discovery().(*fakediscovery.FakeDiscovery)
+	client.Resources = lists
+	return client
+}
+
+var servedResources = []*metav1.APIResourceList{
+	{
+		GroupVersion: "v1",
+		APIResources: []metav1.APIResource{
+			{Name: "pods", Kind: "Pod", Namespaced: true, ShortNames: []string{"po"}},
+		},
+	},
+	{
+		GroupVersion: "apps/v1",
+		APIResources: []metav1.APIResource{
+			{Name: "deployments", Kind: "Deployment", Namespaced: true},
+		},
+	},
+	{
+		GroupVersion: "batch/v1",
+		APIResources: []metav1.APIResource{
+			{Name: "cronjobs", Kind: "CronJob", Namespaced: true, ShortNames: []string{"cj"}},
+		},
+	},
+	{
+		GroupVersion: "coordination.k8s.io/v1",
+		APIResources: []metav1.APIResource{
+			{Name: "leases", Kind: "Lease", Namespaced: true},
+		},
+	},
+	{
+		GroupVersion: "cert-manager.io/v1",
+		APIResources: []metav1.APIResource{
+			{Name: "certificates", Kind: "Certificate", Namespaced: true},
+		},
+	},
+	{
+		GroupVersion: "cert-manager.io/v1alpha2",
+		APIResources: []metav1.APIResource{
+			{Name: "certificates", Kind: "Certificate", Namespaced: true},
+		},
+	},
+}
+
+func TestResolve(t *testing.T) {
+	resolver := NewResolver(NewRegistry(), fakeDiscovery(servedResources...))
+
+	var Tests = []struct {
+		name string
+		gvr  schema.GroupVersionResource
+		kind string
+	}{
+		{"po", schema.GroupVersionResource{Version: "v1", Resource: "pods"}, "Pod"},
+		{"deployments.apps", schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}, "Deployment"},
+		{"cronjobs", schema.GroupVersionResource{Group: "batch", Version: "v1", Resource: "cronjobs"}, "CronJob"},
+		{"cj", schema.GroupVersionResource{Group: "batch", Version: "v1", Resource: "cronjobs"}, "CronJob"},
+		{"leases.coordination.k8s.io", schema.GroupVersionResource{Group: "coordination.k8s.io", Version: "v1", Resource: "leases"}, "Lease"},
+		{"Certificate", schema.GroupVersionResource{Group: "cert-manager.io", Version: "v1", Resource: "certificates"}, "Certificate"},
+	}
+
+	for _, tt := range Tests {
+		res, err := resolver.Resolve(tt.name)
+		if err != nil {
+			t.Fatalf("Resolve(%q): %v", tt.name, err)
+		}
+		if res.GVR != tt.gvr || res.Kind != tt.kind {
+			t.Errorf("Resolve(%q) = %s %s, want %s %s", tt.name, res.GVR, res.Kind, tt.gvr, tt.kind)
+		}
+	}
+}
+
+func TestResolveAmbiguous(t *testing.T) {
+	lists := append(servedResources, &metav1.APIResourceList{
+		GroupVersion: "acme.example.com/v1",
+		APIResources: []metav1.APIResource{
+			{Name: "certificates", Kind: "Certificate", Namespaced: true},
+		},
+	})
+	resolver := NewResolver(NewRegistry(), fakeDiscovery(lists...))
+
+	_, err := resolver.Resolve("Certificate")
+	if err == nil || !strings.Contains(err.Error(), "certificates.cert-manager.io") || !strings.Contains(err.Error(), "certificates.acme.example.com") {
+		t.Errorf("Resolve(Certificate) error = %v, want an error naming both groups", err)
+	}
+	if _, err := resolver.Resolve("certificates.acme.example.com"); err != nil {
+		t.Errorf("Resolve(certificates.acme.example.com): %v", err)
+	}
+}
+
+func TestResolveGVR(t *testing.T) {
+	resolver := NewResolver(NewRegistry(), fakeDiscovery(&metav1.APIResourceList{
+		GroupVersion: "cert-manager.io/v1",
+		APIResources: []metav1.APIResource{
+			{Name: "clusterissuers", Kind: "ClusterIssuer"},
+			{Name: "issuers", Kind: "Issuer", Namespaced: true},
+		},
+	}))
+
+	var Tests = []struct {
+		resource      string
+		kind          string
+		clusterScoped bool
+	}{
+		{"clusterissuers", "ClusterIssuer", true},
+		{"issuers", "Issuer", false},
+	}
+
+	for _, tt := range Tests {
+		res, err := resolver.ResolveGVR(schema.GroupVersionResource{Group: "cert-manager.io", Version: "v1", Resource: tt.resource})
+		if err != nil {
+			t.Fatalf("ResolveGVR(%s): %v", tt.resource, err)
+		}
+		if res.Kind != tt.kind || res.ClusterScoped != tt.clusterScoped || !res.Dynamic {
+			t.Errorf("ResolveGVR(%s) = %+v, want the dynamic %s, cluster-scoped %t", tt.resource, res, tt.kind, tt.clusterScoped)
+		}
+	}
+
+	if _, err := resolver.ResolveGVR(schema.GroupVersionResource{Group: "cert-manager.io", Version: "v2", Resource: "issuers"}); !meta.IsNoMatchError(err) {
+		t.Errorf("ResolveGVR() of a version the cluster does not serve error = %v, want a no match error", err)
+	}
+}
+
+func TestResolveAfterReset(t *testing.T) {
+	client := fakeDiscovery(servedResources...)
+	resolver := NewResolver(NewRegistry(), client)
+
+	_, err := resolver.Resolve("widgets.example.com")
+	if !meta.IsNoMatchError(err) {
+		t.Fatalf("Resolve() of a missing resource error = %v, want a no match error", err)
+	}
+
+	client.Resources = append(client.Resources, &metav1.APIResourceList{
+		GroupVersion: "example.com/v1",
+		APIResources: []metav1.APIResource{
+			{Name: "widgets", Kind: "Widget", Namespaced: true},
+		},
+	})
+	if _, err := resolver.Resolve("widgets.example.com"); err == nil {
+		t.Errorf("Resolve() found the new resource before Reset()")
+	}
+	resolver.Reset()
+	res, err := resolver.Resolve("widgets.example.com")
+	if err != nil {
+		t.Fatalf("Resolve() after Reset(): %v", err)
+	}
+	if res.Kind != "Widget" || !res.Dynamic {
+		t.Errorf("Resolve() after Reset() = %+v, want the dynamic Widget resource", res)
+	}
+}
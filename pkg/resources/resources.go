@@ -0,0 +1,155 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package resources lists the kinds of resources kubewatch can watch, keyed
+// by GroupVersionResource, and the names they go by in the configuration.
+package resources
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Resource is a kind of resource kubewatch can watch
+type Resource struct {
+	// Name identifies the resource in the configuration, e.g. pod
+	Name string
+	// Aliases are other names accepted for the resource, e.g. po or pods
+	Aliases []string
+	// Flag is the "kubewatch resource" flag adding or removing the
+	// resource, if any
+	Flag string
+	// Env is the environment variable turning the watch on, if any
+	Env string
+	// Description tells what the resource is, e.g. "pods"
+	Description string
+
+	GVR schema.GroupVersionResource
+	// Kind is the kind of the objects, as reported in events
+	Kind string
+	// ClusterScoped resources, such as nodes, live outside of namespaces
+	ClusterScoped bool
+	// Dynamic resources are watched through the dynamic client, as custom
+	// resources are, rather than through typed informers
+	Dynamic bool
+}
+
+// APIVersion returns the group and version of the resource, e.g. apps/v1
+func (r Resource) APIVersion() string {
+	return r.GVR.GroupVersion().String()
+}
+
+// names returns every name the resource goes by
+func (r Resource) names() []string {
+	return append([]string{r.Name}, r.Aliases...)
+}
+
+// Registry holds the resources kubewatch can watch, keyed by
+// GroupVersionResource
+type Registry struct {
+	resources map[schema.GroupVersionResource]Resource
+	byName    map[string]schema.GroupVersionResource
+}
+
+// NewRegistry returns a registry holding the built-in resources.
+func NewRegistry() *Registry {
+	r := &Registry{
+		resources: map[schema.GroupVersionResource]Resource{},
+		byName:    map[string]schema.GroupVersionResource{},
+	}
+	for _, res := range Builtin {
+		if err := r.Add(res); err != nil {
+			panic(err)
+		}
+	}
+	return r
+}
+
+// Add registers a resource. Names are case insensitive and must not be taken
+// by another resource.
+func (r *Registry) Add(res Resource) error {
+	if _, ok := r.resources[res.GVR]; ok {
+		return fmt.Errorf("resource %s is already registered", res.GVR)
+	}
+	for _, name := range res.names() {
+		if other, ok := r.byName[strings.ToLower(name)]; ok {
+			return fmt.Errorf("resource name %q of %s is taken by %s", name, res.GVR, other)
+		}
+	}
+
+	r.resources[res.GVR] = res
+	for _, name := range res.names() {
+		r.byName[strings.ToLower(name)] = res.GVR
+	}
+	return nil
+}
+
+// Get returns the resource registered under gvr.
+func (r *Registry) Get(gvr schema.GroupVersionResource) (Resource, bool) {
+	res, ok := r.resources[gvr]
+	return res, ok
+}
+
+// Lookup returns the resource going by the given name or alias.
+func (r *Registry) Lookup(name string) (Resource, error) {
+	gvr, ok := r.byName[strings.ToLower(name)]
+	if !ok {
+		return Resource{}, fmt.Errorf("unknown resource %q, known resources are: %s", name, strings.Join(r.Names(), ", "))
+	}
+	return r.resources[gvr], nil
+}
+
+// Names returns the names of the registered resources, sorted.
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.resources))
+	for _, res := range r.resources {
+		names = append(names, res.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// List returns the registered resources, sorted by name.
+func (r *Registry) List() []Resource {
+	list := make([]Resource, 0, len(r.resources))
+	for _, res := range r.resources {
+		list = append(list, res)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Custom returns a resource watched through the dynamic client, such as a
+// custom resource. It goes by its plural name and its group-qualified
+// name, e.g. prometheusrules and prometheusrules.monitoring.coreos.com, and
+// is taken to be namespaced, with its plural name as kind, until
+// Resolver.ResolveGVR finds out the ones the cluster serves it with.
+func Custom(gvr schema.GroupVersionResource) Resource {
+	res := Resource{
+		Name:        gvr.GroupResource().String(),
+		Description: gvr.Resource,
+		GVR:         gvr,
+		Kind:        gvr.Resource,
+		Dynamic:     true,
+	}
+	if gvr.Group != "" {
+		res.Aliases = []string{gvr.Resource}
+	}
+	return res
+}
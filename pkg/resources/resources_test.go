@@ -0,0 +1,81 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestLookup(t *testing.T) {
+	registry := NewRegistry()
+
+	var Tests = []struct {
+		name string
+		want schema.GroupVersionResource
+	}{
+		{"pod", schema.GroupVersionResource{Version: "v1", Resource: "pods"}},
+		{"po", schema.GroupVersionResource{Version: "v1", Resource: "pods"}},
+		{"Deployment", schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}},
+		{"rc", schema.GroupVersionResource{Version: "v1", Resource: "replicationcontrollers"}},
+	}
+
+	for _, tt := range Tests {
+		res, err := registry.Lookup(tt.name)
+		if err != nil {
+			t.Fatalf("Lookup(%q): %v", tt.name, err)
+		}
+		if res.GVR != tt.want {
+			t.Errorf("Lookup(%q) = %s, want %s", tt.name, res.GVR, tt.want)
+		}
+	}
+
+	if _, err := registry.Lookup("pdos"); err == nil || !strings.Contains(err.Error(), "pod") {
+		t.Errorf("Lookup(pdos) error = %v, want an error listing the known resources", err)
+	}
+}
+
+func TestAddTakenName(t *testing.T) {
+	registry := NewRegistry()
+	res := Custom(schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "pods"})
+	if err := registry.Add(res); err == nil {
+		t.Errorf("Add() of a resource aliased pods succeeded")
+	}
+	if _, ok := registry.Get(res.GVR); ok {
+		t.Errorf("Add() registered the resource despite the error")
+	}
+}
+
+func TestCustom(t *testing.T) {
+	registry := NewRegistry()
+	gvr := schema.GroupVersionResource{Group: "monitoring.coreos.com", Version: "v1", Resource: "prometheusrules"}
+	if err := registry.Add(Custom(gvr)); err != nil {
+		t.Fatalf("Add(): %v", err)
+	}
+
+	for _, name := range []string{"prometheusrules", "prometheusrules.monitoring.coreos.com"} {
+		res, err := registry.Lookup(name)
+		if err != nil {
+			t.Fatalf("Lookup(%q): %v", name, err)
+		}
+		if !res.Dynamic || res.APIVersion() != "monitoring.coreos.com/v1" {
+			t.Errorf("Lookup(%q) = %+v, want the dynamic prometheusrules resource", name, res)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package retry tells delivery failures worth retrying from permanent ones,
+// and spaces retries with exponential backoff and jitter.
+package retry
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+)
+
+// Defaults of the policy settings left empty
+const (
+	DefaultAttempts       = 5
+	DefaultInitialBackoff = time.Second
+	DefaultMaxBackoff     = 30 * time.Second
+)
+
+// maxBodyExcerpt bounds how much of an error response ends up in the error
+const maxBodyExcerpt = 512
+
+// permanentError marks a failure that sending again cannot fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent marks err as a failure that sending again cannot fix, such as an
+// invalid message or a rejected request. A nil err stays nil.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err, or an error it wraps, was marked by
+// Permanent. Any other error is deemed transient.
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
+
+// GiveUpError is returned once a delivery is given up on
+type GiveUpError struct {
+	// Attempts is how many times the delivery was tried
+	Attempts int
+	Err      error
+}
+
+func (e *GiveUpError) Error() string {
+	if e.Attempts == 1 {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("giving up after %d attempts: %v", e.Attempts, e.Err)
+}
+
+func (e *GiveUpError) Unwrap() error {
+	return e.Err
+}
+
+// Attempts returns how many times the delivery that failed with err was
+// tried: the count of the GiveUpError it wraps, or 1 if there is none.
+func Attempts(err error) int {
+	var giveUp *GiveUpError
+	if errors.As(err, &giveUp) {
+		return giveUp.Attempts
+	}
+	return 1
+}
+
+// retryable is implemented by the errors of client libraries, such as the
+// Slack one, that know whether they are worth retrying
+type retryable interface {
+	Retryable() bool
+}
+
+// Classify marks err as permanent when it, or an error it wraps, says it is
+// not retryable, and returns it unchanged otherwise.
+func Classify(err error) error {
+	var r retryable
+	if errors.As(err, &r) && !r.Retryable() {
+		return Permanent(err)
+	}
+	return err
+}
+
+// CheckResponse returns nil for a 2xx response, and otherwise an error
+// quoting the start of the body. Too Many Requests and 5xx responses are
+// transient, the others permanent. It does not close the body.
+func CheckResponse(res *http.Response) error {
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		return nil
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(res.Body, maxBodyExcerpt))
+	err := fmt.Errorf("unexpected response %s", res.Status)
+	if excerpt := strings.TrimSpace(string(body)); excerpt != "" {
+		err = fmt.Errorf("unexpected response %s: %s", res.Status, excerpt)
+	}
+	if TransientStatus(res.StatusCode) {
+		return err
+	}
+	return Permanent(err)
+}
+
+// TransientStatus reports whether an HTTP request failing with the given
+// status code may succeed later: Too Many Requests and 5xx.
+func TransientStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= 500
+}
+
+// Policy says how many times to try and how long to wait in between
+type Policy struct {
+	Attempts       int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// NewPolicy returns the policy configured by c, with defaults for the
+// settings left empty.
+func NewPolicy(c config.Retry) (Policy, error) {
+	if c.Attempts < 0 || c.InitialBackoff < 0 || c.MaxBackoff < 0 {
+		return Policy{}, fmt.Errorf("retry settings must not be negative, got %+v", c)
+	}
+
+	p := Policy{Attempts: c.Attempts, InitialBackoff: c.InitialBackoff, MaxBackoff: c.MaxBackoff}
+	if p.Attempts == 0 {
+		p.Attempts = DefaultAttempts
+	}
+	if p.InitialBackoff == 0 {
+		p.InitialBackoff = DefaultInitialBackoff
+	}
+	if p.MaxBackoff == 0 {
+		p.MaxBackoff = DefaultMaxBackoff
+	}
+	if p.MaxBackoff < p.InitialBackoff {
+		p.MaxBackoff = p.InitialBackoff
+	}
+	return p, nil
+}
+
+// Backoff returns how long to wait after the given failed attempt, counting
+// from 1. The wait doubles with every attempt up to MaxBackoff, and a random
+// half of it is taken off so that senders failing together spread out.
+func (p Policy) Backoff(attempt int) time.Duration {
+	backoff := p.InitialBackoff
+	for i := 1; i < attempt && backoff < p.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > p.MaxBackoff {
+		backoff = p.MaxBackoff
+	}
+	half := backoff / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
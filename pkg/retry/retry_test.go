@@ -0,0 +1,127 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package retry
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+)
+
+func TestCheckResponse(t *testing.T) {
+	var Tests = []struct {
+		status    int
+		wantErr   bool
+		permanent bool
+	}{
+		{http.StatusOK, false, false},
+		{http.StatusNoContent, false, false},
+		{http.StatusTooManyRequests, true, false},
+		{http.StatusInternalServerError, true, false},
+		{http.StatusServiceUnavailable, true, false},
+		{http.StatusBadRequest, true, true},
+		{http.StatusNotFound, true, true},
+	}
+
+	for _, tt := range Tests {
+		res := &http.Response{
+			StatusCode: tt.status,
+			Status:     fmt.Sprintf("%d %s", tt.status, http.StatusText(tt.status)),
+			Body:       io.NopCloser(strings.NewReader("no_such_channel")),
+		}
+		err := CheckResponse(res)
+		if (err != nil) != tt.wantErr || IsPermanent(err) != tt.permanent {
+			t.Errorf("CheckResponse(%d) = %v (permanent %t), want error %t, permanent %t", tt.status, err, IsPermanent(err), tt.wantErr, tt.permanent)
+		}
+		if err != nil && !strings.Contains(err.Error(), "no_such_channel") {
+			t.Errorf("CheckResponse(%d) = %v, want it to quote the body", tt.status, err)
+		}
+	}
+}
+
+func TestPermanentSurvivesWrapping(t *testing.T) {
+	err := fmt.Errorf("handler slack: %w", Permanent(errors.New("invalid_auth")))
+	if !IsPermanent(err) {
+		t.Errorf("IsPermanent(%v) = false, want true", err)
+	}
+	if IsPermanent(errors.New("connection refused")) {
+		t.Error("IsPermanent() = true for an unmarked error")
+	}
+	if Permanent(nil) != nil {
+		t.Error("Permanent(nil) != nil")
+	}
+}
+
+type retryableError bool
+
+func (e retryableError) Error() string   { return "library error" }
+func (e retryableError) Retryable() bool { return bool(e) }
+
+func TestClassify(t *testing.T) {
+	if !IsPermanent(Classify(retryableError(false))) {
+		t.Error("Classify() kept an error its library deems not retryable transient")
+	}
+	if IsPermanent(Classify(retryableError(true))) || IsPermanent(Classify(errors.New("EOF"))) {
+		t.Error("Classify() made a retryable error permanent")
+	}
+}
+
+func TestBackoffGrowsWithinBounds(t *testing.T) {
+	p, err := NewPolicy(config.Retry{InitialBackoff: time.Second, MaxBackoff: 10 * time.Second})
+	if err != nil {
+		t.Fatalf("NewPolicy(): %v", err)
+	}
+
+	var Tests = []struct {
+		attempt int
+		ceiling time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 8 * time.Second},
+		{5, 10 * time.Second},
+		{50, 10 * time.Second},
+	}
+
+	for _, tt := range Tests {
+		for i := 0; i < 100; i++ {
+			if got := p.Backoff(tt.attempt); got < tt.ceiling/2 || got > tt.ceiling {
+				t.Fatalf("Backoff(%d) = %s, want between %s and %s", tt.attempt, got, tt.ceiling/2, tt.ceiling)
+			}
+		}
+	}
+}
+
+func TestNewPolicy(t *testing.T) {
+	p, err := NewPolicy(config.Retry{})
+	if err != nil {
+		t.Fatalf("NewPolicy(): %v", err)
+	}
+	if want := (Policy{DefaultAttempts, DefaultInitialBackoff, DefaultMaxBackoff}); p != want {
+		t.Errorf("NewPolicy() = %+v, want the defaults %+v", p, want)
+	}
+	if _, err := NewPolicy(config.Retry{Attempts: -1}); err == nil {
+		t.Error("NewPolicy() accepted negative attempts")
+	}
+}
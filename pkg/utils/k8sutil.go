@@ -4,17 +4,25 @@ import (
 	"os"
 
 	"github.com/sirupsen/logrus"
+	admissionregistration_v1 "k8s.io/api/admissionregistration/v1"
 	apps_v1 "k8s.io/api/apps/v1"
 	batch_v1 "k8s.io/api/batch/v1"
 	api_v1 "k8s.io/api/core/v1"
+	discovery_v1 "k8s.io/api/discovery/v1"
 	ext_v1beta1 "k8s.io/api/extensions/v1beta1"
 	networking_v1 "k8s.io/api/networking/v1"
+	policy_v1 "k8s.io/api/policy/v1"
 	rbac_v1 "k8s.io/api/rbac/v1"
 	events_v1 "k8s.io/api/events/v1"
 	rbac_v1beta1 "k8s.io/api/rbac/v1beta1"
+	scheduling_v1 "k8s.io/api/scheduling/v1"
+	storage_v1 "k8s.io/api/storage/v1"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/metadata"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 )
@@ -86,6 +94,36 @@ func GetDynamicClientOutOfCluster() dynamic.Interface {
 	return clientset
 }
 
+// GetMetadataClient returns a k8s metadata client to the request from inside of cluster
+func GetMetadataClient() metadata.Interface {
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		logrus.Fatalf("Can not get kubernetes config: %v", err)
+	}
+
+	clientset, err := metadata.NewForConfig(config)
+	if err != nil {
+		logrus.Fatalf("Can not create metadata kubernetes client: %v", err)
+	}
+
+	return clientset
+}
+
+// GetMetadataClientOutOfCluster returns a k8s metadata client to the request from outside of cluster
+func GetMetadataClientOutOfCluster() metadata.Interface {
+	config, err := buildOutOfClusterConfig()
+	if err != nil {
+		logrus.Fatalf("Can not get kubernetes config: %v", err)
+	}
+
+	clientset, err := metadata.NewForConfig(config)
+	if err != nil {
+		logrus.Fatalf("Can not get kubernetes config: %v", err)
+	}
+
+	return clientset
+}
+
 // GetObjectMetaData returns metadata of a given k8s object
 func GetObjectMetaData(obj interface{}) (objectMeta meta_v1.ObjectMeta) {
 
@@ -132,6 +170,60 @@ func GetObjectMetaData(obj interface{}) (objectMeta meta_v1.ObjectMeta) {
 		objectMeta = object.ObjectMeta
 	case *events_v1.Event:
 		objectMeta = object.ObjectMeta
+	case *batch_v1.CronJob:
+		objectMeta = object.ObjectMeta
+	case *api_v1.PersistentVolumeClaim:
+		objectMeta = object.ObjectMeta
+	case *api_v1.Endpoints:
+		objectMeta = object.ObjectMeta
+	case *discovery_v1.EndpointSlice:
+		objectMeta = object.ObjectMeta
+	case *networking_v1.NetworkPolicy:
+		objectMeta = object.ObjectMeta
+	case *policy_v1.PodDisruptionBudget:
+		objectMeta = object.ObjectMeta
+	case *storage_v1.StorageClass:
+		objectMeta = object.ObjectMeta
+	case *rbac_v1.Role:
+		objectMeta = object.ObjectMeta
+	case *rbac_v1.RoleBinding:
+		objectMeta = object.ObjectMeta
+	case *admissionregistration_v1.ValidatingWebhookConfiguration:
+		objectMeta = object.ObjectMeta
+	case *admissionregistration_v1.MutatingWebhookConfiguration:
+		objectMeta = object.ObjectMeta
+	case *scheduling_v1.PriorityClass:
+		objectMeta = object.ObjectMeta
+	case *meta_v1.PartialObjectMetadata:
+		objectMeta = object.ObjectMeta
+	case *unstructured.Unstructured:
+		// Custom resources and CRDs come from the dynamic client.
+		objectMeta = meta_v1.ObjectMeta{
+			Name:              object.GetName(),
+			Namespace:         object.GetNamespace(),
+			UID:               object.GetUID(),
+			CreationTimestamp: object.GetCreationTimestamp(),
+			Labels:            object.GetLabels(),
+			Annotations:       object.GetAnnotations(),
+		}
 	}
 	return objectMeta
 }
+
+// ToUnstructured returns obj as it appears in YAML or JSON, e.g. with the
+// replicas of a deployment under ["spec"]["replicas"]. A nil object yields an
+// empty map. The map of an unstructured object is returned as is, so callers
+// must not modify it.
+func ToUnstructured(obj runtime.Object) (map[string]interface{}, error) {
+	switch typed := obj.(type) {
+	case nil:
+		return map[string]interface{}{}, nil
+	case *unstructured.Unstructured:
+		if typed == nil {
+			return map[string]interface{}{}, nil
+		}
+		return typed.Object, nil
+	default:
+		return runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	}
+}